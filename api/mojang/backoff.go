@@ -0,0 +1,64 @@
+package mojang
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"time"
+)
+
+var backoffRand = rand.Int63n
+
+// Backoff calculates the exponentially growing delays with the full jitter, so the requests,
+// which have failed at the same moment, aren't repeated at the same moment too
+type Backoff struct {
+	// Base is the upper bound of the delay before the first retry
+	Base time.Duration
+	// The upper bound doesn't grow beyond Max. It's not limited when zero
+	Max time.Duration
+}
+
+// Delay returns the random duration between zero and Base * 2^attempt. The attempt is zero-based
+func (b Backoff) Delay(attempt int) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+
+	ceil := b.Base
+	for i := 0; i < attempt && (b.Max <= 0 || ceil < b.Max); i++ {
+		// Protects from the overflow on the big number of attempts
+		if ceil > math.MaxInt64/2 {
+			break
+		}
+
+		ceil *= 2
+	}
+
+	if b.Max > 0 && ceil > b.Max {
+		ceil = b.Max
+	}
+
+	return time.Duration(backoffRand(int64(ceil) + 1))
+}
+
+// IsTransientError returns true for the server errors and the network failures, so the request may succeed
+// when it's repeated. Other errors mean that the server is alive and responds, even if it has rejected the request
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		return true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,41 @@
+package mojang
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	testify "github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	backoffRand = func(n int64) int64 {
+		return n - 1
+	}
+	defer func() {
+		backoffRand = rand.Int63n
+	}()
+
+	backoff := Backoff{Base: time.Second, Max: 5 * time.Second}
+	testify.Equal(t, time.Second, backoff.Delay(0))
+	testify.Equal(t, 2*time.Second, backoff.Delay(1))
+	testify.Equal(t, 4*time.Second, backoff.Delay(2))
+	testify.Equal(t, 5*time.Second, backoff.Delay(3))
+	testify.Equal(t, 5*time.Second, backoff.Delay(100))
+
+	testify.Equal(t, time.Duration(0), Backoff{}.Delay(3))
+	testify.True(t, Backoff{Base: time.Second}.Delay(100) > 0, "shouldn't overflow")
+}
+
+func TestIsTransientError(t *testing.T) {
+	testify.False(t, IsTransientError(nil))
+	testify.True(t, IsTransientError(&ServerError{Status: 502}))
+	testify.True(t, IsTransientError(context.DeadlineExceeded))
+	testify.True(t, IsTransientError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	testify.False(t, IsTransientError(&TooManyRequestsError{}))
+	testify.False(t, IsTransientError(&BadRequestError{}))
+	testify.False(t, IsTransientError(context.Canceled))
+}
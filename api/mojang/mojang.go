@@ -2,10 +2,13 @@ package mojang
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -66,21 +69,53 @@ type ProfileInfo struct {
 	IsDemo   bool   `json:"demo,omitempty"`
 }
 
-var ApiMojangDotComAddr = "https://api.mojang.com"
-var SessionServerMojangComAddr = "https://sessionserver.mojang.com"
+const DefaultApiBaseUrl = "https://api.mojang.com"
+const DefaultSessionServerBaseUrl = "https://sessionserver.mojang.com"
+
+// Client performs the requests to the Mojang's API or to any other server, which implements the same endpoints.
+// The zero value requests Mojang itself, so the clients with different settings may be used at the same time
+type Client struct {
+	// DefaultApiBaseUrl is used when not set
+	ApiBaseUrl string
+	// DefaultSessionServerBaseUrl is used when not set
+	SessionServerBaseUrl string
+	// HttpClient is used when not set
+	HttpClient *http.Client
+	// Timeout limits each attempt separately. Only the HttpClient's timeout is applied when it's zero
+	Timeout time.Duration
+	// The Go's default User-Agent is sent when it's empty
+	UserAgent string
+	// Headers are added to each request
+	Headers http.Header
+	// Retries is the number of the additional attempts after the network error or the 5xx response
+	Retries      int
+	RetryBackoff Backoff
+}
+
+// Exchanges usernames array to array of uuids using the Mojang's API
+func UsernamesToUuids(ctx context.Context, usernames []string) ([]*ProfileInfo, error) {
+	return (&Client{}).UsernamesToUuids(ctx, usernames)
+}
+
+// Obtains textures information for provided uuid using the Mojang's Session server
+func UuidToTextures(ctx context.Context, uuid string, signed bool) (*SignedTexturesResponse, error) {
+	return (&Client{}).UuidToTextures(ctx, uuid, signed)
+}
 
 // Exchanges usernames array to array of uuids
 // See https://wiki.vg/Mojang_API#Playernames_-.3E_UUIDs
-func UsernamesToUuids(usernames []string) ([]*ProfileInfo, error) {
+func (c *Client) UsernamesToUuids(ctx context.Context, usernames []string) ([]*ProfileInfo, error) {
 	requestBody, _ := json.Marshal(usernames)
-	request, err := http.NewRequest("POST", ApiMojangDotComAddr+"/profiles/minecraft", bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, err
-	}
+	response, err := c.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		request, err := http.NewRequestWithContext(ctx, "POST", c.apiBaseUrl()+"/profiles/minecraft", bytes.NewReader(requestBody))
+		if err != nil {
+			return nil, err
+		}
 
-	request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Content-Type", "application/json")
 
-	response, err := HttpClient.Do(request)
+		return request, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -100,19 +135,16 @@ func UsernamesToUuids(usernames []string) ([]*ProfileInfo, error) {
 
 // Obtains textures information for provided uuid
 // See https://wiki.vg/Mojang_API#UUID_-.3E_Profile_.2B_Skin.2FCape
-func UuidToTextures(uuid string, signed bool) (*SignedTexturesResponse, error) {
+func (c *Client) UuidToTextures(ctx context.Context, uuid string, signed bool) (*SignedTexturesResponse, error) {
 	normalizedUuid := strings.ReplaceAll(uuid, "-", "")
-	url := SessionServerMojangComAddr + "/session/minecraft/profile/" + normalizedUuid
+	url := c.sessionServerBaseUrl() + "/session/minecraft/profile/" + normalizedUuid
 	if signed {
 		url += "?unsigned=false"
 	}
 
-	request, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	response, err := HttpClient.Do(request)
+	response, err := c.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "GET", url, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -130,6 +162,104 @@ func UuidToTextures(uuid string, signed bool) (*SignedTexturesResponse, error) {
 	return result, nil
 }
 
+// do performs the request, which is created by the newRequest for each attempt. The 5xx response
+// is returned as is after the last attempt, so it can be validated as usual
+func (c *Client) do(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		response, err := c.attempt(ctx, newRequest)
+		if attempt >= c.Retries || !isRetryable(response, err) || ctx.Err() != nil {
+			return response, err
+		}
+
+		if response != nil {
+			_, _ = io.Copy(ioutil.Discard, response.Body)
+			_ = response.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.RetryBackoff.Delay(attempt)):
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	cancel := func() {}
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+	}
+
+	request, err := newRequest(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	for name, values := range c.Headers {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+
+	if c.UserAgent != "" {
+		request.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	response, err := c.httpClient().Do(request)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The attempt's context must live until the body is read
+	response.Body = &cancelOnCloseBody{ReadCloser: response.Body, cancel: cancel}
+
+	return response, nil
+}
+
+func isRetryable(response *http.Response, err error) bool {
+	if err != nil {
+		return IsTransientError(err)
+	}
+
+	return response.StatusCode >= 500
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func (c *Client) apiBaseUrl() string {
+	if c.ApiBaseUrl != "" {
+		return c.ApiBaseUrl
+	}
+
+	return DefaultApiBaseUrl
+}
+
+func (c *Client) sessionServerBaseUrl() string {
+	if c.SessionServerBaseUrl != "" {
+		return c.SessionServerBaseUrl
+	}
+
+	return DefaultSessionServerBaseUrl
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HttpClient != nil {
+		return c.HttpClient
+	}
+
+	return HttpClient
+}
+
 func validateResponse(response *http.Response) error {
 	switch {
 	case response.StatusCode == 204:
@@ -148,7 +278,7 @@ func validateResponse(response *http.Response) error {
 	case response.StatusCode == 403:
 		return &ForbiddenError{}
 	case response.StatusCode == 429:
-		return &TooManyRequestsError{}
+		return &TooManyRequestsError{RetryAfter: ParseRetryAfter(response.Header.Get("Retry-After"))}
 	case response.StatusCode >= 500:
 		return &ServerError{Status: response.StatusCode}
 	}
@@ -156,6 +286,29 @@ func validateResponse(response *http.Response) error {
 	return nil
 }
 
+// ParseRetryAfter accepts both the delay in seconds and the HTTP date. Zero is returned when the value is invalid
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
+
 type ResponseError interface {
 	IsMojangError() bool
 }
@@ -200,6 +353,8 @@ func (*ForbiddenError) Error() string {
 // When you exceed the set limit of requests, this error will be returned
 type TooManyRequestsError struct {
 	ResponseError
+	// RetryAfter is the delay taken from the Retry-After header. Zero when the header isn't present
+	RetryAfter time.Duration
 }
 
 func (*TooManyRequestsError) Error() string {
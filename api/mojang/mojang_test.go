@@ -1,8 +1,13 @@
 package mojang
 
 import (
+	"context"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/h2non/gock"
 
@@ -66,7 +71,7 @@ func TestUsernamesToUuids(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UsernamesToUuids([]string{"Thinkofdeath", "maksimkurb"})
+		result, err := UsernamesToUuids(context.Background(), []string{"Thinkofdeath", "maksimkurb"})
 		if assert.NoError(err) {
 			assert.Len(result, 2)
 			assert.Equal("4566e69fc90748ee8d71d7ba5aa00d20", result[0].Id)
@@ -98,7 +103,7 @@ func TestUsernamesToUuids(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UsernamesToUuids([]string{""})
+		result, err := UsernamesToUuids(context.Background(), []string{""})
 		assert.Nil(result)
 		assert.IsType(&BadRequestError{}, err)
 		assert.EqualError(err, "400 IllegalArgumentException: profileName can not be null or empty.")
@@ -119,7 +124,7 @@ func TestUsernamesToUuids(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UsernamesToUuids([]string{"Thinkofdeath", "maksimkurb"})
+		result, err := UsernamesToUuids(context.Background(), []string{"Thinkofdeath", "maksimkurb"})
 		assert.Nil(result)
 		assert.IsType(&ForbiddenError{}, err)
 		assert.EqualError(err, "403: Forbidden")
@@ -143,13 +148,34 @@ func TestUsernamesToUuids(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UsernamesToUuids([]string{"Thinkofdeath", "maksimkurb"})
+		result, err := UsernamesToUuids(context.Background(), []string{"Thinkofdeath", "maksimkurb"})
 		assert.Nil(result)
 		assert.IsType(&TooManyRequestsError{}, err)
 		assert.EqualError(err, "429: Too Many Requests")
 		assert.Implements((*ResponseError)(nil), err)
 	})
 
+	t.Run("handle too many requests response with retry after header", func(t *testing.T) {
+		assert := testify.New(t)
+
+		defer gock.Off()
+		gock.New("https://api.mojang.com").
+			Post("/profiles/minecraft").
+			Reply(429).
+			SetHeader("Retry-After", "30")
+
+		client := &http.Client{}
+		gock.InterceptClient(client)
+
+		HttpClient = client
+
+		result, err := UsernamesToUuids(context.Background(), []string{"Thinkofdeath", "maksimkurb"})
+		assert.Nil(result)
+		if assert.IsType(&TooManyRequestsError{}, err) {
+			assert.Equal(30*time.Second, err.(*TooManyRequestsError).RetryAfter)
+		}
+	})
+
 	t.Run("handle server error", func(t *testing.T) {
 		assert := testify.New(t)
 
@@ -164,7 +190,7 @@ func TestUsernamesToUuids(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UsernamesToUuids([]string{"Thinkofdeath", "maksimkurb"})
+		result, err := UsernamesToUuids(context.Background(), []string{"Thinkofdeath", "maksimkurb"})
 		assert.Nil(result)
 		assert.IsType(&ServerError{}, err)
 		assert.EqualError(err, "500: Server error")
@@ -197,7 +223,7 @@ func TestUuidToTextures(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UuidToTextures("4566e69fc90748ee8d71d7ba5aa00d20", false)
+		result, err := UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
 		if assert.NoError(err) {
 			assert.Equal("4566e69fc90748ee8d71d7ba5aa00d20", result.Id)
 			assert.Equal("Thinkofdeath", result.Name)
@@ -233,7 +259,7 @@ func TestUuidToTextures(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UuidToTextures("4566e69f-c907-48ee-8d71-d7ba5aa00d20", true)
+		result, err := UuidToTextures(context.Background(), "4566e69f-c907-48ee-8d71-d7ba5aa00d20", true)
 		if assert.NoError(err) {
 			assert.Equal("4566e69fc90748ee8d71d7ba5aa00d20", result.Id)
 			assert.Equal("Thinkofdeath", result.Name)
@@ -258,7 +284,7 @@ func TestUuidToTextures(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UuidToTextures("4566e69fc90748ee8d71d7ba5aa00d20", false)
+		result, err := UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
 		assert.Nil(result)
 		assert.IsType(&EmptyResponse{}, err)
 		assert.EqualError(err, "204: Empty Response")
@@ -282,7 +308,7 @@ func TestUuidToTextures(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UuidToTextures("4566e69fc90748ee8d71d7ba5aa00d20", false)
+		result, err := UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
 		assert.Nil(result)
 		assert.IsType(&TooManyRequestsError{}, err)
 		assert.EqualError(err, "429: Too Many Requests")
@@ -303,7 +329,7 @@ func TestUuidToTextures(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UuidToTextures("4566e69fc90748ee8d71d7ba5aa00d20", false)
+		result, err := UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
 		assert.Nil(result)
 		assert.IsType(&ServerError{}, err)
 		assert.EqualError(err, "500: Server error")
@@ -311,3 +337,157 @@ func TestUuidToTextures(t *testing.T) {
 		assert.Implements((*ResponseError)(nil), err)
 	})
 }
+
+func TestClient(t *testing.T) {
+	t.Run("use custom base urls and http client", func(t *testing.T) {
+		assert := testify.New(t)
+
+		defer gock.Off()
+		gock.New("https://yggdrasil.example.com").
+			Post("/api/profiles/minecraft").
+			JSON([]string{"mock"}).
+			Reply(200).
+			JSON([]map[string]interface{}{
+				{"id": "4566e69fc90748ee8d71d7ba5aa00d20", "name": "mock"},
+			})
+		gock.New("https://yggdrasil.example.com").
+			Get("/sessionserver/session/minecraft/profile/4566e69fc90748ee8d71d7ba5aa00d20").
+			MatchParam("unsigned", "false").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"id":   "4566e69fc90748ee8d71d7ba5aa00d20",
+				"name": "mock",
+			})
+
+		httpClient := &http.Client{}
+		gock.InterceptClient(httpClient)
+
+		client := &Client{
+			ApiBaseUrl:           "https://yggdrasil.example.com/api",
+			SessionServerBaseUrl: "https://yggdrasil.example.com/sessionserver",
+			HttpClient:           httpClient,
+		}
+
+		profiles, err := client.UsernamesToUuids(context.Background(), []string{"mock"})
+		if assert.NoError(err) && assert.Len(profiles, 1) {
+			assert.Equal("4566e69fc90748ee8d71d7ba5aa00d20", profiles[0].Id)
+		}
+
+		textures, err := client.UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", true)
+		if assert.NoError(err) {
+			assert.Equal("mock", textures.Name)
+		}
+
+		assert.True(gock.IsDone())
+	})
+}
+
+func TestClientConfig(t *testing.T) {
+	t.Run("send the user agent and the extra headers", func(t *testing.T) {
+		var request *http.Request
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request = r
+			_, _ = w.Write([]byte(`{"id":"4566e69fc90748ee8d71d7ba5aa00d20","name":"mock"}`))
+		}))
+		defer server.Close()
+
+		client := &Client{
+			SessionServerBaseUrl: server.URL,
+			HttpClient:           server.Client(),
+			UserAgent:            "Chrly/test",
+			Headers:              http.Header{"X-Tenant": []string{"ely"}},
+		}
+
+		_, err := client.UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
+		testify.NoError(t, err)
+		testify.Equal(t, "Chrly/test", request.Header.Get("User-Agent"))
+		testify.Equal(t, "ely", request.Header.Get("X-Tenant"))
+	})
+
+	t.Run("retry the server errors", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+
+			body, _ := ioutil.ReadAll(r.Body)
+			testify.JSONEq(t, `["mock"]`, string(body), "the body must be sent on each attempt")
+			_, _ = w.Write([]byte(`[{"id":"4566e69fc90748ee8d71d7ba5aa00d20","name":"mock"}]`))
+		}))
+		defer server.Close()
+
+		client := &Client{ApiBaseUrl: server.URL, HttpClient: server.Client(), Retries: 2}
+
+		profiles, err := client.UsernamesToUuids(context.Background(), []string{"mock"})
+		testify.NoError(t, err)
+		testify.Len(t, profiles, 1)
+		testify.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("return the last error when the retries are exhausted", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := &Client{SessionServerBaseUrl: server.URL, HttpClient: server.Client(), Retries: 1}
+
+		_, err := client.UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
+		testify.Equal(t, &ServerError{Status: http.StatusServiceUnavailable}, err)
+		testify.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("don't retry the client errors", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client := &Client{SessionServerBaseUrl: server.URL, HttpClient: server.Client(), Retries: 3}
+
+		_, err := client.UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
+		testify.IsType(t, &TooManyRequestsError{}, err)
+		testify.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("limit each attempt with the timeout", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				<-r.Context().Done()
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"id":"4566e69fc90748ee8d71d7ba5aa00d20","name":"mock"}`))
+		}))
+		defer server.Close()
+
+		client := &Client{
+			SessionServerBaseUrl: server.URL,
+			HttpClient:           server.Client(),
+			Timeout:              50 * time.Millisecond,
+			Retries:              1,
+		}
+
+		textures, err := client.UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
+		testify.NoError(t, err)
+		testify.Equal(t, "mock", textures.Name)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	testify.Equal(t, 10*time.Second, ParseRetryAfter("10"))
+	testify.Equal(t, time.Duration(0), ParseRetryAfter(""))
+	testify.Equal(t, time.Duration(0), ParseRetryAfter("-1"))
+	testify.Equal(t, time.Duration(0), ParseRetryAfter("not a value"))
+	testify.Equal(t, time.Duration(0), ParseRetryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)))
+
+	delay := ParseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	testify.True(t, delay > 50*time.Second && delay <= time.Minute)
+}
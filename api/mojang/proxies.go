@@ -0,0 +1,137 @@
+package mojang
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var proxiesNow = time.Now
+
+var ErrNoAvailableProxies = errors.New("all proxies are benched")
+
+// DirectProxy is the special proxy value, which means the direct connection without any proxy
+const DirectProxy = "direct"
+
+// Proxy is a single egress for the requests to Mojang
+type Proxy struct {
+	// Url is nil for the direct connection
+	Url *url.URL
+
+	transport    http.RoundTripper
+	lock         sync.Mutex
+	benchedUntil time.Time
+}
+
+// NewProxy accepts the http, https, socks5 and socks5h proxies urls or the DirectProxy value
+func NewProxy(rawUrl string) (*Proxy, error) {
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: 1024,
+	}
+	if rawUrl == DirectProxy {
+		return &Proxy{transport: transport}, nil
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme \"%s\"", u.Scheme)
+	}
+
+	transport.Proxy = http.ProxyURL(u)
+
+	return &Proxy{Url: u, transport: transport}, nil
+}
+
+// String returns the proxy url without the password, so it's safe to display it
+func (p *Proxy) String() string {
+	if p.Url == nil {
+		return DirectProxy
+	}
+
+	return p.Url.Redacted()
+}
+
+// Status returns nil when the proxy is available or the error when it's benched
+func (p *Proxy) Status() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.isBenched() {
+		return nil
+	}
+
+	return fmt.Errorf("benched until %s after the 429 response", p.benchedUntil.Format(time.RFC3339))
+}
+
+func (p *Proxy) bench(d time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.benchedUntil = proxiesNow().Add(d)
+}
+
+func (p *Proxy) isBenched() bool {
+	return proxiesNow().Before(p.benchedUntil)
+}
+
+// ProxiesTransport rotates the requests between the proxies. The proxy, which has received
+// the 429 Too Many Requests response, isn't used for the BenchDuration
+type ProxiesTransport struct {
+	Proxies       []*Proxy
+	BenchDuration time.Duration
+
+	lock sync.Mutex
+	next int
+}
+
+func (t *ProxiesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	proxy := t.pickProxy()
+	if proxy == nil {
+		return nil, ErrNoAvailableProxies
+	}
+
+	response, err := proxy.transport.RoundTrip(req)
+	if err == nil && response.StatusCode == http.StatusTooManyRequests {
+		proxy.bench(t.BenchDuration)
+	}
+
+	return response, err
+}
+
+// Status returns nil when at least one proxy is available
+func (t *ProxiesTransport) Status() error {
+	for _, proxy := range t.Proxies {
+		if proxy.Status() == nil {
+			return nil
+		}
+	}
+
+	return ErrNoAvailableProxies
+}
+
+func (t *ProxiesTransport) pickProxy() *Proxy {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for i := 0; i < len(t.Proxies); i++ {
+		proxy := t.Proxies[(t.next+i)%len(t.Proxies)]
+		proxy.lock.Lock()
+		benched := proxy.isBenched()
+		proxy.lock.Unlock()
+		if !benched {
+			t.next = (t.next + i + 1) % len(t.Proxies)
+			return proxy
+		}
+	}
+
+	return nil
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var tokenScopes []string
+
 var tokenCmd = &cobra.Command{
 	Use:   "token",
 	Short: "Creates a new token, which allows to interact with Chrly API",
@@ -20,7 +22,12 @@ var tokenCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 
-		token, err := auth.NewToken(http.SkinScope)
+		scopes := make([]http.Scope, len(tokenScopes))
+		for i, scope := range tokenScopes {
+			scopes[i] = http.Scope(scope)
+		}
+
+		token, err := auth.NewToken(scopes...)
 		if err != nil {
 			log.Fatalf("Unable to create new token. The error is %v\n", err)
 		}
@@ -30,5 +37,6 @@ var tokenCmd = &cobra.Command{
 }
 
 func init() {
+	tokenCmd.Flags().StringSliceVar(&tokenScopes, "scope", []string{string(http.SkinScope)}, "scopes of the token: skin to interact with the API, worker to access the worker endpoints")
 	RootCmd.AddCommand(tokenCmd)
 }
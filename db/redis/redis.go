@@ -25,17 +25,28 @@ func New(ctx context.Context, addr string, poolSize int) (*Redis, error) {
 	}
 
 	return &Redis{
-		client:  client,
-		context: ctx,
+		client:                client,
+		context:               ctx,
+		addr:                  addr,
+		MojangUuidDuration:    defaultMojangUuidDuration,
+		MojangNilUuidDuration: defaultMojangUuidDuration,
 	}, nil
 }
 
 const accountIdToUsernameKey = "hash:username-to-account-id" // TODO: this should be actually "hash:user-id-to-username"
 const mojangUsernameToUuidKey = "hash:mojang-username-to-uuid"
 
+const defaultMojangUuidDuration = time.Hour * 24 * 30
+
 type Redis struct {
+	// Duration during which the stored Mojang uuids are considered valid
+	MojangUuidDuration time.Duration
+	// Duration during which the usernames, that have no Mojang account, are considered as unknown
+	MojangNilUuidDuration time.Duration
+
 	client  radix.Client
 	context context.Context
+	addr    string
 }
 
 func (db *Redis) FindSkinByUsername(username string) (*model.Skin, error) {
@@ -222,7 +233,7 @@ func (db *Redis) GetUuid(username string) (string, bool, error) {
 	var found bool
 	err := db.client.Do(db.context, radix.WithConn("", func(ctx context.Context, conn radix.Conn) error {
 		var err error
-		uuid, found, err = findMojangUuidByUsername(ctx, conn, username)
+		uuid, found, err = findMojangUuidByUsername(ctx, conn, username, db.MojangUuidDuration, db.MojangNilUuidDuration)
 
 		return err
 	}))
@@ -230,7 +241,13 @@ func (db *Redis) GetUuid(username string) (string, bool, error) {
 	return uuid, found, err
 }
 
-func findMojangUuidByUsername(ctx context.Context, conn radix.Conn, username string) (string, bool, error) {
+func findMojangUuidByUsername(
+	ctx context.Context,
+	conn radix.Conn,
+	username string,
+	duration time.Duration,
+	nilDuration time.Duration,
+) (string, bool, error) {
 	key := strings.ToLower(username)
 	var result string
 	err := conn.Do(ctx, radix.Cmd(&result, "HGET", mojangUsernameToUuidKey, key))
@@ -253,9 +270,13 @@ func findMojangUuidByUsername(ctx context.Context, conn radix.Conn, username str
 		return "", false, fmt.Errorf("got unexpected response from the mojangUsernameToUuid hash: \"%s\"", result)
 	}
 
+	if parts[0] == "" {
+		duration = nilDuration
+	}
+
 	timestamp, _ := strconv.ParseInt(parts[1], 10, 64)
 	storedAt := time.Unix(timestamp, 0)
-	if storedAt.Add(time.Hour * 24 * 30).Before(now()) {
+	if storedAt.Add(duration).Before(now()) {
 		err = conn.Do(ctx, radix.Cmd(nil, "HDEL", mojangUsernameToUuidKey, key))
 		if err != nil {
 			return "", false, err
@@ -267,6 +288,22 @@ func findMojangUuidByUsername(ctx context.Context, conn radix.Conn, username str
 	return parts[0], true, nil
 }
 
+// PeekUuid returns the stored uuid regardless of its expiration and keeps the expired record untouched
+func (db *Redis) PeekUuid(username string) (string, error) {
+	var result string
+	err := db.client.Do(db.context, radix.Cmd(&result, "HGET", mojangUsernameToUuidKey, strings.ToLower(username)))
+	if err != nil {
+		return "", err
+	}
+
+	parts := strings.Split(result, ":")
+	if len(parts) < 2 {
+		return "", nil
+	}
+
+	return parts[0], nil
+}
+
 func (db *Redis) StoreUuid(username string, uuid string) error {
 	return db.client.Do(db.context, radix.WithConn("", func(ctx context.Context, conn radix.Conn) error {
 		return storeMojangUuid(ctx, conn, username, uuid)
@@ -283,10 +320,271 @@ func storeMojangUuid(ctx context.Context, conn radix.Conn, username string, uuid
 	return nil
 }
 
+func (db *Redis) RemoveUuid(username string) error {
+	return db.client.Do(db.context, radix.Cmd(nil, "HDEL", mojangUsernameToUuidKey, strings.ToLower(username)))
+}
+
+// The script uses the Redis server time to not depend on the clocks of the replicas.
+// The key holds the time in milliseconds of the next free slot
+var reserveRateLimitSlotScript = radix.NewEvalScript(`
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local interval = tonumber(ARGV[1])
+local maxWait = tonumber(ARGV[2])
+local slot = tonumber(redis.call("GET", KEYS[1]) or now)
+if slot < now then
+	slot = now
+end
+
+local delay = slot - now
+if delay > maxWait then
+	return -1
+end
+
+redis.call("SET", KEYS[1], slot + interval, "PX", delay + interval)
+
+return delay
+`)
+
+// RateLimiter allows one request per interval across all the replicas using the same Redis
+type RateLimiter struct {
+	db       *Redis
+	key      string
+	interval time.Duration
+}
+
+func (db *Redis) NewRateLimiter(key string, interval time.Duration) *RateLimiter {
+	return &RateLimiter{
+		db:       db,
+		key:      "rate-limit:" + key,
+		interval: interval,
+	}
+}
+
+func (l *RateLimiter) Reserve(maxWait time.Duration) (time.Duration, bool, error) {
+	var delay int64
+	err := l.db.client.Do(l.db.context, reserveRateLimitSlotScript.Cmd(
+		&delay,
+		[]string{l.key},
+		strconv.FormatInt(l.interval.Milliseconds(), 10),
+		strconv.FormatInt(maxWait.Milliseconds(), 10),
+	))
+	if err != nil {
+		return 0, false, err
+	}
+
+	if delay < 0 {
+		return 0, false, nil
+	}
+
+	return time.Duration(delay) * time.Millisecond, true, nil
+}
+
+// Moves up to ARGV[1] values from the lists passed in the first half of the KEYS in their order
+// to the processing lists passed in the second half of the KEYS. The first #KEYS/2 values of the result
+// are the lengths of the lists left after the pop. The script is atomic, so it works as LMOVE,
+// which isn't available in the older Redis versions
+var popFromListsScript = radix.NewEvalScript(`
+local n = tonumber(ARGV[1])
+local count = #KEYS / 2
+local values = {}
+local lengths = {}
+for i = 1, count do
+	while #values < n do
+		local value = redis.call("LPOP", KEYS[i])
+		if not value then
+			break
+		end
+
+		redis.call("RPUSH", KEYS[count + i], value)
+		table.insert(values, value)
+	end
+
+	table.insert(lengths, tostring(redis.call("LLEN", KEYS[i])))
+end
+
+for _, value in ipairs(values) do
+	table.insert(lengths, value)
+end
+
+return lengths
+`)
+
+// Removes all the ARGV values from the lists passed in the KEYS
+var removeFromListsScript = radix.NewEvalScript(`
+for _, key in ipairs(KEYS) do
+	for _, value in ipairs(ARGV) do
+		redis.call("LREM", key, 0, value)
+	end
+end
+
+return 1
+`)
+
+// Moves all the values from the processing lists passed in the second half of the KEYS
+// to the head of the lists passed in the first half, keeping their order
+var requeueListsScript = radix.NewEvalScript(`
+local count = #KEYS / 2
+for i = 1, count do
+	while true do
+		local value = redis.call("RPOP", KEYS[count + i])
+		if not value then
+			break
+		end
+
+		redis.call("LPUSH", KEYS[i], value)
+	end
+end
+
+return 1
+`)
+
+// The leadership is prolonged when the key is already held by the same id
+var acquireLeadershipScript = radix.NewEvalScript(`
+local current = redis.call("GET", KEYS[1])
+if current and current ~= ARGV[1] then
+	return 0
+end
+
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+
+return 1
+`)
+
+// JobsQueue is the set of the lists, which are shared between the replicas using the same Redis.
+// It also elects the leader among the replicas and delivers the messages to all of them
+type JobsQueue struct {
+	db  *Redis
+	key string
+}
+
+func (db *Redis) NewJobsQueue(key string) *JobsQueue {
+	return &JobsQueue{
+		db:  db,
+		key: "queue:" + key,
+	}
+}
+
+// Push appends the value to the end of the list of the lane
+func (q *JobsQueue) Push(lane string, value string) error {
+	return q.db.client.Do(q.db.context, radix.Cmd(nil, "RPUSH", q.laneKey(lane), value))
+}
+
+// Pop moves up to n values from the lanes in their order into the processing lists of the lanes
+// and returns them with the lengths of the lanes left after the pop
+func (q *JobsQueue) Pop(lanes []string, n int) ([]string, []int, error) {
+	keys := append(q.laneKeys(lanes), q.processingKeys(lanes)...)
+	var result []string
+	err := q.db.client.Do(q.db.context, popFromListsScript.Cmd(&result, keys, strconv.Itoa(n)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lengths := make([]int, len(lanes))
+	for i := range lanes {
+		lengths[i], _ = strconv.Atoi(result[i])
+	}
+
+	return result[len(lanes):], lengths, nil
+}
+
+// Ack removes the processed values from the processing lists of the lanes
+func (q *JobsQueue) Ack(lanes []string, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	return q.db.client.Do(q.db.context, removeFromListsScript.Cmd(nil, q.processingKeys(lanes), values...))
+}
+
+// Requeue moves the values left in the processing lists back to the head of their lanes
+func (q *JobsQueue) Requeue(lanes []string) error {
+	keys := append(q.laneKeys(lanes), q.processingKeys(lanes)...)
+
+	return q.db.client.Do(q.db.context, requeueListsScript.Cmd(nil, keys))
+}
+
+// AcquireLeadership returns true when the leadership is held by the id for the next ttl
+func (q *JobsQueue) AcquireLeadership(id string, ttl time.Duration) (bool, error) {
+	var acquired int
+	err := q.db.client.Do(q.db.context, acquireLeadershipScript.Cmd(
+		&acquired,
+		[]string{q.key + ":leader"},
+		id,
+		strconv.FormatInt(ttl.Milliseconds(), 10),
+	))
+	if err != nil {
+		return false, err
+	}
+
+	return acquired == 1, nil
+}
+
+// Publish delivers the message to all the subscribed replicas
+func (q *JobsQueue) Publish(message []byte) error {
+	return q.db.client.Do(q.db.context, radix.Cmd(nil, "PUBLISH", q.key+":results", string(message)))
+}
+
+// Subscribe calls the handler for each published message until the ctx is done.
+// The connection is reestablished, when it's lost
+func (q *JobsQueue) Subscribe(ctx context.Context, handler func(message []byte)) error {
+	conn, err := (radix.PersistentPubSubConnConfig{}).New(ctx, func() (string, string, error) {
+		return "tcp", q.db.addr, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = conn.Subscribe(ctx, q.key+":results")
+	if err != nil {
+		return err
+	}
+
+	for {
+		message, err := conn.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		handler(message.Message)
+	}
+}
+
+func (q *JobsQueue) laneKey(lane string) string {
+	return q.key + ":" + lane
+}
+
+func (q *JobsQueue) laneKeys(lanes []string) []string {
+	keys := make([]string, len(lanes))
+	for i, lane := range lanes {
+		keys[i] = q.laneKey(lane)
+	}
+
+	return keys
+}
+
+func (q *JobsQueue) processingKeys(lanes []string) []string {
+	keys := make([]string, len(lanes))
+	for i, lane := range lanes {
+		keys[i] = q.laneKey(lane) + ":processing"
+	}
+
+	return keys
+}
+
 func (db *Redis) Ping() error {
 	return db.client.Do(db.context, radix.Cmd(nil, "PING"))
 }
 
+func (db *Redis) Close() error {
+	return db.client.Close()
+}
+
 func buildUsernameKey(username string) string {
 	return "username:" + strings.ToLower(username)
 }
@@ -376,6 +376,42 @@ func (suite *redisTestSuite) TestGetUuid() {
 		suite.Require().Empty(resp, "should cleanup expired records")
 	})
 
+	suite.RunSubTest("exists record with empty uuid value, which is expired by its own duration", func() {
+		suite.Redis.MojangNilUuidDuration = time.Hour
+		defer func() {
+			suite.Redis.MojangNilUuidDuration = defaultMojangUuidDuration
+		}()
+
+		suite.cmd("HSET",
+			"hash:mojang-username-to-uuid",
+			"mock",
+			fmt.Sprintf(":%d", time.Now().Add(-2*time.Hour).Unix()),
+		)
+
+		uuid, found, err := suite.Redis.GetUuid("Mock")
+		suite.Require().Empty(uuid)
+		suite.Require().False(found)
+		suite.Require().Nil(err)
+	})
+
+	suite.RunSubTest("exists record, which isn't expired by the duration of empty uuid values", func() {
+		suite.Redis.MojangNilUuidDuration = time.Hour
+		defer func() {
+			suite.Redis.MojangNilUuidDuration = defaultMojangUuidDuration
+		}()
+
+		suite.cmd("HSET",
+			"hash:mojang-username-to-uuid",
+			"mock",
+			fmt.Sprintf("%s:%d", "d3ca513eb3e14946b58047f2bd3530fd", time.Now().Add(-2*time.Hour).Unix()),
+		)
+
+		uuid, found, err := suite.Redis.GetUuid("Mock")
+		suite.Require().Nil(err)
+		suite.Require().True(found)
+		suite.Require().Equal("d3ca513eb3e14946b58047f2bd3530fd", uuid)
+	})
+
 	suite.RunSubTest("exists, but corrupted record", func() {
 		suite.cmd("HSET",
 			"hash:mojang-username-to-uuid",
@@ -393,6 +429,41 @@ func (suite *redisTestSuite) TestGetUuid() {
 	})
 }
 
+func (suite *redisTestSuite) TestPeekUuid() {
+	suite.RunSubTest("exists, but expired record", func() {
+		suite.cmd("HSET",
+			"hash:mojang-username-to-uuid",
+			"mock",
+			fmt.Sprintf("%s:%d", "d3ca513eb3e14946b58047f2bd3530fd", time.Now().Add(-1*time.Hour*24*31).Unix()),
+		)
+
+		uuid, err := suite.Redis.PeekUuid("Mock")
+		suite.Require().Nil(err)
+		suite.Require().Equal("d3ca513eb3e14946b58047f2bd3530fd", uuid)
+
+		resp := suite.cmd("HGET", "hash:mojang-username-to-uuid", "mock")
+		suite.Require().NotEmpty(resp, "should keep the expired record")
+	})
+
+	suite.RunSubTest("not exists record", func() {
+		uuid, err := suite.Redis.PeekUuid("Mock")
+		suite.Require().Nil(err)
+		suite.Require().Empty(uuid)
+	})
+
+	suite.RunSubTest("exists, but corrupted record", func() {
+		suite.cmd("HSET",
+			"hash:mojang-username-to-uuid",
+			"mock",
+			"corrupted value",
+		)
+
+		uuid, err := suite.Redis.PeekUuid("Mock")
+		suite.Require().Nil(err)
+		suite.Require().Empty(uuid)
+	})
+}
+
 func (suite *redisTestSuite) TestStoreUuid() {
 	suite.RunSubTest("store uuid", func() {
 		now = func() time.Time {
@@ -419,6 +490,151 @@ func (suite *redisTestSuite) TestStoreUuid() {
 	})
 }
 
+func (suite *redisTestSuite) TestRemoveUuid() {
+	suite.RunSubTest("remove uuid", func() {
+		suite.cmd("HSET",
+			"hash:mojang-username-to-uuid",
+			"mock",
+			fmt.Sprintf("%s:%d", "d3ca513eb3e14946b58047f2bd3530fd", time.Now().Unix()),
+		)
+
+		err := suite.Redis.RemoveUuid("Mock")
+		suite.Require().Nil(err)
+
+		resp := suite.cmd("HGET", "hash:mojang-username-to-uuid", "mock")
+		suite.Require().Empty(resp)
+	})
+
+	suite.RunSubTest("remove not exists uuid", func() {
+		err := suite.Redis.RemoveUuid("Mock")
+		suite.Require().Nil(err)
+	})
+}
+
+func (suite *redisTestSuite) TestRateLimiter() {
+	suite.RunSubTest("reserve consecutive slots", func() {
+		limiter := suite.Redis.NewRateLimiter("mock", time.Second)
+
+		delay, reserved, err := limiter.Reserve(time.Minute)
+		suite.Require().Nil(err)
+		suite.Require().True(reserved)
+		suite.Require().Equal(time.Duration(0), delay)
+
+		delay, reserved, err = limiter.Reserve(time.Minute)
+		suite.Require().Nil(err)
+		suite.Require().True(reserved)
+		suite.Require().InDelta(time.Second, delay, float64(50*time.Millisecond))
+	})
+
+	suite.RunSubTest("don't reserve slot beyond max wait", func() {
+		limiter := suite.Redis.NewRateLimiter("mock", time.Minute)
+
+		_, reserved, err := limiter.Reserve(time.Second)
+		suite.Require().Nil(err)
+		suite.Require().True(reserved)
+
+		_, reserved, err = limiter.Reserve(time.Second)
+		suite.Require().Nil(err)
+		suite.Require().False(reserved)
+
+		_, reserved, err = limiter.Reserve(2 * time.Minute)
+		suite.Require().Nil(err)
+		suite.Require().True(reserved)
+	})
+}
+
+func (suite *redisTestSuite) TestJobsQueue() {
+	suite.RunSubTest("pop from the lanes in their order", func() {
+		queue := suite.Redis.NewJobsQueue("mock")
+		suite.Require().Nil(queue.Push("low", "background1"))
+		suite.Require().Nil(queue.Push("low", "background2"))
+		suite.Require().Nil(queue.Push("high", "interactive"))
+
+		values, lengths, err := queue.Pop([]string{"high", "low"}, 2)
+		suite.Require().Nil(err)
+		suite.Require().Equal([]string{"interactive", "background1"}, values)
+		suite.Require().Equal([]int{0, 1}, lengths)
+
+		values, lengths, err = queue.Pop([]string{"high", "low"}, 2)
+		suite.Require().Nil(err)
+		suite.Require().Equal([]string{"background2"}, values)
+		suite.Require().Equal([]int{0, 0}, lengths)
+	})
+
+	suite.RunSubTest("keep the popped values until they are acked", func() {
+		queue := suite.Redis.NewJobsQueue("mock")
+		suite.Require().Nil(queue.Push("low", "background"))
+		suite.Require().Nil(queue.Push("high", "interactive1"))
+		suite.Require().Nil(queue.Push("high", "interactive2"))
+
+		values, _, err := queue.Pop([]string{"high", "low"}, 3)
+		suite.Require().Nil(err)
+		suite.Require().Equal([]string{"interactive1", "interactive2", "background"}, values)
+		suite.Require().Equal("2", suite.cmd("LLEN", "queue:mock:high:processing"))
+		suite.Require().Equal("1", suite.cmd("LLEN", "queue:mock:low:processing"))
+
+		suite.Require().Nil(queue.Ack([]string{"high", "low"}, []string{"interactive1", "background"}))
+		suite.Require().Equal("1", suite.cmd("LLEN", "queue:mock:high:processing"))
+		suite.Require().Equal("interactive2", suite.cmd("LINDEX", "queue:mock:high:processing", "0"))
+		suite.Require().Equal("0", suite.cmd("LLEN", "queue:mock:low:processing"))
+	})
+
+	suite.RunSubTest("requeue the values left in the processing lists", func() {
+		queue := suite.Redis.NewJobsQueue("mock")
+		suite.Require().Nil(queue.Push("high", "interactive1"))
+		suite.Require().Nil(queue.Push("high", "interactive2"))
+		suite.Require().Nil(queue.Push("high", "interactive3"))
+
+		_, _, err := queue.Pop([]string{"high", "low"}, 2)
+		suite.Require().Nil(err)
+
+		suite.Require().Nil(queue.Requeue([]string{"high", "low"}))
+		values, lengths, err := queue.Pop([]string{"high", "low"}, 3)
+		suite.Require().Nil(err)
+		suite.Require().Equal([]string{"interactive1", "interactive2", "interactive3"}, values)
+		suite.Require().Equal([]int{0, 0}, lengths)
+	})
+
+	suite.RunSubTest("acquire leadership", func() {
+		queue := suite.Redis.NewJobsQueue("mock")
+
+		acquired, err := queue.AcquireLeadership("replica1", time.Minute)
+		suite.Require().Nil(err)
+		suite.Require().True(acquired)
+
+		acquired, err = queue.AcquireLeadership("replica2", time.Minute)
+		suite.Require().Nil(err)
+		suite.Require().False(acquired)
+
+		acquired, err = queue.AcquireLeadership("replica1", time.Minute)
+		suite.Require().Nil(err)
+		suite.Require().True(acquired, "the leader should prolong its leadership")
+	})
+
+	suite.RunSubTest("deliver published messages", func() {
+		queue := suite.Redis.NewJobsQueue("mock")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		messages := make(chan []byte, 1)
+		go func() {
+			_ = queue.Subscribe(ctx, func(message []byte) {
+				messages <- message
+			})
+		}()
+
+		suite.Require().Eventually(func() bool {
+			suite.Require().Nil(queue.Publish([]byte("mock")))
+			select {
+			case message := <-messages:
+				return string(message) == "mock"
+			default:
+				return false
+			}
+		}, time.Second, 50*time.Millisecond)
+	})
+}
+
 func (suite *redisTestSuite) TestPing() {
 	err := suite.Redis.Ping()
 	suite.Require().Nil(err)
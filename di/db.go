@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"time"
 
 	"github.com/defval/di"
 	"github.com/spf13/viper"
@@ -28,13 +29,15 @@ var db = di.Options(
 	di.Provide(newFSFactory,
 		di.As(new(http.CapesRepository)),
 	),
-	di.Provide(newMojangSignedTexturesStorage),
+	di.Provide(newMojangSignedTexturesStorage, di.As(new(mojangtextures.TexturesStorage))),
 )
 
 func newRedis(container *di.Container, config *viper.Viper) (*redis.Redis, error) {
 	config.SetDefault("storage.redis.host", "localhost")
 	config.SetDefault("storage.redis.port", 6379)
 	config.SetDefault("storage.redis.poolSize", 10)
+	config.SetDefault("mojang_textures.uuids_storage.duration", 30*24*time.Hour)
+	config.SetDefault("mojang_textures.uuids_storage.unknown_duration", 30*24*time.Hour)
 
 	conn, err := redis.New(
 		context.Background(),
@@ -45,6 +48,9 @@ func newRedis(container *di.Container, config *viper.Viper) (*redis.Redis, error
 		return nil, err
 	}
 
+	conn.MojangUuidDuration = config.GetDuration("mojang_textures.uuids_storage.duration")
+	conn.MojangNilUuidDuration = config.GetDuration("mojang_textures.uuids_storage.unknown_duration")
+
 	if err := container.Provide(func() *namedHealthChecker {
 		return &namedHealthChecker{
 			Name:    "redis",
@@ -54,6 +60,17 @@ func newRedis(container *di.Container, config *viper.Viper) (*redis.Redis, error
 		return nil, err
 	}
 
+	if err := container.Provide(func() *http.ShutdownHook {
+		return &http.ShutdownHook{
+			Name: "redis",
+			Shutdown: func(ctx context.Context) error {
+				return conn.Close()
+			},
+		}
+	}); err != nil {
+		return nil, err
+	}
+
 	return conn, nil
 }
 
@@ -67,6 +84,29 @@ func newFSFactory(config *viper.Viper) (*fs.Filesystem, error) {
 	))
 }
 
-func newMojangSignedTexturesStorage() mojangtextures.TexturesStorage {
-	return mojangtextures.NewInMemoryTexturesStorage()
+func newMojangSignedTexturesStorage(
+	container *di.Container,
+	config *viper.Viper,
+) (*mojangtextures.InMemoryTexturesStorage, error) {
+	config.SetDefault("mojang_textures.textures_storage.fresh_duration", time.Minute+10*time.Second)
+	config.SetDefault("mojang_textures.textures_storage.unknown_duration", time.Minute+10*time.Second)
+	config.SetDefault("mojang_textures.textures_storage.stale_duration", 0)
+
+	storage := mojangtextures.NewInMemoryTexturesStorage()
+	storage.Duration = config.GetDuration("mojang_textures.textures_storage.fresh_duration")
+	storage.NilDuration = config.GetDuration("mojang_textures.textures_storage.unknown_duration")
+	storage.StaleDuration = config.GetDuration("mojang_textures.textures_storage.stale_duration")
+	if err := container.Provide(func() *http.ShutdownHook {
+		return &http.ShutdownHook{
+			Name: "mojang-textures-storage",
+			Shutdown: func(ctx context.Context) error {
+				storage.Stop()
+				return nil
+			},
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	return storage, nil
 }
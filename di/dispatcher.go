@@ -1,13 +1,18 @@
 package di
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/defval/di"
 	"github.com/mono83/slf"
+	"github.com/spf13/viper"
 
 	d "github.com/elyby/chrly/dispatcher"
 	"github.com/elyby/chrly/eventsubscribers"
 	"github.com/elyby/chrly/http"
 	"github.com/elyby/chrly/mojangtextures"
+	"github.com/elyby/chrly/requestinfo"
 )
 
 var dispatcher = di.Options(
@@ -26,11 +31,31 @@ func newDispatcher() d.Dispatcher {
 }
 
 func enableEventsHandlers(
+	config *viper.Viper,
 	dispatcher d.Subscriber,
 	logger slf.Logger,
 	statsReporter slf.StatsReporter,
-) {
+	trustedProxies requestinfo.TrustedProxies,
+) error {
+	config.SetDefault("log.access_format", "text")
+
+	loggerSubscriber := &eventsubscribers.Logger{
+		Logger:         logger,
+		TrustedProxies: trustedProxies,
+	}
+
+	accessLogFormat := config.GetString("log.access_format")
+	switch accessLogFormat {
+	case "text":
+	case "json":
+		loggerSubscriber.AccessLogWriter = os.Stdout
+	default:
+		return fmt.Errorf("unknown access log format \"%s\"", accessLogFormat)
+	}
+
 	// TODO: use idea from https://github.com/defval/di/issues/10#issuecomment-615869852
-	(&eventsubscribers.Logger{Logger: logger}).ConfigureWithDispatcher(dispatcher)
+	loggerSubscriber.ConfigureWithDispatcher(dispatcher)
 	(&eventsubscribers.StatsReporter{StatsReporter: statsReporter}).ConfigureWithDispatcher(dispatcher)
+
+	return nil
 }
@@ -2,8 +2,11 @@ package di
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/defval/di"
 	"github.com/etherlabsio/healthcheck/v2"
@@ -12,6 +15,7 @@ import (
 
 	. "github.com/elyby/chrly/http"
 	"github.com/elyby/chrly/mojangtextures"
+	"github.com/elyby/chrly/requestinfo"
 )
 
 var handlers = di.Options(
@@ -56,6 +60,13 @@ func newHandlerFactory(
 			return nil, err
 		}
 
+		authenticator, err := newWorkerAuthenticator(container, config, emitter)
+		if err != nil {
+			return nil, err
+		}
+
+		workerRouter.Use(requestEventsMiddleware)
+		workerRouter.Use(CreateAuthenticationMiddleware(authenticator))
 		mount(router, "/api/worker", workerRouter)
 	}
 
@@ -65,12 +76,13 @@ func newHandlerFactory(
 			return nil, err
 		}
 
-		var authenticator Authenticator
+		var authenticator *JwtAuth
 		if err := container.Resolve(&authenticator); err != nil {
 			return nil, err
 		}
 
-		apiRouter.Use(CreateAuthenticationMiddleware(authenticator))
+		apiRouter.Use(requestEventsMiddleware)
+		apiRouter.Use(CreateAuthenticationMiddleware(authenticator.RequireScope(SkinScope)))
 
 		mount(router, "/api", apiRouter)
 	}
@@ -89,16 +101,52 @@ func newHandlerFactory(
 		}
 
 		checkersOptions := make([]healthcheck.Option, len(healthCheckers))
+		readinessCheckers := make([]*HealthChecker, len(healthCheckers))
 		for i, checker := range healthCheckers {
-			checkersOptions[i] = healthcheck.WithChecker(checker.Name, checker.Checker)
+			if checker.Observer {
+				checkersOptions[i] = healthcheck.WithObserver(checker.Name, checker.Checker)
+			} else {
+				checkersOptions[i] = healthcheck.WithChecker(checker.Name, checker.Checker)
+			}
+
+			readinessCheckers[i] = &HealthChecker{Name: checker.Name, Checker: checker.Checker, Observer: checker.Observer}
 		}
 
 		router.Handle("/healthcheck", healthcheck.Handler(checkersOptions...)).Methods("GET")
+		router.Handle("/healthz/ready", CreateHealthHandler(readinessCheckers)).Methods("GET")
 	}
 
+	// All the checked services (Redis, Mojang, the queue) recover without the restart of the application,
+	// so the liveness probe only confirms that the server responds
+	router.Handle("/healthz/live", CreateHealthHandler(nil)).Methods("GET")
+
 	return router, nil
 }
 
+// newWorkerAuthenticator accepts either the client certificate, when the server verifies them,
+// or the token issued for the worker scope, when the secret is set
+func newWorkerAuthenticator(container *di.Container, config *viper.Viper, emitter Emitter) (Authenticator, error) {
+	var authenticators AnyAuthenticator
+	if config.GetString("server.tls.client_ca_file") != "" {
+		authenticators = append(authenticators, &ClientCertificateAuth{Emitter: emitter})
+	}
+
+	if config.GetString("chrly.secret") != "" {
+		var jwtAuth *JwtAuth
+		if err := container.Resolve(&jwtAuth); err != nil {
+			return nil, err
+		}
+
+		authenticators = append(authenticators, jwtAuth.RequireScope(WorkerScope))
+	}
+
+	if len(authenticators) == 0 {
+		return nil, errors.New("either chrly.secret or server.tls.client_ca_file must be set in order to authenticate the worker requests")
+	}
+
+	return authenticators, nil
+}
+
 func newSkinsystemHandler(
 	config *viper.Viper,
 	emitter Emitter,
@@ -106,9 +154,17 @@ func newSkinsystemHandler(
 	capesRepository CapesRepository,
 	mojangTexturesProvider MojangTexturesProvider,
 	texturesSigner TexturesSigner,
+	trustedProxies requestinfo.TrustedProxies,
 ) (*mux.Router, error) {
 	config.SetDefault("textures.extra_param_name", "chrly")
 	config.SetDefault("textures.extra_param_value", "how do you tame a horse in Minecraft?")
+	config.SetDefault("storage.filesystem.basePath", "data")
+	config.SetDefault("mojang_textures.mirror.enabled", false)
+	config.SetDefault("mojang_textures.mirror.base_path", path.Join(config.GetString("storage.filesystem.basePath"), "mojang-textures"))
+	// The permanent redirects are cached forever, so the changed textures wouldn't be displayed
+	config.SetDefault("textures.redirect_status", http.StatusFound)
+	// The server's write timeout is 5 seconds, so there is some time left to respond
+	config.SetDefault("mojang_textures.request_timeout", 4*time.Second)
 
 	app, err := NewSkinsystem(
 		emitter,
@@ -123,13 +179,69 @@ func newSkinsystemHandler(
 		return nil, err
 	}
 
+	app.TrustedProxies = trustedProxies
+	app.MojangTexturesTimeout = config.GetDuration("mojang_textures.request_timeout")
+	if config.GetBool("mojang_textures.mirror.enabled") {
+		app.TexturesMirror = &mojangtextures.FilesystemTexturesMirror{
+			BasePath:     config.GetString("mojang_textures.mirror.base_path"),
+			AllowedHosts: config.GetStringSlice("mojang_textures.mirror.allowed_hosts"),
+		}
+	}
+
+	switch status := config.GetInt("textures.redirect_status"); status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect:
+		app.RedirectStatus = status
+	default:
+		return nil, fmt.Errorf("textures.redirect_status must be one of 301, 302 or 307, but %d given", status)
+	}
+
+	app.CachePolicies = map[string]*RouteCachePolicy{}
+	for _, route := range []string{RouteSkins, RouteCloaks, RouteTextures, RouteSignedTextures, RouteProfile} {
+		local, err := newCachePolicy(config, "cache."+route+".local.")
+		if err != nil {
+			return nil, err
+		}
+
+		mojang, err := newCachePolicy(config, "cache."+route+".mojang.")
+		if err != nil {
+			return nil, err
+		}
+
+		if local != nil || mojang != nil {
+			app.CachePolicies[route] = &RouteCachePolicy{Local: local, Mojang: mojang}
+		}
+	}
+
 	return app.Handler(), nil
 }
 
-func newApiHandler(skinsRepository SkinsRepository) *mux.Router {
-	return (&Api{
+// newCachePolicy returns nil when none of the policy params is set, so the Cache-Control header isn't sent
+func newCachePolicy(config *viper.Viper, prefix string) (*CachePolicy, error) {
+	if !config.IsSet(prefix+"max_age") && !config.IsSet(prefix+"s_maxage") && !config.IsSet(prefix+"stale_while_revalidate") {
+		return nil, nil
+	}
+
+	policy := &CachePolicy{
+		MaxAge:               config.GetDuration(prefix + "max_age"),
+		SharedMaxAge:         config.GetDuration(prefix + "s_maxage"),
+		StaleWhileRevalidate: config.GetDuration(prefix + "stale_while_revalidate"),
+	}
+	if policy.MaxAge < 0 || policy.SharedMaxAge < 0 || policy.StaleWhileRevalidate < 0 {
+		return nil, fmt.Errorf("%s* durations must not be negative", prefix)
+	}
+
+	return policy, nil
+}
+
+func newApiHandler(skinsRepository SkinsRepository, mojangTexturesProvider MojangTexturesProvider) *mux.Router {
+	api := &Api{
 		SkinsRepo: skinsRepository,
-	}).Handler()
+	}
+	if purger, ok := mojangTexturesProvider.(MojangTexturesPurger); ok {
+		api.MojangTexturesPurger = purger
+	}
+
+	return api.Handler()
 }
 
 func newUUIDsWorkerHandler(mojangUUIDsProvider *mojangtextures.BatchUuidsProvider) *mux.Router {
@@ -160,4 +272,6 @@ func mount(router *mux.Router, path string, handler http.Handler) {
 type namedHealthChecker struct {
 	Name    string
 	Checker healthcheck.Checker
+	// Observer checkers are displayed in the health check responses, but don't affect the overall status
+	Observer bool
 }
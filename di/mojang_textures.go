@@ -2,21 +2,27 @@ package di
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	gohttp "net/http"
 	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/defval/di"
 	"github.com/spf13/viper"
 
 	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/db/redis"
 	es "github.com/elyby/chrly/eventsubscribers"
 	"github.com/elyby/chrly/http"
 	"github.com/elyby/chrly/mojangtextures"
 )
 
 var mojangTextures = di.Options(
-	di.Invoke(interceptMojangApiUrls),
+	di.Provide(newMojangClient),
 	di.Provide(newMojangTexturesProviderFactory),
 	di.Provide(newMojangTexturesProvider),
 	di.Provide(newMojangTexturesUuidsProviderFactory),
@@ -24,39 +30,154 @@ var mojangTextures = di.Options(
 	di.Provide(newMojangTexturesBatchUUIDsProviderStrategyFactory),
 	di.Provide(newMojangTexturesBatchUUIDsProviderDelayedStrategy),
 	di.Provide(newMojangTexturesBatchUUIDsProviderFullBusStrategy),
+	di.Provide(newMojangTexturesBatchUUIDsProviderAdaptiveStrategy),
+	di.Provide(newMojangTexturesBatchUUIDsProviderSharedStrategy),
 	di.Provide(newMojangTexturesRemoteUUIDsProvider),
 	di.Provide(newMojangSignedTexturesProvider),
 	di.Provide(newMojangTexturesStorageFactory),
 )
 
-func interceptMojangApiUrls(config *viper.Viper) error {
-	apiUrl := config.GetString("mojang.api_base_url")
-	if apiUrl != "" {
-		u, err := url.ParseRequestURI(apiUrl)
+// newMojangClient returns the client for the Mojang's API and Session server. It has its own http client,
+// so the proxies and the timeouts configured for Mojang don't affect the other upstreams
+func newMojangClient(container *di.Container, config *viper.Viper) (*mojang.Client, error) {
+	config.SetDefault("mojang.api_base_url", mojang.DefaultApiBaseUrl)
+	config.SetDefault("mojang.session_server_base_url", mojang.DefaultSessionServerBaseUrl)
+	config.SetDefault("mojang.timeout", 10*time.Second)
+	config.SetDefault("mojang.retries", 0)
+	config.SetDefault("mojang.retry_delay", 500*time.Millisecond)
+	config.SetDefault("mojang.retry_max_delay", 5*time.Second)
+
+	apiBaseUrl, err := parseMojangBaseUrl(config, "mojang.api_base_url")
+	if err != nil {
+		return nil, err
+	}
+
+	sessionServerBaseUrl, err := parseMojangBaseUrl(config, "mojang.session_server_base_url")
+	if err != nil {
+		return nil, err
+	}
+
+	headers := gohttp.Header{}
+	for name, value := range config.GetStringMapString("mojang.headers") {
+		headers.Set(name, value)
+	}
+
+	transport, err := newMojangTransport(container, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mojang.Client{
+		ApiBaseUrl:           apiBaseUrl,
+		SessionServerBaseUrl: sessionServerBaseUrl,
+		HttpClient:           &gohttp.Client{Transport: transport},
+		Timeout:              config.GetDuration("mojang.timeout"),
+		UserAgent:            config.GetString("mojang.user_agent"),
+		Headers:              headers,
+		Retries:              config.GetInt("mojang.retries"),
+		RetryBackoff: mojang.Backoff{
+			Base: config.GetDuration("mojang.retry_delay"),
+			Max:  config.GetDuration("mojang.retry_max_delay"),
+		},
+	}, nil
+}
+
+func parseMojangBaseUrl(config *viper.Viper, key string) (string, error) {
+	u, err := url.ParseRequestURI(config.GetString(key))
+	if err != nil {
+		return "", fmt.Errorf("unable to parse %s: %w", key, err)
+	}
+
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// newMojangTransport makes the requests to Mojang rotate between the configured proxies,
+// so the Mojang's rate limits are applied to each of them separately
+func newMojangTransport(container *di.Container, config *viper.Viper) (gohttp.RoundTripper, error) {
+	config.SetDefault("mojang.proxies_bench_duration", time.Minute)
+
+	proxiesUrls := config.GetStringSlice("mojang.proxies")
+	if len(proxiesUrls) == 0 {
+		return &gohttp.Transport{
+			MaxIdleConnsPerHost: 1024,
+		}, nil
+	}
+
+	proxies := make([]*mojang.Proxy, len(proxiesUrls))
+	for i, proxyUrl := range proxiesUrls {
+		proxy, err := mojang.NewProxy(proxyUrl)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("unable to parse proxy url: %w", err)
 		}
 
-		mojang.ApiMojangDotComAddr = u.String()
+		proxies[i] = proxy
 	}
 
-	sessionServerUrl := config.GetString("mojang.session_server_base_url")
-	if sessionServerUrl != "" {
-		u, err := url.ParseRequestURI(apiUrl)
-		if err != nil {
-			return err
+	transport := &mojang.ProxiesTransport{
+		Proxies:       proxies,
+		BenchDuration: config.GetDuration("mojang.proxies_bench_duration"),
+	}
+
+	if err := container.Provide(func() *namedHealthChecker {
+		return &namedHealthChecker{
+			Name:    "mojang-proxies",
+			Checker: es.StatusChecker(transport),
 		}
+	}); err != nil {
+		return nil, err
+	}
 
-		mojang.SessionServerMojangComAddr = u.String()
+	for _, proxy := range proxies {
+		proxy := proxy
+		if err := container.Provide(func() *namedHealthChecker {
+			return &namedHealthChecker{
+				Name:     "mojang-proxy:" + proxy.String(),
+				Checker:  es.StatusChecker(proxy),
+				Observer: true,
+			}
+		}); err != nil {
+			return nil, err
+		}
 	}
 
-	return nil
+	return transport, nil
 }
 
+// newMojangTexturesProviderFactory returns the chain of the configured upstreams
+// or the Mojang's provider itself when there are no other upstreams
 func newMojangTexturesProviderFactory(
 	container *di.Container,
 	config *viper.Viper,
+	emitter mojangtextures.Emitter,
 ) (http.MojangTexturesProvider, error) {
+	config.SetDefault("upstreams.order", []string{"mojang"})
+
+	names := config.GetStringSlice("upstreams.order")
+	if len(names) == 1 && names[0] == "mojang" {
+		return newMojangUpstreamProvider(container, config)
+	}
+
+	chain := &mojangtextures.ChainProvider{}
+	for _, name := range names {
+		var provider mojangtextures.UsernameTexturesProvider
+		var err error
+		if name == "mojang" {
+			provider, err = newMojangUpstreamProvider(container, config)
+		} else {
+			provider, err = newUpstreamProvider(container, config, emitter, name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		chain.Providers = append(chain.Providers, provider)
+	}
+
+	return chain, nil
+}
+
+func newMojangUpstreamProvider(container *di.Container, config *viper.Viper) (http.MojangTexturesProvider, error) {
 	config.SetDefault("mojang_textures.enabled", true)
 	if !config.GetBool("mojang_textures.enabled") {
 		return &mojangtextures.NilProvider{}, nil
@@ -71,18 +192,152 @@ func newMojangTexturesProviderFactory(
 	return provider, nil
 }
 
+var upstreamNameRegex = regexp.MustCompile(`^[a-z0-9]+$`)
+
+func newUpstreamProvider(
+	container *di.Container,
+	config *viper.Viper,
+	emitter mojangtextures.Emitter,
+	name string,
+) (*mojangtextures.UpstreamProvider, error) {
+	// The name is a part of the config keys, so it must be representable in the env variables names
+	if !upstreamNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid upstream name \"%s\"", name)
+	}
+
+	prefix := "upstreams." + name + "."
+	config.SetDefault(prefix+"cache_duration", time.Minute+10*time.Second)
+	config.SetDefault(prefix+"unknown_cache_duration", 10*time.Second)
+	// The upstreams are requested within the textures request, so they must not take the whole request time
+	config.SetDefault(prefix+"timeout", 3*time.Second)
+
+	var upstream mojangtextures.Upstream
+	switch upstreamType := config.GetString(prefix + "type"); upstreamType {
+	case "chrly":
+		rawUrl := config.GetString(prefix + "url")
+		if rawUrl == "" {
+			return nil, fmt.Errorf("%surl must be set in order to use the chrly upstream", prefix)
+		}
+
+		u, err := url.Parse(rawUrl)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the upstream url: %w", err)
+		}
+
+		upstream = &mojangtextures.ChrlyUpstream{
+			Url: *u,
+			Client: &gohttp.Client{
+				Transport: mojangtextures.HttpClient.Transport,
+				Timeout:   config.GetDuration(prefix + "timeout"),
+			},
+		}
+	case "yggdrasil":
+		apiUrl := config.GetString(prefix + "api_url")
+		sessionServerUrl := config.GetString(prefix + "session_server_url")
+		if apiUrl == "" || sessionServerUrl == "" {
+			return nil, fmt.Errorf("both %sapi_url and %ssession_server_url must be set in order to use the yggdrasil upstream", prefix, prefix)
+		}
+
+		upstream = &mojangtextures.YggdrasilUpstream{
+			Client: &mojang.Client{
+				ApiBaseUrl:           strings.TrimSuffix(apiUrl, "/"),
+				SessionServerBaseUrl: strings.TrimSuffix(sessionServerUrl, "/"),
+				// The Mojang's client may be configured to use the proxies, which are needed only for Mojang
+				HttpClient: &gohttp.Client{
+					Transport: mojangtextures.HttpClient.Transport,
+					// Limits the whole lookup, while the client's timeout limits each of its requests
+					Timeout: 2 * config.GetDuration(prefix+"timeout"),
+				},
+				Timeout: config.GetDuration(prefix + "timeout"),
+			},
+		}
+	default:
+		return nil, fmt.Errorf("unknown type \"%s\" of the upstream \"%s\"", upstreamType, name)
+	}
+
+	storage := mojangtextures.NewInMemoryTexturesStorage()
+	storage.Duration = config.GetDuration(prefix + "cache_duration")
+	storage.NilDuration = config.GetDuration(prefix + "unknown_cache_duration")
+	if err := container.Provide(func() *http.ShutdownHook {
+		return &http.ShutdownHook{
+			Name: "upstream-storage:" + name,
+			Shutdown: func(ctx context.Context) error {
+				storage.Stop()
+				return nil
+			},
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := container.Provide(func(emitter es.Subscriber, config *viper.Viper) *namedHealthChecker {
+		config.SetDefault("healthcheck.upstreams_cool_down_duration", time.Minute)
+
+		return &namedHealthChecker{
+			Name: "upstream:" + name,
+			Checker: es.UpstreamResponseChecker(
+				emitter,
+				name,
+				config.GetDuration("healthcheck.upstreams_cool_down_duration"),
+			),
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	return &mojangtextures.UpstreamProvider{
+		Emitter:  emitter,
+		Upstream: upstream,
+		Name:     name,
+		Storage:  storage,
+	}, nil
+}
+
 func newMojangTexturesProvider(
+	container *di.Container,
+	config *viper.Viper,
 	emitter mojangtextures.Emitter,
 	uuidsProvider mojangtextures.UUIDsProvider,
 	texturesProvider mojangtextures.TexturesProvider,
 	storage mojangtextures.Storage,
-) *mojangtextures.Provider {
-	return &mojangtextures.Provider{
+) (*mojangtextures.Provider, error) {
+	config.SetDefault("mojang_textures.prefetch.threshold", 0)
+	config.SetDefault("mojang_textures.prefetch.window", 10*time.Minute)
+	config.SetDefault("mojang_textures.prefetch.refresh_after", time.Minute)
+	config.SetDefault("mojang_textures.prefetch.interval", 5*time.Second)
+	config.SetDefault("mojang_textures.prefetch.rate_limit", 60)
+
+	provider := &mojangtextures.Provider{
 		Emitter:          emitter,
 		UUIDsProvider:    uuidsProvider,
 		TexturesProvider: texturesProvider,
 		Storage:          storage,
 	}
+
+	threshold := config.GetInt("mojang_textures.prefetch.threshold")
+	// The limit is set in requests per minute
+	rateLimit := config.GetInt("mojang_textures.prefetch.rate_limit")
+	if threshold > 0 && rateLimit > 0 {
+		provider.Prefetcher = &mojangtextures.Prefetcher{
+			Emitter:      emitter,
+			Threshold:    threshold,
+			Window:       config.GetDuration("mojang_textures.prefetch.window"),
+			RefreshAfter: config.GetDuration("mojang_textures.prefetch.refresh_after"),
+			Interval:     config.GetDuration("mojang_textures.prefetch.interval"),
+			RateLimiter:  &mojangtextures.LocalRateLimiter{Interval: time.Minute / time.Duration(rateLimit)},
+		}
+	}
+
+	if err := container.Provide(func() *http.ShutdownHook {
+		return &http.ShutdownHook{
+			Name:     "mojang-textures-provider",
+			Shutdown: provider.Shutdown,
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	return provider, nil
 }
 
 func newMojangTexturesUuidsProviderFactory(
@@ -90,7 +345,8 @@ func newMojangTexturesUuidsProviderFactory(
 	container *di.Container,
 ) (mojangtextures.UUIDsProvider, error) {
 	preferredUuidsProvider := config.GetString("mojang_textures.uuids_provider.driver")
-	if preferredUuidsProvider == "remote" {
+	// In the batch mode the usernames are queued locally and sent to the remote worker as a whole batch
+	if preferredUuidsProvider == "remote" && !config.GetBool("mojang_textures.uuids_provider.batch") {
 		var provider *mojangtextures.RemoteApiUuidsProvider
 		err := container.Resolve(&provider)
 
@@ -105,8 +361,10 @@ func newMojangTexturesUuidsProviderFactory(
 
 func newMojangTexturesBatchUUIDsProvider(
 	container *di.Container,
+	config *viper.Viper,
 	strategy mojangtextures.BatchUuidsProviderStrategy,
 	emitter mojangtextures.Emitter,
+	client *mojang.Client,
 ) (*mojangtextures.BatchUuidsProvider, error) {
 	if err := container.Provide(func(emitter es.Subscriber, config *viper.Viper) *namedHealthChecker {
 		config.SetDefault("healthcheck.mojang_batch_uuids_provider_cool_down_duration", time.Minute)
@@ -125,6 +383,7 @@ func newMojangTexturesBatchUUIDsProvider(
 	if err := container.Provide(func(emitter es.Subscriber, config *viper.Viper) *namedHealthChecker {
 		config.SetDefault("healthcheck.mojang_batch_uuids_provider_queue_length_limit", 50)
 
+		// The long queue means that the instance is overloaded, so it's a readiness check and the instance isn't restarted
 		return &namedHealthChecker{
 			Name: "mojang-batch-uuids-provider-queue-length",
 			Checker: es.MojangBatchUuidsProviderQueueLengthChecker(
@@ -136,7 +395,46 @@ func newMojangTexturesBatchUUIDsProvider(
 		return nil, err
 	}
 
-	return mojangtextures.NewBatchUuidsProvider(context.Background(), strategy, emitter), nil
+	provider := mojangtextures.NewBatchUuidsProvider(context.Background(), strategy, emitter)
+	circuitBreaker, err := newMojangTexturesCircuitBreaker(container, config, emitter, "usernames")
+	if err != nil {
+		return nil, err
+	}
+
+	config.SetDefault("queue.max_retries", 2)
+	config.SetDefault("queue.retry_delay", time.Second)
+	config.SetDefault("queue.retry_max_delay", 10*time.Second)
+
+	provider.CircuitBreaker = circuitBreaker
+	// Only the leader performs the requests, so the other replicas learn the Mojang's state from its results
+	if sharedStrategy, ok := strategy.(*mojangtextures.SharedStrategy); ok && circuitBreaker != nil {
+		sharedStrategy.CircuitBreaker = circuitBreaker
+	}
+	provider.UsernamesToUuids = client.UsernamesToUuids
+	provider.MaxRetries = config.GetInt("queue.max_retries")
+	provider.RetryBackoff = mojang.Backoff{
+		Base: config.GetDuration("queue.retry_delay"),
+		Max:  config.GetDuration("queue.retry_max_delay"),
+	}
+
+	if config.GetString("mojang_textures.uuids_provider.driver") == "remote" && config.GetBool("mojang_textures.uuids_provider.batch") {
+		var remoteProvider *mojangtextures.RemoteApiUuidsProvider
+		if err := container.Resolve(&remoteProvider); err != nil {
+			return nil, err
+		}
+
+		provider.UsernamesToUuids = remoteProvider.GetUuids
+	}
+	if err := container.Provide(func() *http.ShutdownHook {
+		return &http.ShutdownHook{
+			Name:     "mojang-batch-uuids-provider",
+			Shutdown: provider.Shutdown,
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	return provider, nil
 }
 
 func newMojangTexturesBatchUUIDsProviderStrategyFactory(
@@ -162,6 +460,22 @@ func newMojangTexturesBatchUUIDsProviderStrategyFactory(
 			return nil, err
 		}
 
+		return strategy, nil
+	case "adaptive":
+		var strategy *mojangtextures.AdaptiveStrategy
+		err := container.Resolve(&strategy)
+		if err != nil {
+			return nil, err
+		}
+
+		return strategy, nil
+	case "shared":
+		var strategy *mojangtextures.SharedStrategy
+		err := container.Resolve(&strategy)
+		if err != nil {
+			return nil, err
+		}
+
 		return strategy, nil
 	default:
 		return nil, fmt.Errorf("unknown queue strategy \"%s\"", strategyName)
@@ -188,14 +502,138 @@ func newMojangTexturesBatchUUIDsProviderFullBusStrategy(config *viper.Viper) *mo
 	)
 }
 
+func newMojangTexturesBatchUUIDsProviderAdaptiveStrategy(
+	config *viper.Viper,
+	emitter mojangtextures.Emitter,
+) (*mojangtextures.AdaptiveStrategy, error) {
+	config.SetDefault("queue.batch_size", 10)
+	config.SetDefault("queue.max_rate", 0.4)
+	config.SetDefault("queue.min_rate", 0.05)
+	config.SetDefault("queue.rate_ramp_up", 0.01)
+
+	maxRate := config.GetFloat64("queue.max_rate")
+	minRate := config.GetFloat64("queue.min_rate")
+	if minRate <= 0 || maxRate < minRate {
+		return nil, errors.New("queue.min_rate must be positive and not greater than queue.max_rate")
+	}
+
+	return mojangtextures.NewAdaptiveStrategy(
+		emitter,
+		config.GetInt("queue.batch_size"),
+		maxRate,
+		minRate,
+		config.GetFloat64("queue.rate_ramp_up"),
+	), nil
+}
+
+// newMojangTexturesBatchUUIDsProviderSharedStrategy stores the queue in Redis, so all the replicas
+// share it and only one of them sends the requests to Mojang
+func newMojangTexturesBatchUUIDsProviderSharedStrategy(
+	container *di.Container,
+	config *viper.Viper,
+	emitter mojangtextures.Emitter,
+) (*mojangtextures.SharedStrategy, error) {
+	config.SetDefault("queue.loop_delay", 2*time.Second+500*time.Millisecond)
+	config.SetDefault("queue.batch_size", 10)
+	config.SetDefault("queue.leadership_ttl", 15*time.Second)
+	config.SetDefault("queue.result_timeout", time.Minute)
+
+	var client *mojang.Client
+	if err := container.Resolve(&client); err != nil {
+		return nil, err
+	}
+
+	// The leadership must outlive the round, even if the leader has failed right after its renewal
+	delay := config.GetDuration("queue.loop_delay")
+	leadershipTtl := config.GetDuration("queue.leadership_ttl")
+	if leadershipTtl <= delay+client.Timeout {
+		return nil, errors.New("queue.leadership_ttl must be greater than the sum of queue.loop_delay and mojang.timeout")
+	}
+
+	var conn *redis.Redis
+	if err := container.Resolve(&conn); err != nil {
+		return nil, err
+	}
+
+	strategy := mojangtextures.NewSharedStrategy(
+		emitter,
+		conn.NewJobsQueue("mojang-usernames"),
+		delay,
+		config.GetInt("queue.batch_size"),
+		leadershipTtl,
+	)
+	strategy.ResultTimeout = config.GetDuration("queue.result_timeout")
+
+	return strategy, nil
+}
+
 func newMojangTexturesRemoteUUIDsProvider(
 	container *di.Container,
 	config *viper.Viper,
 	emitter mojangtextures.Emitter,
 ) (*mojangtextures.RemoteApiUuidsProvider, error) {
-	remoteUrl, err := url.Parse(config.GetString("mojang_textures.uuids_provider.url"))
+	config.SetDefault("mojang_textures.uuids_provider.balancing", string(mojangtextures.LeastInFlightBalancing))
+	config.SetDefault("mojang_textures.uuids_provider.cooldown", time.Minute)
+
+	balancing := mojangtextures.WorkersBalancing(config.GetString("mojang_textures.uuids_provider.balancing"))
+	if balancing != mojangtextures.LeastInFlightBalancing && balancing != mojangtextures.RoundRobinBalancing {
+		return nil, fmt.Errorf("unknown remote workers balancing \"%s\"", balancing)
+	}
+
+	remoteUrls := config.GetStringSlice("mojang_textures.uuids_provider.url")
+	if len(remoteUrls) == 0 {
+		return nil, errors.New("mojang_textures.uuids_provider.url must be set in order to use the remote uuids provider")
+	}
+
+	workers := make([]*mojangtextures.RemoteApiWorker, len(remoteUrls))
+	for i, remoteUrl := range remoteUrls {
+		u, err := url.Parse(remoteUrl)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse remote url: %w", err)
+		}
+
+		workers[i] = &mojangtextures.RemoteApiWorker{Url: *u}
+	}
+
+	token, err := newMojangTexturesRemoteUUIDsProviderToken(container, config)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newMojangTexturesRemoteUUIDsProviderClient(config)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse remote url: %w", err)
+		return nil, err
+	}
+
+	provider := &mojangtextures.RemoteApiUuidsProvider{
+		Emitter:   emitter,
+		Workers:   workers,
+		Balancing: balancing,
+		Cooldown:  config.GetDuration("mojang_textures.uuids_provider.cooldown"),
+		Token:     token,
+		Client:    client,
+	}
+
+	if err := container.Provide(func() *namedHealthChecker {
+		return &namedHealthChecker{
+			Name:    "mojang-remote-uuids-provider-workers",
+			Checker: es.StatusChecker(provider),
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, worker := range workers {
+		worker := worker
+		if err := container.Provide(func() *namedHealthChecker {
+			return &namedHealthChecker{
+				Name:     "mojang-remote-uuids-worker:" + worker.Url.String(),
+				Checker:  es.StatusChecker(worker),
+				Observer: true,
+			}
+		}); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := container.Provide(func(emitter es.Subscriber, config *viper.Viper) *namedHealthChecker {
@@ -212,16 +650,154 @@ func newMojangTexturesRemoteUUIDsProvider(
 		return nil, err
 	}
 
-	return &mojangtextures.RemoteApiUuidsProvider{
-		Emitter: emitter,
-		Url:     *remoteUrl,
+	return provider, nil
+}
+
+// newMojangTexturesRemoteUUIDsProviderToken returns the explicitly configured token or issues a new one
+// for the worker scope when the workers share the same secret
+func newMojangTexturesRemoteUUIDsProviderToken(container *di.Container, config *viper.Viper) (string, error) {
+	if token := config.GetString("mojang_textures.uuids_provider.token"); token != "" {
+		return token, nil
+	}
+
+	if config.GetString("chrly.secret") == "" {
+		return "", nil
+	}
+
+	var auth *http.JwtAuth
+	if err := container.Resolve(&auth); err != nil {
+		return "", err
+	}
+
+	token, err := auth.NewToken(http.WorkerScope)
+	if err != nil {
+		return "", err
+	}
+
+	return string(token), nil
+}
+
+// newMojangTexturesRemoteUUIDsProviderClient returns nil when there is no need to customize
+// the TLS settings, so the default client will be used
+func newMojangTexturesRemoteUUIDsProviderClient(config *viper.Viper) (*gohttp.Client, error) {
+	certFile := config.GetString("mojang_textures.uuids_provider.tls.cert_file")
+	keyFile := config.GetString("mojang_textures.uuids_provider.tls.key_file")
+	caFile := config.GetString("mojang_textures.uuids_provider.tls.ca_file")
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("both mojang_textures.uuids_provider.tls.cert_file and mojang_textures.uuids_provider.tls.key_file must be set in order to use the client certificate")
+		}
+
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		rootCAs, err := http.LoadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	return &gohttp.Client{
+		Transport: &gohttp.Transport{
+			MaxIdleConnsPerHost: 1024,
+			TLSClientConfig:     tlsConfig,
+			ForceAttemptHTTP2:   true,
+		},
 	}, nil
 }
 
-func newMojangSignedTexturesProvider(emitter mojangtextures.Emitter) mojangtextures.TexturesProvider {
-	return &mojangtextures.MojangApiTexturesProvider{
-		Emitter: emitter,
+func newMojangSignedTexturesProvider(
+	container *di.Container,
+	config *viper.Viper,
+	emitter mojangtextures.Emitter,
+	client *mojang.Client,
+) (mojangtextures.TexturesProvider, error) {
+	config.SetDefault("mojang_textures.textures_provider.rate_limit", 0)
+	config.SetDefault("mojang_textures.textures_provider.rate_limit_storage", "local")
+	config.SetDefault("mojang_textures.textures_provider.max_wait", 3*time.Second)
+
+	circuitBreaker, err := newMojangTexturesCircuitBreaker(container, config, emitter, "textures")
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &mojangtextures.MojangApiTexturesProvider{
+		Emitter:        emitter,
+		UuidToTextures: client.UuidToTextures,
+		MaxWait:        config.GetDuration("mojang_textures.textures_provider.max_wait"),
+		CircuitBreaker: circuitBreaker,
+	}
+
+	// The limit is set in requests per minute
+	rateLimit := config.GetInt("mojang_textures.textures_provider.rate_limit")
+	if rateLimit <= 0 {
+		return provider, nil
+	}
+
+	interval := time.Minute / time.Duration(rateLimit)
+	storage := config.GetString("mojang_textures.textures_provider.rate_limit_storage")
+	switch storage {
+	case "local":
+		provider.RateLimiter = &mojangtextures.LocalRateLimiter{Interval: interval}
+	case "redis":
+		var conn *redis.Redis
+		if err := container.Resolve(&conn); err != nil {
+			return nil, err
+		}
+
+		provider.RateLimiter = conn.NewRateLimiter("mojang-textures-provider", interval)
+	default:
+		return nil, fmt.Errorf("unknown rate limit storage \"%s\"", storage)
+	}
+
+	return provider, nil
+}
+
+// newMojangTexturesCircuitBreaker returns nil when the circuit breaker is disabled
+func newMojangTexturesCircuitBreaker(
+	container *di.Container,
+	config *viper.Viper,
+	emitter mojangtextures.Emitter,
+	name string,
+) (*mojangtextures.CircuitBreaker, error) {
+	config.SetDefault("mojang_textures.circuit_breaker.failure_threshold", 5)
+	config.SetDefault("mojang_textures.circuit_breaker.open_duration", 30*time.Second)
+
+	failureThreshold := config.GetInt("mojang_textures.circuit_breaker.failure_threshold")
+	if failureThreshold <= 0 {
+		return nil, nil
+	}
+
+	circuitBreaker := &mojangtextures.CircuitBreaker{
+		Emitter:          emitter,
+		Name:             name,
+		FailureThreshold: failureThreshold,
+		OpenDuration:     config.GetDuration("mojang_textures.circuit_breaker.open_duration"),
 	}
+
+	if err := container.Provide(func() *namedHealthChecker {
+		return &namedHealthChecker{
+			Name:    "mojang-" + name + "-circuit-breaker",
+			Checker: es.StatusChecker(circuitBreaker),
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	return circuitBreaker, nil
 }
 
 func newMojangTexturesStorageFactory(
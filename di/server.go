@@ -1,6 +1,7 @@
 package di
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/http"
@@ -9,14 +10,18 @@ import (
 
 	"github.com/defval/di"
 	"github.com/getsentry/raven-go"
+	"github.com/mono83/slf"
 	"github.com/spf13/viper"
 
 	. "github.com/elyby/chrly/http"
+	"github.com/elyby/chrly/requestinfo"
 )
 
 var server = di.Options(
 	di.Provide(newAuthenticator, di.As(new(Authenticator))),
+	di.Provide(newTrustedProxies),
 	di.Provide(newServer),
+	di.Provide(newGracefulShutdown),
 )
 
 func newAuthenticator(config *viper.Viper, emitter Emitter) (*JwtAuth, error) {
@@ -31,25 +36,26 @@ func newAuthenticator(config *viper.Viper, emitter Emitter) (*JwtAuth, error) {
 	}, nil
 }
 
+func newTrustedProxies(config *viper.Viper) (requestinfo.TrustedProxies, error) {
+	return requestinfo.ParseTrustedProxies(config.GetStringSlice("server.trusted_proxies"))
+}
+
 type serverParams struct {
 	di.Inject
 
 	Config  *viper.Viper  `di:""`
 	Handler http.Handler  `di:""`
+	Logger  slf.Logger    `di:""`
 	Sentry  *raven.Client `di:"" optional:"true"`
 }
 
-func newServer(params serverParams) *http.Server {
+func newServer(params serverParams) (*http.Server, error) {
 	params.Config.SetDefault("server.host", "")
 	params.Config.SetDefault("server.port", 80)
 
 	var handler http.Handler
 	if params.Sentry != nil {
-		// raven.Recoverer uses DefaultClient and nothing can be done about it
-		// To avoid code duplication, if the Sentry service is successfully initiated,
-		// it will also replace DefaultClient, so raven.Recoverer will work with the instance
-		// created in the application constructor
-		handler = raven.Recoverer(params.Handler)
+		handler = newSentryRecoverer(params.Sentry, params.Handler)
 	} else {
 		// Raven's Recoverer is prints the stacktrace and sets the corresponding status itself.
 		// But there is no magic and if you don't define a panic handler, Mux will just reset the connection
@@ -65,6 +71,9 @@ func newServer(params serverParams) *http.Server {
 		})
 	}
 
+	// The request id must be assigned before anything else to be available for the panics handlers
+	handler = CreateRequestIdMiddleware()(handler)
+
 	address := fmt.Sprintf("%s:%d", params.Config.GetString("server.host"), params.Config.GetInt("server.port"))
 	server := &http.Server{
 		Addr:           address,
@@ -75,5 +84,93 @@ func newServer(params serverParams) *http.Server {
 		Handler:        handler,
 	}
 
-	return server
+	certFile := params.Config.GetString("server.tls.cert_file")
+	keyFile := params.Config.GetString("server.tls.key_file")
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("both server.tls.cert_file and server.tls.key_file must be set in order to enable TLS")
+		}
+
+		reloader, err := NewCertificateReloader(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		reloader.ReloadOnSighup(params.Logger)
+		server.TLSConfig = reloader.TLSConfig()
+	}
+
+	if clientCaFile := params.Config.GetString("server.tls.client_ca_file"); clientCaFile != "" {
+		if server.TLSConfig == nil {
+			return nil, errors.New("server.tls.cert_file and server.tls.key_file must be set in order to verify client certificates")
+		}
+
+		clientCAs, err := LoadCertPool(clientCaFile)
+		if err != nil {
+			return nil, err
+		}
+
+		// The certificate is optional at the TLS level, because only the worker endpoints require it.
+		// The presented certificate, however, must always be valid
+		server.TLSConfig.ClientCAs = clientCAs
+		server.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return server, nil
+}
+
+func newGracefulShutdown(container *di.Container, config *viper.Viper) (*GracefulShutdown, error) {
+	config.SetDefault("server.shutdown_timeout", 30*time.Second)
+
+	var hooks []*ShutdownHook
+	if has, _ := container.Has(&hooks); has {
+		if err := container.Resolve(&hooks); err != nil {
+			return nil, err
+		}
+	}
+
+	// Hooks are registered by the services as they are created, so the dependent services
+	// appear later in the list. Reverse it to shut down the services before their dependencies
+	for i, j := 0, len(hooks)-1; i < j; i, j = i+1, j-1 {
+		hooks[i], hooks[j] = hooks[j], hooks[i]
+	}
+
+	return &GracefulShutdown{
+		Timeout: config.GetDuration("server.shutdown_timeout"),
+		Hooks:   hooks,
+	}, nil
+}
+
+// newSentryRecoverer is the same as raven.Recoverer, but it also tags the event with the request id
+func newSentryRecoverer(client *raven.Client, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				debug.PrintStack()
+				recoveredStr := fmt.Sprint(recovered)
+				var stacktrace *raven.Stacktrace
+				if err, ok := recovered.(error); ok {
+					stacktrace = raven.GetOrNewStacktrace(err, 2, 3, nil)
+				} else {
+					stacktrace = raven.NewStacktrace(2, 3, nil)
+				}
+
+				packet := raven.NewPacket(
+					recoveredStr,
+					raven.NewException(errors.New(recoveredStr), stacktrace),
+					raven.NewHttp(req),
+				)
+
+				var tags map[string]string
+				if requestId := requestinfo.RequestId(req.Context()); requestId != "" {
+					tags = map[string]string{"request_id": requestId}
+				}
+
+				client.Capture(packet, tags)
+				resp.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+
+		handler.ServeHTTP(resp, req)
+	})
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/etherlabsio/healthcheck/v2"
 
 	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/mojangtextures"
 )
 
 type Pingable interface {
@@ -31,6 +32,16 @@ func DatabaseChecker(connection Pingable) healthcheck.CheckerFunc {
 	}
 }
 
+type StatusReporter interface {
+	Status() error
+}
+
+func StatusChecker(reporter StatusReporter) healthcheck.CheckerFunc {
+	return func(ctx context.Context) error {
+		return reporter.Status()
+	}
+}
+
 func MojangBatchUuidsProviderResponseChecker(dispatcher Subscriber, resetDuration time.Duration) healthcheck.CheckerFunc {
 	errHolder := &expiringErrHolder{D: resetDuration}
 	dispatcher.Subscribe(
@@ -48,7 +59,7 @@ func MojangBatchUuidsProviderResponseChecker(dispatcher Subscriber, resetDuratio
 func MojangBatchUuidsProviderQueueLengthChecker(dispatcher Subscriber, maxLength int) healthcheck.CheckerFunc {
 	var mutex sync.Mutex
 	queueLength := 0
-	dispatcher.Subscribe("mojang_textures:batch_uuids_provider:round", func(usernames []string, tasksInQueue int) {
+	dispatcher.Subscribe("mojang_textures:batch_uuids_provider:round", func(usernames []string, tasksInQueue int, _ map[mojangtextures.Priority]int) {
 		mutex.Lock()
 		queueLength = tasksInQueue
 		mutex.Unlock()
@@ -80,6 +91,22 @@ func MojangApiTexturesProviderResponseChecker(dispatcher Subscriber, resetDurati
 	}
 }
 
+func UpstreamResponseChecker(dispatcher Subscriber, name string, resetDuration time.Duration) healthcheck.CheckerFunc {
+	errHolder := &expiringErrHolder{D: resetDuration}
+	dispatcher.Subscribe(
+		"upstream:after_call",
+		func(_ context.Context, upstream string, username string, textures *mojang.SignedTexturesResponse, err error) {
+			if upstream == name {
+				errHolder.Set(err)
+			}
+		},
+	)
+
+	return func(ctx context.Context) error {
+		return errHolder.Get()
+	}
+}
+
 type expiringErrHolder struct {
 	D   time.Duration
 	err error
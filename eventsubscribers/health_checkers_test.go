@@ -11,6 +11,7 @@ import (
 
 	"github.com/elyby/chrly/api/mojang"
 	"github.com/elyby/chrly/dispatcher"
+	"github.com/elyby/chrly/mojangtextures"
 )
 
 type pingableMock struct {
@@ -22,6 +23,30 @@ func (p *pingableMock) Ping() error {
 	return args.Error(0)
 }
 
+type statusReporterMock struct {
+	mock.Mock
+}
+
+func (s *statusReporterMock) Status() error {
+	args := s.Called()
+	return args.Error(0)
+}
+
+func TestStatusChecker(t *testing.T) {
+	t.Run("no error", func(t *testing.T) {
+		s := &statusReporterMock{}
+		s.On("Status").Return(nil)
+		assert.Nil(t, StatusChecker(s)(context.Background()))
+	})
+
+	t.Run("with error", func(t *testing.T) {
+		err := errors.New("mock error")
+		s := &statusReporterMock{}
+		s.On("Status").Return(err)
+		assert.Equal(t, err, StatusChecker(s)(context.Background()))
+	})
+}
+
 func TestDatabaseChecker(t *testing.T) {
 	t.Run("no error", func(t *testing.T) {
 		p := &pingableMock{}
@@ -95,14 +120,14 @@ func TestMojangBatchUuidsProviderQueueLengthChecker(t *testing.T) {
 	t.Run("less than allowed limit", func(t *testing.T) {
 		d := dispatcher.New()
 		checker := MojangBatchUuidsProviderQueueLengthChecker(d, 10)
-		d.Emit("mojang_textures:batch_uuids_provider:round", []string{"username"}, 9)
+		d.Emit("mojang_textures:batch_uuids_provider:round", []string{"username"}, 9, map[mojangtextures.Priority]int{mojangtextures.PriorityHigh: 9})
 		assert.Nil(t, checker(context.Background()))
 	})
 
 	t.Run("greater than allowed limit", func(t *testing.T) {
 		d := dispatcher.New()
 		checker := MojangBatchUuidsProviderQueueLengthChecker(d, 10)
-		d.Emit("mojang_textures:batch_uuids_provider:round", []string{"username"}, 10)
+		d.Emit("mojang_textures:batch_uuids_provider:round", []string{"username"}, 10, map[mojangtextures.Priority]int{mojangtextures.PriorityHigh: 10})
 		checkResult := checker(context.Background())
 		if assert.Error(t, checkResult) {
 			assert.Equal(t, "the maximum number of tasks in the queue has been exceeded", checkResult.Error())
@@ -146,3 +171,29 @@ func TestMojangApiTexturesProviderResponseChecker(t *testing.T) {
 		assert.Nil(t, checker(context.Background()))
 	})
 }
+
+func TestUpstreamResponseChecker(t *testing.T) {
+	t.Run("empty state", func(t *testing.T) {
+		d := dispatcher.New()
+		checker := UpstreamResponseChecker(d, "ely", time.Millisecond)
+		assert.Nil(t, checker(context.Background()))
+	})
+
+	t.Run("when error occurred", func(t *testing.T) {
+		d := dispatcher.New()
+		checker := UpstreamResponseChecker(d, "ely", time.Second)
+		err := errors.New("some error occurred")
+		d.Emit("upstream:after_call", context.Background(), "ely", "mock", nil, err)
+		assert.Equal(t, err, checker(context.Background()))
+
+		d.Emit("upstream:after_call", context.Background(), "ely", "mock", &mojang.SignedTexturesResponse{}, nil)
+		assert.Nil(t, checker(context.Background()))
+	})
+
+	t.Run("should ignore other upstreams", func(t *testing.T) {
+		d := dispatcher.New()
+		checker := UpstreamResponseChecker(d, "ely", time.Second)
+		d.Emit("upstream:after_call", context.Background(), "another", "mock", nil, errors.New("some error occurred"))
+		assert.Nil(t, checker(context.Background()))
+	})
+}
@@ -1,64 +1,189 @@
 package eventsubscribers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
-	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/mono83/slf"
 	"github.com/mono83/slf/wd"
 
 	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/mojangtextures"
+	"github.com/elyby/chrly/requestinfo"
 )
 
+var timeNow = time.Now
+
 type Logger struct {
 	slf.Logger
+	// When set, the access log entries are written into it as JSON lines instead of the text logger
+	AccessLogWriter io.Writer
+	TrustedProxies  requestinfo.TrustedProxies
+
+	accessLogLock sync.Mutex
 }
 
 func (l *Logger) ConfigureWithDispatcher(d Subscriber) {
-	d.Subscribe("skinsystem:after_request", l.handleAfterSkinsystemRequest)
+	// All mounted modules (api, worker) are passing through the root router, so there is only one topic
+	d.Subscribe("skinsystem:after_request", l.handleAfterRequest)
 
 	d.Subscribe("mojang_textures:usernames:after_call", l.createMojangTexturesErrorHandler("usernames"))
 	d.Subscribe("mojang_textures:textures:after_call", l.createMojangTexturesErrorHandler("textures"))
+	d.Subscribe("skinsystem:mirror_error", l.handleMirrorError)
+	d.Subscribe("mojang_textures:circuit_breaker:state_changed", l.handleCircuitBreakerStateChange)
+	d.Subscribe("upstream:after_call", l.handleUpstreamError)
+	d.Subscribe("mojang_textures:shared_strategy:error", l.handleSharedStrategyError)
+}
+
+func (l *Logger) handleUpstreamError(
+	ctx context.Context,
+	name string,
+	username string,
+	textures *mojang.SignedTexturesResponse,
+	err error,
+) {
+	if err == nil {
+		return
+	}
+
+	params := []slf.Param{wd.NameParam(name), wd.StringParam("username", username), wd.ErrParam(err)}
+	if requestId := requestinfo.RequestId(ctx); requestId != "" {
+		params = append(params, wd.StringParam("requestId", requestId))
+	}
+
+	l.Warning(":name: Unable to get the textures of :username from the upstream: :err", params...)
+}
+
+func (l *Logger) handleCircuitBreakerStateChange(
+	name string,
+	from mojangtextures.CircuitBreakerState,
+	to mojangtextures.CircuitBreakerState,
+	err error,
+) {
+	if to == mojangtextures.CircuitBreakerOpen {
+		l.Warning(":name: Mojang circuit breaker is open: :err", wd.NameParam(name), wd.ErrParam(err))
+		return
+	}
+
+	l.Info(
+		":name: Mojang circuit breaker has changed its state from :from to :to",
+		wd.NameParam(name),
+		wd.StringParam("from", string(from)),
+		wd.StringParam("to", string(to)),
+	)
+}
+
+func (l *Logger) handleMirrorError(url string, err error) {
+	l.Warning("Unable to mirror the texture :url: :err", wd.StringParam("url", url), wd.ErrParam(err))
 }
 
-func (l *Logger) handleAfterSkinsystemRequest(req *http.Request, statusCode int) {
+func (l *Logger) handleSharedStrategyError(err error) {
+	l.Warning("Unable to use the shared Mojang usernames queue: :err", wd.ErrParam(err))
+}
+
+func (l *Logger) handleAfterRequest(req *http.Request, statusCode int, duration time.Duration, size int) {
+	if l.AccessLogWriter != nil {
+		l.writeJsonAccessLog(req, statusCode, duration, size)
+		return
+	}
+
 	path := req.URL.Path
 	if req.URL.RawQuery != "" {
 		path += "?" + req.URL.RawQuery
 	}
 
 	l.Info(
-		":ip - - \":method :path\" :statusCode - \":userAgent\" \":forwardedIp\"",
-		wd.StringParam("ip", trimPort(req.RemoteAddr)),
+		":ip - - \":method :path\" :statusCode :size \":userAgent\" \":forwardedIp\" :delta :requestId",
+		wd.StringParam("ip", l.TrustedProxies.ClientIp(req)),
 		wd.StringParam("method", req.Method),
 		wd.StringParam("path", path),
 		wd.IntParam("statusCode", statusCode),
+		wd.IntParam("size", size),
 		wd.StringParam("userAgent", req.UserAgent()),
 		wd.StringParam("forwardedIp", req.Header.Get("X-Forwarded-For")),
+		wd.DeltaParam(duration),
+		wd.StringParam("requestId", orDash(requestinfo.RequestId(req.Context()))),
 	)
 }
 
-func (l *Logger) createMojangTexturesErrorHandler(provider string) func(identity string, result interface{}, err error) {
+type accessLogEntry struct {
+	Time       string  `json:"time"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Query      string  `json:"query,omitempty"`
+	Route      string  `json:"route,omitempty"`
+	Status     int     `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Bytes      int     `json:"bytes"`
+	RemoteIp   string  `json:"remote_ip"`
+	UserAgent  string  `json:"user_agent"`
+	RequestId  string  `json:"request_id,omitempty"`
+}
+
+func (l *Logger) writeJsonAccessLog(req *http.Request, statusCode int, duration time.Duration, size int) {
+	line, _ := json.Marshal(&accessLogEntry{
+		Time:       timeNow().UTC().Format(time.RFC3339Nano),
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		Query:      req.URL.RawQuery,
+		Route:      requestinfo.FromContext(req.Context()).RouteTemplate(),
+		Status:     statusCode,
+		DurationMs: float64(duration) / float64(time.Millisecond),
+		Bytes:      size,
+		RemoteIp:   l.TrustedProxies.ClientIp(req),
+		UserAgent:  req.UserAgent(),
+		RequestId:  requestinfo.RequestId(req.Context()),
+	})
+
+	l.accessLogLock.Lock()
+	defer l.accessLogLock.Unlock()
+
+	_, _ = l.AccessLogWriter.Write(append(line, '\n'))
+}
+
+func (l *Logger) createMojangTexturesErrorHandler(provider string) func(ctx context.Context, identity string, result interface{}, err error) {
 	providerParam := wd.NameParam(provider)
-	return func(identity string, result interface{}, err error) {
+	return func(ctx context.Context, identity string, result interface{}, err error) {
 		if err == nil {
 			return
 		}
 
-		errParam := wd.ErrParam(err)
+		params := []slf.Param{providerParam, wd.ErrParam(err)}
+		if requestId := requestinfo.RequestId(ctx); requestId != "" {
+			params = append(params, wd.StringParam("requestId", requestId))
+		}
+
+		// The opening of the circuit breaker is logged once, so the rejected requests don't flood the log.
+		// The canceled lookup means that the client has gone, which says nothing about the Mojang's state
+		if errors.Is(err, mojangtextures.ErrCircuitBreakerOpen) || errors.Is(err, context.Canceled) {
+			return
+		}
+
+		if errors.Is(err, mojangtextures.ErrNoAvailableRemoteApiWorkers) ||
+			errors.Is(err, context.DeadlineExceeded) ||
+			errors.Is(err, mojangtextures.ErrRateLimitExceeded) ||
+			errors.Is(err, mojang.ErrNoAvailableProxies) {
+			l.logMojangTexturesWarning(params...)
+			return
+		}
 
 		switch err.(type) {
 		case *mojang.BadRequestError:
-			l.logMojangTexturesWarning(providerParam, errParam)
+			l.logMojangTexturesWarning(params...)
 			return
 		case *mojang.ForbiddenError:
-			l.logMojangTexturesWarning(providerParam, errParam)
+			l.logMojangTexturesWarning(params...)
 			return
 		case *mojang.TooManyRequestsError:
-			l.logMojangTexturesWarning(providerParam, errParam)
+			l.logMojangTexturesWarning(params...)
 			return
 		case net.Error:
 			if err.(net.Error).Timeout() {
@@ -78,17 +203,18 @@ func (l *Logger) createMojangTexturesErrorHandler(provider string) func(identity
 			}
 		}
 
-		l.Error(":name: Unexpected Mojang response error: :err", providerParam, errParam)
+		l.Error(":name: Unexpected Mojang response error: :err", params...)
 	}
 }
 
-func (l *Logger) logMojangTexturesWarning(providerParam slf.Param, errParam slf.Param) {
-	l.Warning(":name: :err", providerParam, errParam)
+func (l *Logger) logMojangTexturesWarning(params ...slf.Param) {
+	l.Warning(":name: :err", params...)
 }
 
-func trimPort(ip string) string {
-	// Don't care about possible -1 result because RemoteAddr will always contain ip and port
-	cutTo := strings.LastIndexByte(ip, ':')
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
 
-	return ip[0:cutTo]
+	return value
 }
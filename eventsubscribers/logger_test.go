@@ -1,19 +1,27 @@
 package eventsubscribers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/mono83/slf"
 	"github.com/mono83/slf/params"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	"github.com/elyby/chrly/api/mojang"
 	"github.com/elyby/chrly/dispatcher"
+	"github.com/elyby/chrly/mojangtextures"
+	"github.com/elyby/chrly/requestinfo"
 )
 
 type LoggerMock struct {
@@ -73,11 +81,13 @@ var loggerTestCases = map[string]*LoggerTestCase{
 					return req
 				})(),
 				201,
+				150 * time.Millisecond,
+				1024,
 			},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"Info",
-				":ip - - \":method :path\" :statusCode - \":userAgent\" \":forwardedIp\"",
+				":ip - - \":method :path\" :statusCode :size \":userAgent\" \":forwardedIp\" :delta :requestId",
 				mock.MatchedBy(func(strParam params.String) bool {
 					return strParam.Key == "ip" && strParam.Value == "192.0.2.1"
 				}),
@@ -90,12 +100,21 @@ var loggerTestCases = map[string]*LoggerTestCase{
 				mock.MatchedBy(func(strParam params.Int) bool {
 					return strParam.Key == "statusCode" && strParam.Value == 201
 				}),
+				mock.MatchedBy(func(strParam params.Int) bool {
+					return strParam.Key == "size" && strParam.Value == 1024
+				}),
 				mock.MatchedBy(func(strParam params.String) bool {
 					return strParam.Key == "userAgent" && strParam.Value == "Test user agent"
 				}),
 				mock.MatchedBy(func(strParam params.String) bool {
 					return strParam.Key == "forwardedIp" && strParam.Value == ""
 				}),
+				mock.MatchedBy(func(durationParam params.Duration) bool {
+					return durationParam.Key == "delta" && durationParam.Value == 150*time.Millisecond
+				}),
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "requestId" && strParam.Value == "-"
+				}),
 			},
 		},
 	},
@@ -107,29 +126,139 @@ var loggerTestCases = map[string]*LoggerTestCase{
 					req.Header.Add("User-Agent", "Test user agent")
 					req.Header.Add("X-Forwarded-For", "1.2.3.4")
 
-					return req
+					return req.WithContext(requestinfo.WithRequestId(req.Context(), "mock-request-id"))
 				})(),
 				201,
+				time.Millisecond,
+				0,
 			},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"Info",
-				":ip - - \":method :path\" :statusCode - \":userAgent\" \":forwardedIp\"",
-				mock.Anything, // Already tested
+				":ip - - \":method :path\" :statusCode :size \":userAgent\" \":forwardedIp\" :delta :requestId",
+				mock.MatchedBy(func(strParam params.String) bool {
+					// There are no trusted proxies, so the forwarded address must be ignored
+					return strParam.Key == "ip" && strParam.Value == "192.0.2.1"
+				}),
 				mock.Anything, // Already tested
 				mock.MatchedBy(func(strParam params.String) bool {
 					return strParam.Key == "path" && strParam.Value == "/skins/username.png?authlib=1.5.2"
 				}),
 				mock.Anything, // Already tested
 				mock.Anything, // Already tested
+				mock.Anything, // Already tested
 				mock.MatchedBy(func(strParam params.String) bool {
 					return strParam.Key == "forwardedIp" && strParam.Value == "1.2.3.4"
 				}),
+				mock.Anything, // Already tested
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "requestId" && strParam.Value == "mock-request-id"
+				}),
 			},
 		},
 	},
 }
 
+func init() {
+	loggerTestCases["should log the texture mirroring error"] = &LoggerTestCase{
+		Events: [][]interface{}{
+			{"skinsystem:mirror_error", "http://textures.minecraft.net/texture/mock", errors.New("mock error")},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"Warning",
+				"Unable to mirror the texture :url: :err",
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "url" && strParam.Value == "http://textures.minecraft.net/texture/mock"
+				}),
+				mock.MatchedBy(func(errParam params.Error) bool {
+					return errParam.Key == "err" && errParam.Value.Error() == "mock error"
+				}),
+			},
+		},
+	}
+}
+
+func init() {
+	loggerTestCases["should log the shared queue error"] = &LoggerTestCase{
+		Events: [][]interface{}{
+			{"mojang_textures:shared_strategy:error", errors.New("mock error")},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"Warning",
+				"Unable to use the shared Mojang usernames queue: :err",
+				mock.MatchedBy(func(errParam params.Error) bool {
+					return errParam.Key == "err" && errParam.Value.Error() == "mock error"
+				}),
+			},
+		},
+	}
+}
+
+func init() {
+	loggerTestCases["should log the opening of the circuit breaker"] = &LoggerTestCase{
+		Events: [][]interface{}{
+			{"mojang_textures:circuit_breaker:state_changed", "textures", mojangtextures.CircuitBreakerClosed, mojangtextures.CircuitBreakerOpen, errors.New("mock error")},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"Warning",
+				":name: Mojang circuit breaker is open: :err",
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "name" && strParam.Value == "textures"
+				}),
+				mock.MatchedBy(func(errParam params.Error) bool {
+					return errParam.Key == "err" && errParam.Value.Error() == "mock error"
+				}),
+			},
+		},
+	}
+
+	loggerTestCases["should log other circuit breaker state changes"] = &LoggerTestCase{
+		Events: [][]interface{}{
+			{"mojang_textures:circuit_breaker:state_changed", "usernames", mojangtextures.CircuitBreakerHalfOpen, mojangtextures.CircuitBreakerClosed, nil},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"Info",
+				":name: Mojang circuit breaker has changed its state from :from to :to",
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "name" && strParam.Value == "usernames"
+				}),
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "from" && strParam.Value == "half-open"
+				}),
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "to" && strParam.Value == "closed"
+				}),
+			},
+		},
+	}
+}
+
+func init() {
+	loggerTestCases["should log the upstream errors"] = &LoggerTestCase{
+		Events: [][]interface{}{
+			{"upstream:after_call", context.Background(), "ely", "mock", &mojang.SignedTexturesResponse{}, nil},
+			{"upstream:after_call", requestinfo.WithRequestId(context.Background(), "mock-request-id"), "ely", "mock", nil, errors.New("mock error")},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"Warning",
+				":name: Unable to get the textures of :username from the upstream: :err",
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "name" && strParam.Value == "ely"
+				}),
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "username" && strParam.Value == "mock"
+				}),
+				mock.MatchedBy(func(errParam params.Error) bool {
+					return errParam.Key == "err" && errParam.Value.Error() == "mock error"
+				}),
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "requestId" && strParam.Value == "mock-request-id"
+				}),
+			},
+		},
+	}
+}
+
 type timeoutError struct{}
 
 func (*timeoutError) Error() string   { return "timeout error" }
@@ -142,30 +271,49 @@ func init() {
 		pn := providerName // Store pointer to iteration value
 		loggerTestCases["should not log when no error occurred for "+pn+" provider"] = &LoggerTestCase{
 			Events: [][]interface{}{
-				{"mojang_textures:" + pn + ":after_call", pn, &mojang.ProfileInfo{}, nil},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, &mojang.ProfileInfo{}, nil},
 			},
 			ExpectedCalls: nil,
 		}
 
 		loggerTestCases["should not log when some network errors occured for "+pn+" provider"] = &LoggerTestCase{
 			Events: [][]interface{}{
-				{"mojang_textures:" + pn + ":after_call", pn, nil, &timeoutError{}},
-				{"mojang_textures:" + pn + ":after_call", pn, nil, &url.Error{Op: "GET", URL: "http://localhost"}},
-				{"mojang_textures:" + pn + ":after_call", pn, nil, &net.OpError{Op: "read"}},
-				{"mojang_textures:" + pn + ":after_call", pn, nil, &net.OpError{Op: "dial"}},
-				{"mojang_textures:" + pn + ":after_call", pn, nil, syscall.ECONNREFUSED},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, &timeoutError{}},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, &url.Error{Op: "GET", URL: "http://localhost"}},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, &net.OpError{Op: "read"}},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, &net.OpError{Op: "dial"}},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, syscall.ECONNREFUSED},
+			},
+			ExpectedCalls: nil,
+		}
+
+		loggerTestCases["should not log rejected by the circuit breaker requests for "+pn+" provider"] = &LoggerTestCase{
+			Events: [][]interface{}{
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, mojangtextures.ErrCircuitBreakerOpen},
+			},
+			ExpectedCalls: nil,
+		}
+
+		loggerTestCases["should not log the lookups abandoned by the client for "+pn+" provider"] = &LoggerTestCase{
+			Events: [][]interface{}{
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, context.Canceled},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, fmt.Errorf("wrapped: %w", context.Canceled)},
 			},
 			ExpectedCalls: nil,
 		}
 
 		loggerTestCases["should log expected mojang errors for "+pn+" provider"] = &LoggerTestCase{
 			Events: [][]interface{}{
-				{"mojang_textures:" + pn + ":after_call", pn, nil, &mojang.BadRequestError{
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, &mojang.BadRequestError{
 					ErrorType: "IllegalArgumentException",
 					Message:   "profileName can not be null or empty.",
 				}},
-				{"mojang_textures:" + pn + ":after_call", pn, nil, &mojang.ForbiddenError{}},
-				{"mojang_textures:" + pn + ":after_call", pn, nil, &mojang.TooManyRequestsError{}},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, &mojang.ForbiddenError{}},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, &mojang.TooManyRequestsError{}},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, mojangtextures.ErrNoAvailableRemoteApiWorkers},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, mojangtextures.ErrRateLimitExceeded},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, context.DeadlineExceeded},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, &url.Error{Op: "GET", URL: "http://localhost", Err: mojang.ErrNoAvailableProxies}},
 			},
 			ExpectedCalls: [][]interface{}{
 				{"Warning",
@@ -190,15 +338,52 @@ func init() {
 							return true
 						}
 
+						if errParam.Value == mojangtextures.ErrNoAvailableRemoteApiWorkers {
+							return true
+						}
+
+						if errParam.Value == mojangtextures.ErrRateLimitExceeded {
+							return true
+						}
+
+						if errParam.Value == context.DeadlineExceeded {
+							return true
+						}
+
+						if errors.Is(errParam.Value, mojang.ErrNoAvailableProxies) {
+							return true
+						}
+
 						return false
 					}),
 				},
 			},
 		}
 
+		loggerTestCases["should pass request id with mojang errors for "+pn+" provider"] = &LoggerTestCase{
+			Events: [][]interface{}{
+				{"mojang_textures:" + pn + ":after_call",
+					requestinfo.WithRequestId(context.Background(), "mock-request-id"),
+					pn,
+					nil,
+					&mojang.TooManyRequestsError{},
+				},
+			},
+			ExpectedCalls: [][]interface{}{
+				{"Warning",
+					":name: :err",
+					mock.Anything, // Already tested
+					mock.Anything, // Already tested
+					mock.MatchedBy(func(strParam params.String) bool {
+						return strParam.Key == "requestId" && strParam.Value == "mock-request-id"
+					}),
+				},
+			},
+		}
+
 		loggerTestCases["should call error when unexpected error occurred for "+pn+" provider"] = &LoggerTestCase{
 			Events: [][]interface{}{
-				{"mojang_textures:" + pn + ":after_call", pn, nil, &mojang.ServerError{Status: 500}},
+				{"mojang_textures:" + pn + ":after_call", context.Background(), pn, nil, &mojang.ServerError{Status: 500}},
 			},
 			ExpectedCalls: [][]interface{}{
 				{"Error",
@@ -254,3 +439,43 @@ func TestLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestLoggerJsonAccessLog(t *testing.T) {
+	timeNow = func() time.Time {
+		return time.Date(2021, 2, 25, 1, 50, 23, 0, time.UTC)
+	}
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	trustedProxies, _ := requestinfo.ParseTrustedProxies([]string{"192.0.2.0/24"})
+	writer := &strings.Builder{}
+	logger := &Logger{
+		Logger:          &LoggerMock{},
+		AccessLogWriter: writer,
+		TrustedProxies:  trustedProxies,
+	}
+
+	d := dispatcher.New()
+	logger.ConfigureWithDispatcher(d)
+
+	req := httptest.NewRequest("GET", "http://localhost/skins/username.png?authlib=1.5.2", nil)
+	req.Header.Add("User-Agent", "Test user agent")
+	req.Header.Add("X-Forwarded-For", "1.2.3.4, 192.0.2.10")
+	req = req.WithContext(requestinfo.WithRequestId(req.Context(), "mock-request-id"))
+	d.Emit("skinsystem:after_request", req, 301, 1500*time.Microsecond, 128)
+
+	assert.JSONEq(t, `{
+		"time": "2021-02-25T01:50:23Z",
+		"method": "GET",
+		"path": "/skins/username.png",
+		"query": "authlib=1.5.2",
+		"status": 301,
+		"duration_ms": 1.5,
+		"bytes": 128,
+		"remote_ip": "1.2.3.4",
+		"user_agent": "Test user agent",
+		"request_id": "mock-request-id"
+	}`, writer.String())
+	assert.True(t, strings.HasSuffix(writer.String(), "\n"))
+}
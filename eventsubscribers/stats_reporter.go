@@ -1,6 +1,8 @@
 package eventsubscribers
 
 import (
+	"context"
+	"math"
 	"net/http"
 	"strings"
 	"sync"
@@ -9,6 +11,7 @@ import (
 	"github.com/mono83/slf"
 
 	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/mojangtextures"
 )
 
 type StatsReporter struct {
@@ -66,7 +69,9 @@ func (s *StatsReporter) ConfigureWithDispatcher(d Subscriber) {
 		}
 	})
 	d.Subscribe("mojang_textures:already_processing", s.incCounterHandler("mojang_textures.already_scheduled"))
-	d.Subscribe("mojang_textures:usernames:after_call", func(username string, profile *mojang.ProfileInfo, err error) {
+	d.Subscribe("mojang_textures:textures:stale_refresh", s.incCounterHandler("mojang_textures.textures.stale_hit"))
+	d.Subscribe("mojang_textures:textures:stale_fallback", s.incCounterHandler("mojang_textures.textures.stale_fallback"))
+	d.Subscribe("mojang_textures:usernames:after_call", func(_ context.Context, username string, profile *mojang.ProfileInfo, err error) {
 		if err != nil {
 			return
 		}
@@ -78,7 +83,7 @@ func (s *StatsReporter) ConfigureWithDispatcher(d Subscriber) {
 		}
 	})
 	d.Subscribe("mojang_textures:textures:before_call", s.incCounterHandler("mojang_textures.textures.request"))
-	d.Subscribe("mojang_textures:textures:after_call", func(uuid string, textures *mojang.SignedTexturesResponse, err error) {
+	d.Subscribe("mojang_textures:textures:after_call", func(_ context.Context, uuid string, textures *mojang.SignedTexturesResponse, err error) {
 		if err != nil {
 			return
 		}
@@ -95,18 +100,61 @@ func (s *StatsReporter) ConfigureWithDispatcher(d Subscriber) {
 	d.Subscribe("mojang_textures:after_result", func(username string, textures *mojang.SignedTexturesResponse, err error) {
 		s.finalizeTimeRecording("mojang_textures_result_time_"+username, "mojang_textures.result_time")
 	})
-	d.Subscribe("mojang_textures:textures:before_call", func(uuid string) {
+	d.Subscribe("mojang_textures:textures:before_call", func(_ context.Context, uuid string) {
 		s.startTimeRecording("mojang_textures_provider_time_" + uuid)
 	})
-	d.Subscribe("mojang_textures:textures:after_call", func(uuid string, textures *mojang.SignedTexturesResponse, err error) {
+	d.Subscribe("mojang_textures:textures:after_call", func(_ context.Context, uuid string, textures *mojang.SignedTexturesResponse, err error) {
 		s.finalizeTimeRecording("mojang_textures_provider_time_"+uuid, "mojang_textures.textures.request_time")
 	})
 
+	// Mojang textures provider rate limit metrics
+	d.Subscribe("mojang_textures:mojang_api_textures_provider:before_wait", func(uuid string) {
+		s.startTimeRecording("mojang_api_textures_provider_wait_time_" + uuid)
+	})
+	d.Subscribe("mojang_textures:mojang_api_textures_provider:after_wait", func(uuid string, err error) {
+		s.finalizeTimeRecording("mojang_api_textures_provider_wait_time_"+uuid, "mojang_textures.textures.rate_limit_wait_time")
+		if err != nil {
+			s.IncCounter("mojang_textures.textures.rate_limited", 1)
+		}
+	})
+
+	// Mojang textures prefetch metrics
+	d.Subscribe("mojang_textures:prefetch:refresh", s.incCounterHandler("mojang_textures.prefetch.refresh"))
+	d.Subscribe("mojang_textures:prefetch:hit", s.incCounterHandler("mojang_textures.prefetch.hit"))
+
+	// Mojang circuit breakers metrics
+	d.Subscribe("mojang_textures:circuit_breaker:state_changed", func(
+		name string,
+		from mojangtextures.CircuitBreakerState,
+		to mojangtextures.CircuitBreakerState,
+		err error,
+	) {
+		s.IncCounter("mojang_textures.circuit_breaker."+name+"."+strings.ReplaceAll(string(to), "-", "_"), 1)
+	})
+
+	// Upstreams metrics
+	d.Subscribe("upstream:cache_hit", func(name string, username string) {
+		s.IncCounter("upstreams."+name+".cache_hit", 1)
+	})
+	d.Subscribe("upstream:after_call", func(_ context.Context, name string, username string, textures *mojang.SignedTexturesResponse, err error) {
+		s.IncCounter("upstreams."+name+".request", 1)
+		if err != nil {
+			s.IncCounter("upstreams."+name+".error", 1)
+		} else if textures != nil {
+			s.IncCounter("upstreams."+name+".hit", 1)
+		} else {
+			s.IncCounter("upstreams."+name+".miss", 1)
+		}
+	})
+
 	// Mojang UUIDs batch provider metrics
 	d.Subscribe("mojang_textures:batch_uuids_provider:queued", s.incCounterHandler("mojang_textures.usernames.queued"))
-	d.Subscribe("mojang_textures:batch_uuids_provider:round", func(usernames []string, queueSize int) {
+	d.Subscribe("mojang_textures:batch_uuids_provider:round", func(usernames []string, queueSize int, lanes map[mojangtextures.Priority]int) {
 		s.UpdateGauge("mojang_textures.usernames.iteration_size", int64(len(usernames)))
 		s.UpdateGauge("mojang_textures.usernames.queue_size", int64(queueSize))
+		for priority, laneSize := range lanes {
+			s.UpdateGauge("mojang_textures.usernames.queue_size."+string(priority), int64(laneSize))
+		}
 		if len(usernames) != 0 {
 			s.startTimeRecording("batch_uuids_provider_round_time_" + strings.Join(usernames, "|"))
 		}
@@ -114,6 +162,11 @@ func (s *StatsReporter) ConfigureWithDispatcher(d Subscriber) {
 	d.Subscribe("mojang_textures:batch_uuids_provider:result", func(usernames []string, profiles []*mojang.ProfileInfo, err error) {
 		s.finalizeTimeRecording("batch_uuids_provider_round_time_"+strings.Join(usernames, "|"), "mojang_textures.usernames.round_time")
 	})
+	d.Subscribe("mojang_textures:batch_uuids_provider:retry", s.incCounterHandler("mojang_textures.usernames.retry"))
+	d.Subscribe("mojang_textures:batch_uuids_provider:rate", func(rate float64) {
+		// Gauges can't hold fractional values, so the rate is reported per minute
+		s.UpdateGauge("mojang_textures.usernames.rate_per_minute", int64(math.Round(rate*60)))
+	})
 }
 
 func (s *StatsReporter) handleBeforeRequest(req *http.Request) {
@@ -145,7 +198,7 @@ func (s *StatsReporter) handleBeforeRequest(req *http.Request) {
 	s.IncCounter(key, 1)
 }
 
-func (s *StatsReporter) handleAfterRequest(req *http.Request, code int) {
+func (s *StatsReporter) handleAfterRequest(req *http.Request, code int, _ time.Duration, _ int) {
 	var key string
 	m := req.Method
 	p := req.URL.Path
@@ -1,6 +1,7 @@
 package eventsubscribers
 
 import (
+	"context"
 	"errors"
 	"net/http/httptest"
 	"testing"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/elyby/chrly/api/mojang"
 	"github.com/elyby/chrly/dispatcher"
+	"github.com/elyby/chrly/mojangtextures"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -139,7 +141,7 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	// After request
 	{
 		Events: [][]interface{}{
-			{"skinsystem:after_request", httptest.NewRequest("POST", "http://localhost/api/skins", nil), 201},
+			{"skinsystem:after_request", httptest.NewRequest("POST", "http://localhost/api/skins", nil), 201, time.Millisecond, 0},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "api.skins.post.success", int64(1)},
@@ -147,7 +149,7 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"skinsystem:after_request", httptest.NewRequest("POST", "http://localhost/api/skins", nil), 400},
+			{"skinsystem:after_request", httptest.NewRequest("POST", "http://localhost/api/skins", nil), 400, time.Millisecond, 0},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "api.skins.post.validation_failed", int64(1)},
@@ -155,7 +157,7 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/api/skins/username", nil), 204},
+			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/api/skins/username", nil), 204, time.Millisecond, 0},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "api.skins.delete.success", int64(1)},
@@ -163,7 +165,7 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/api/skins/username", nil), 404},
+			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/api/skins/username", nil), 404, time.Millisecond, 0},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "api.skins.delete.not_found", int64(1)},
@@ -171,7 +173,7 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/api/skins/id:1", nil), 204},
+			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/api/skins/id:1", nil), 204, time.Millisecond, 0},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "api.skins.delete.success", int64(1)},
@@ -179,7 +181,7 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/api/skins/id:1", nil), 404},
+			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/api/skins/id:1", nil), 404, time.Millisecond, 0},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "api.skins.delete.not_found", int64(1)},
@@ -187,7 +189,7 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/unknown", nil), 404},
+			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/unknown", nil), 404, time.Millisecond, 0},
 		},
 		ExpectedCalls: nil,
 	},
@@ -277,13 +279,29 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:usernames:after_call", "username", nil, errors.New("error")},
+			{"mojang_textures:textures:stale_refresh", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.textures.stale_hit", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:textures:stale_fallback", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", errors.New("error")},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.textures.stale_fallback", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:usernames:after_call", context.Background(), "username", nil, errors.New("error")},
 		},
 		ExpectedCalls: [][]interface{}{},
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:usernames:after_call", "username", nil, nil},
+			{"mojang_textures:usernames:after_call", context.Background(), "username", nil, nil},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "mojang_textures.usernames.uuid_miss", int64(1)},
@@ -291,7 +309,7 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:usernames:after_call", "username", &mojang.ProfileInfo{}, nil},
+			{"mojang_textures:usernames:after_call", context.Background(), "username", &mojang.ProfileInfo{}, nil},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "mojang_textures.usernames.uuid_hit", int64(1)},
@@ -299,13 +317,13 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil, errors.New("error")},
+			{"mojang_textures:textures:after_call", context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil, errors.New("error")},
 		},
 		ExpectedCalls: [][]interface{}{},
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil, nil},
+			{"mojang_textures:textures:after_call", context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil, nil},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "mojang_textures.usernames.textures_miss", int64(1)},
@@ -313,7 +331,7 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", &mojang.SignedTexturesResponse{}, nil},
+			{"mojang_textures:textures:after_call", context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", &mojang.SignedTexturesResponse{}, nil},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "mojang_textures.usernames.textures_hit", int64(1)},
@@ -330,8 +348,8 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:textures:before_call", "аааааааааааааааааааааааааааааааа"},
-			{"mojang_textures:textures:after_call", "аааааааааааааааааааааааааааааааа", &mojang.SignedTexturesResponse{}, nil},
+			{"mojang_textures:textures:before_call", context.Background(), "аааааааааааааааааааааааааааааааа"},
+			{"mojang_textures:textures:after_call", context.Background(), "аааааааааааааааааааааааааааааааа", &mojang.SignedTexturesResponse{}, nil},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "mojang_textures.textures.request", int64(1)},
@@ -350,18 +368,28 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:batch_uuids_provider:round", []string{"username1", "username2"}, 5},
+			{"mojang_textures:batch_uuids_provider:retry", "username", 1, &mojang.ServerError{Status: 502}},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.usernames.retry", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:batch_uuids_provider:round", []string{"username1", "username2"}, 5, map[mojangtextures.Priority]int{mojangtextures.PriorityHigh: 1, mojangtextures.PriorityLow: 4}},
 			{"mojang_textures:batch_uuids_provider:result", []string{"username1", "username2"}, []*mojang.ProfileInfo{}, nil},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"UpdateGauge", "mojang_textures.usernames.iteration_size", int64(2)},
 			{"UpdateGauge", "mojang_textures.usernames.queue_size", int64(5)},
+			{"UpdateGauge", "mojang_textures.usernames.queue_size.high", int64(1)},
+			{"UpdateGauge", "mojang_textures.usernames.queue_size.low", int64(4)},
 			{"RecordTimer", "mojang_textures.usernames.round_time", mock.AnythingOfType("time.Duration")},
 		},
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:batch_uuids_provider:round", []string{}, 0},
+			{"mojang_textures:batch_uuids_provider:round", []string{}, 0, map[mojangtextures.Priority]int{}},
 			// This event will be not emitted, but we emit it to ensure, that RecordTimer will not be called
 			{"mojang_textures:batch_uuids_provider:result", []string{}, []*mojang.ProfileInfo{}, nil},
 		},
@@ -371,6 +399,94 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 			// Should not call RecordTimer
 		},
 	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:mojang_api_textures_provider:before_wait", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+			{"mojang_textures:mojang_api_textures_provider:after_wait", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"RecordTimer", "mojang_textures.textures.rate_limit_wait_time", mock.AnythingOfType("time.Duration")},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:mojang_api_textures_provider:before_wait", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+			{"mojang_textures:mojang_api_textures_provider:after_wait", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", mojangtextures.ErrRateLimitExceeded},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"RecordTimer", "mojang_textures.textures.rate_limit_wait_time", mock.AnythingOfType("time.Duration")},
+			{"IncCounter", "mojang_textures.textures.rate_limited", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:prefetch:refresh", "username"},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.prefetch.refresh", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:prefetch:hit", "username"},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.prefetch.hit", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:circuit_breaker:state_changed", "textures", mojangtextures.CircuitBreakerClosed, mojangtextures.CircuitBreakerOpen, errors.New("mock error")},
+			{"mojang_textures:circuit_breaker:state_changed", "textures", mojangtextures.CircuitBreakerOpen, mojangtextures.CircuitBreakerHalfOpen, errors.New("mock error")},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.circuit_breaker.textures.open", int64(1)},
+			{"IncCounter", "mojang_textures.circuit_breaker.textures.half_open", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"upstream:cache_hit", "ely", "mock"},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "upstreams.ely.cache_hit", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"upstream:after_call", context.Background(), "ely", "mock", &mojang.SignedTexturesResponse{}, nil},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "upstreams.ely.request", int64(1)},
+			{"IncCounter", "upstreams.ely.hit", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"upstream:after_call", context.Background(), "ely", "mock", nil, nil},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "upstreams.ely.request", int64(1)},
+			{"IncCounter", "upstreams.ely.miss", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"upstream:after_call", context.Background(), "ely", "mock", nil, errors.New("mock error")},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "upstreams.ely.request", int64(1)},
+			{"IncCounter", "upstreams.ely.error", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:batch_uuids_provider:rate", 0.125},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"UpdateGauge", "mojang_textures.usernames.rate_per_minute", int64(8)},
+		},
+	},
 }
 
 func TestStatsReporter(t *testing.T) {
@@ -34,8 +34,13 @@ func init() {
 	})
 }
 
+type MojangTexturesPurger interface {
+	Purge(username string) error
+}
+
 type Api struct {
-	SkinsRepo SkinsRepository
+	SkinsRepo            SkinsRepository
+	MojangTexturesPurger MojangTexturesPurger
 }
 
 func (ctx *Api) Handler() *mux.Router {
@@ -43,6 +48,9 @@ func (ctx *Api) Handler() *mux.Router {
 	router.HandleFunc("/skins", ctx.postSkinHandler).Methods(http.MethodPost)
 	router.HandleFunc("/skins/id:{id:[0-9]+}", ctx.deleteSkinByUserIdHandler).Methods(http.MethodDelete)
 	router.HandleFunc("/skins/{username}", ctx.deleteSkinByUsernameHandler).Methods(http.MethodDelete)
+	if ctx.MojangTexturesPurger != nil {
+		router.HandleFunc("/mojang-textures/{username}", ctx.purgeMojangTexturesHandler).Methods(http.MethodDelete)
+	}
 
 	return router
 }
@@ -80,6 +88,7 @@ func (ctx *Api) postSkinHandler(resp http.ResponseWriter, req *http.Request) {
 	record.Url = req.Form.Get("url")
 	record.MojangTextures = req.Form.Get("mojangTextures")
 	record.MojangSignature = req.Form.Get("mojangSignature")
+	record.UpdatedAt = timeNow().Unix()
 
 	err = ctx.SkinsRepo.SaveSkin(record)
 	if err != nil {
@@ -119,6 +128,15 @@ func (ctx *Api) deleteSkin(skin *model.Skin, err error, resp http.ResponseWriter
 	resp.WriteHeader(http.StatusNoContent)
 }
 
+func (ctx *Api) purgeMojangTexturesHandler(resp http.ResponseWriter, req *http.Request) {
+	err := ctx.MojangTexturesPurger.Purge(mux.Vars(req)["username"])
+	if err != nil {
+		panic(err)
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
 func (ctx *Api) findIdentityOrCleanup(identityId int, username string) (*model.Skin, error) {
 	record, err := ctx.SkinsRepo.FindSkinByUserId(identityId)
 	if err != nil {
@@ -21,12 +21,22 @@ import (
  * Setup mocks *
  ***************/
 
+type mojangTexturesPurgerMock struct {
+	mock.Mock
+}
+
+func (m *mojangTexturesPurgerMock) Purge(username string) error {
+	args := m.Called(username)
+	return args.Error(0)
+}
+
 type apiTestSuite struct {
 	suite.Suite
 
 	App *Api
 
-	SkinsRepository *skinsRepositoryMock
+	SkinsRepository      *skinsRepositoryMock
+	MojangTexturesPurger *mojangTexturesPurgerMock
 }
 
 /********************
@@ -35,14 +45,17 @@ type apiTestSuite struct {
 
 func (suite *apiTestSuite) SetupTest() {
 	suite.SkinsRepository = &skinsRepositoryMock{}
+	suite.MojangTexturesPurger = &mojangTexturesPurgerMock{}
 
 	suite.App = &Api{
-		SkinsRepo: suite.SkinsRepository,
+		SkinsRepo:            suite.SkinsRepository,
+		MojangTexturesPurger: suite.MojangTexturesPurger,
 	}
 }
 
 func (suite *apiTestSuite) TearDownTest() {
 	suite.SkinsRepository.AssertExpectations(suite.T())
+	suite.MojangTexturesPurger.AssertExpectations(suite.T())
 }
 
 func (suite *apiTestSuite) RunSubTest(name string, subTest func()) {
@@ -94,6 +107,7 @@ var postSkinTestsCases = []*postSkinTestCase{
 				suite.False(model.Is1_8)
 				suite.False(model.IsSlim)
 				suite.Equal("http://example.com/skin.png", model.Url)
+				suite.NotZero(model.UpdatedAt)
 
 				return true
 			})).Times(1).Return(nil)
@@ -400,6 +414,38 @@ func (suite *apiTestSuite) TestDeleteByUsername() {
 	})
 }
 
+/*************************************
+ * Purge Mojang textures tests cases *
+ *************************************/
+
+func (suite *apiTestSuite) TestPurgeMojangTextures() {
+	suite.RunSubTest("Purge Mojang textures by username", func() {
+		suite.MojangTexturesPurger.On("Purge", "mock_username").Once().Return(nil)
+
+		req := httptest.NewRequest("DELETE", "http://chrly/mojang-textures/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(204, resp.StatusCode)
+		body, _ := ioutil.ReadAll(resp.Body)
+		suite.Empty(body)
+	})
+
+	suite.RunSubTest("Handle an error from the purger", func() {
+		suite.MojangTexturesPurger.On("Purge", "mock_username").Once().Return(errors.New("mock error"))
+
+		req := httptest.NewRequest("DELETE", "http://chrly/mojang-textures/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.PanicsWithError("mock error", func() {
+			suite.App.Handler().ServeHTTP(w, req)
+		})
+	})
+}
+
 /*************
  * Utilities *
  *************/
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CachePolicy describes the Cache-Control header of the response
+type CachePolicy struct {
+	MaxAge time.Duration
+	// SharedMaxAge overrides the MaxAge for the shared caches, like CDNs and proxies. It's not sent when zero
+	SharedMaxAge time.Duration
+	// StaleWhileRevalidate allows to serve the stale response while it's revalidated in the background.
+	// It's not sent when zero
+	StaleWhileRevalidate time.Duration
+}
+
+func (p *CachePolicy) String() string {
+	directives := []string{"max-age=" + formatSeconds(p.MaxAge)}
+	if p.SharedMaxAge > 0 {
+		directives = append(directives, "s-maxage="+formatSeconds(p.SharedMaxAge))
+	}
+
+	if p.StaleWhileRevalidate > 0 {
+		directives = append(directives, "stale-while-revalidate="+formatSeconds(p.StaleWhileRevalidate))
+	}
+
+	return strings.Join(directives, ", ")
+}
+
+// RouteCachePolicy holds the policies for the textures found in the local storage
+// and for the textures received from Mojang. Nil policy means that the Cache-Control header isn't sent
+type RouteCachePolicy struct {
+	Local  *CachePolicy
+	Mojang *CachePolicy
+}
+
+func (p *RouteCachePolicy) write(response http.ResponseWriter, isMojang bool) {
+	if p == nil {
+		return
+	}
+
+	policy := p.Local
+	if isMojang {
+		policy = p.Mojang
+	}
+
+	if policy != nil {
+		response.Header().Set("Cache-Control", policy.String())
+	}
+}
+
+func formatSeconds(duration time.Duration) string {
+	return strconv.FormatInt(int64(duration/time.Second), 10)
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachePolicy_String(t *testing.T) {
+	assert.Equal(t, "max-age=0", (&CachePolicy{}).String())
+	assert.Equal(t, "max-age=60", (&CachePolicy{MaxAge: time.Minute + 500*time.Millisecond}).String())
+	assert.Equal(t, "max-age=60, s-maxage=300, stale-while-revalidate=30", (&CachePolicy{
+		MaxAge:               time.Minute,
+		SharedMaxAge:         5 * time.Minute,
+		StaleWhileRevalidate: 30 * time.Second,
+	}).String())
+}
+
+func TestRouteCachePolicy_write(t *testing.T) {
+	policy := &RouteCachePolicy{
+		Local:  &CachePolicy{MaxAge: time.Hour},
+		Mojang: &CachePolicy{MaxAge: time.Minute},
+	}
+
+	t.Run("local profile", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		policy.write(w, false)
+		assert.Equal(t, "max-age=3600", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("Mojang profile", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		policy.write(w, true)
+		assert.Equal(t, "max-age=60", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("no policy for the source", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		(&RouteCachePolicy{Local: policy.Local}).write(w, true)
+		assert.Empty(t, w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("no policy for the route", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		var policy *RouteCachePolicy
+		policy.write(w, false)
+		assert.Empty(t, w.Header().Get("Cache-Control"))
+	})
+}
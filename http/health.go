@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/etherlabsio/healthcheck/v2"
+)
+
+// HealthCheckTimeout limits the time of a single health check request
+var HealthCheckTimeout = 10 * time.Second
+
+// HealthChecker wraps a checker and remembers the results of its previous calls,
+// so they can be displayed in the detailed health check response
+type HealthChecker struct {
+	Name    string
+	Checker healthcheck.Checker
+	// Observer checker is displayed in the response, but its failure doesn't affect the overall status
+	Observer bool
+
+	lock          sync.Mutex
+	lastError     string
+	lastErrorAt   time.Time
+	lastSuccessAt time.Time
+}
+
+type healthCheckerResult struct {
+	Status        string     `json:"status"`
+	Error         string     `json:"error,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LatencyMs     float64    `json:"latency_ms"`
+}
+
+type healthCheckResponse struct {
+	Status   string                          `json:"status"`
+	Checkers map[string]*healthCheckerResult `json:"checkers"`
+}
+
+func (c *HealthChecker) check(ctx context.Context) *healthCheckerResult {
+	startedAt := timeNow()
+	err := c.Checker.Check(ctx)
+	finishedAt := timeNow()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	result := &healthCheckerResult{
+		Status:    http.StatusText(http.StatusOK),
+		LatencyMs: float64(finishedAt.Sub(startedAt)) / float64(time.Millisecond),
+	}
+
+	if err != nil {
+		c.lastError = err.Error()
+		c.lastErrorAt = finishedAt
+		result.Status = http.StatusText(http.StatusServiceUnavailable)
+		result.Error = err.Error()
+	} else {
+		c.lastSuccessAt = finishedAt
+	}
+
+	result.LastError = c.lastError
+	if !c.lastErrorAt.IsZero() {
+		lastErrorAt := c.lastErrorAt.UTC()
+		result.LastErrorAt = &lastErrorAt
+	}
+
+	if !c.lastSuccessAt.IsZero() {
+		lastSuccessAt := c.lastSuccessAt.UTC()
+		result.LastSuccessAt = &lastSuccessAt
+	}
+
+	return result
+}
+
+// CreateHealthHandler runs all passed checkers concurrently and responds with the 503 status
+// if any of them has failed. The body contains the detailed information about each checker
+func CreateHealthHandler(checkers []*HealthChecker) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithTimeout(req.Context(), HealthCheckTimeout)
+		defer cancel()
+
+		var lock sync.Mutex
+		var wg sync.WaitGroup
+		response := &healthCheckResponse{
+			Status:   http.StatusText(http.StatusOK),
+			Checkers: make(map[string]*healthCheckerResult, len(checkers)),
+		}
+		code := http.StatusOK
+
+		wg.Add(len(checkers))
+		for _, checker := range checkers {
+			go func(checker *HealthChecker) {
+				defer wg.Done()
+				result := checker.check(ctx)
+
+				lock.Lock()
+				defer lock.Unlock()
+
+				response.Checkers[checker.Name] = result
+				if result.Error != "" && !checker.Observer {
+					code = http.StatusServiceUnavailable
+					response.Status = http.StatusText(code)
+				}
+			}(checker)
+		}
+
+		wg.Wait()
+
+		responseBody, _ := json.Marshal(response)
+		resp.Header().Set("Content-Type", "application/json")
+		resp.WriteHeader(code)
+		_, _ = resp.Write(responseBody)
+	})
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/etherlabsio/healthcheck/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateHealthHandler(t *testing.T) {
+	now := time.Date(2021, 2, 25, 1, 50, 23, 0, time.UTC)
+	timeNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t.Run("all checkers are successful", func(t *testing.T) {
+		handler := CreateHealthHandler([]*HealthChecker{
+			{Name: "first", Checker: healthcheck.CheckerFunc(func(ctx context.Context) error { return nil })},
+			{Name: "second", Checker: healthcheck.CheckerFunc(func(ctx context.Context) error { return nil })},
+		})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "http://chrly/healthz/ready", nil))
+
+		result := w.Result()
+		assert.Equal(t, 200, result.StatusCode)
+		assert.Equal(t, "application/json", result.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(result.Body)
+		assert.JSONEq(t, `{
+			"status": "OK",
+			"checkers": {
+				"first": {"status": "OK", "last_success_at": "2021-02-25T01:50:23Z", "latency_ms": 0},
+				"second": {"status": "OK", "last_success_at": "2021-02-25T01:50:23Z", "latency_ms": 0}
+			}
+		}`, string(body))
+	})
+
+	t.Run("one of the checkers has failed", func(t *testing.T) {
+		var err error
+		handler := CreateHealthHandler([]*HealthChecker{
+			{Name: "first", Checker: healthcheck.CheckerFunc(func(ctx context.Context) error { return nil })},
+			{Name: "second", Checker: healthcheck.CheckerFunc(func(ctx context.Context) error { return err })},
+		})
+
+		// The first call is successful to remember the last success time
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://chrly/healthz/ready", nil))
+
+		now = now.Add(time.Minute)
+		err = errors.New("mock error")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "http://chrly/healthz/ready", nil))
+
+		result := w.Result()
+		assert.Equal(t, 503, result.StatusCode)
+		body, _ := io.ReadAll(result.Body)
+		assert.JSONEq(t, `{
+			"status": "Service Unavailable",
+			"checkers": {
+				"first": {"status": "OK", "last_success_at": "2021-02-25T01:51:23Z", "latency_ms": 0},
+				"second": {
+					"status": "Service Unavailable",
+					"error": "mock error",
+					"last_error": "mock error",
+					"last_error_at": "2021-02-25T01:51:23Z",
+					"last_success_at": "2021-02-25T01:50:23Z",
+					"latency_ms": 0
+				}
+			}
+		}`, string(body))
+
+		// After recovering the last error should be still displayed
+		now = now.Add(time.Minute)
+		err = nil
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "http://chrly/healthz/ready", nil))
+
+		result = w.Result()
+		assert.Equal(t, 200, result.StatusCode)
+		var response struct {
+			Checkers map[string]map[string]interface{} `json:"checkers"`
+		}
+		body, _ = io.ReadAll(result.Body)
+		assert.NoError(t, json.Unmarshal(body, &response))
+		assert.Equal(t, "mock error", response.Checkers["second"]["last_error"])
+		assert.Equal(t, "2021-02-25T01:52:23Z", response.Checkers["second"]["last_success_at"])
+	})
+
+	t.Run("failed observer doesn't affect the status", func(t *testing.T) {
+		handler := CreateHealthHandler([]*HealthChecker{
+			{Name: "checker", Checker: healthcheck.CheckerFunc(func(ctx context.Context) error { return nil })},
+			{
+				Name:     "observer",
+				Checker:  healthcheck.CheckerFunc(func(ctx context.Context) error { return errors.New("mock error") }),
+				Observer: true,
+			},
+		})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "http://chrly/healthz/ready", nil))
+
+		result := w.Result()
+		assert.Equal(t, 200, result.StatusCode)
+		var response struct {
+			Status   string                            `json:"status"`
+			Checkers map[string]map[string]interface{} `json:"checkers"`
+		}
+		body, _ := io.ReadAll(result.Body)
+		assert.NoError(t, json.Unmarshal(body, &response))
+		assert.Equal(t, "OK", response.Status)
+		assert.Equal(t, "mock error", response.Checkers["observer"]["error"])
+	})
+
+	t.Run("no checkers", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		CreateHealthHandler(nil).ServeHTTP(w, httptest.NewRequest("GET", "http://chrly/healthz/live", nil))
+
+		result := w.Result()
+		assert.Equal(t, 200, result.StatusCode)
+		body, _ := io.ReadAll(result.Body)
+		assert.JSONEq(t, `{"status": "OK", "checkers": {}}`, string(body))
+	})
+}
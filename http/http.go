@@ -3,17 +3,20 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/mono83/slf"
 	"github.com/mono83/slf/wd"
 
 	"github.com/elyby/chrly/dispatcher"
+	"github.com/elyby/chrly/requestinfo"
 	v "github.com/elyby/chrly/version"
 )
 
@@ -21,13 +24,36 @@ type Emitter interface {
 	dispatcher.Emitter
 }
 
-func StartServer(server *http.Server, logger slf.Logger) {
+// ShutdownHook is called after the server has stopped accepting new requests
+// to let the service finish its work and release the resources
+type ShutdownHook struct {
+	Name     string
+	Shutdown func(ctx context.Context) error
+}
+
+type GracefulShutdown struct {
+	// Timeout limits the total duration of the server shutdown and all hooks calls
+	Timeout time.Duration
+	// Hooks are called sequentially in the specified order
+	Hooks []*ShutdownHook
+}
+
+func StartServer(server *http.Server, logger slf.Logger, shutdown *GracefulShutdown) {
 	logger.Debug("Chrly :v (:c)", wd.StringParam("v", v.Version()), wd.StringParam("c", v.Commit()))
 
 	done := make(chan bool, 1)
 	go func() {
-		logger.Info("Starting the server, HTTP on: :addr", wd.StringParam("addr", server.Addr))
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if server.TLSConfig != nil {
+			logger.Info("Starting the server, HTTPS on: :addr", wd.StringParam("addr", server.Addr))
+			// The certificate is provided by the TLSConfig.GetCertificate callback
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			logger.Info("Starting the server, HTTP on: :addr", wd.StringParam("addr", server.Addr))
+			err = server.ListenAndServe()
+		}
+
+		if err != nil && err != http.ErrServerClosed {
 			logger.Emergency("Error in main(): :err", wd.ErrParam(err))
 			close(done)
 		}
@@ -36,7 +62,20 @@ func StartServer(server *http.Server, logger slf.Logger) {
 	go func() {
 		s := waitForExitSignal()
 		logger.Info("Got signal: :signal, starting graceful shutdown", wd.StringParam("signal", s.String()))
-		_ = server.Shutdown(context.Background())
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdown.Timeout)
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Warning("Unable to gracefully stop the server: :err", wd.ErrParam(err))
+		}
+
+		for _, hook := range shutdown.Hooks {
+			if err := hook.Shutdown(ctx); err != nil {
+				logger.Warning("Unable to gracefully shutdown :name: :err", wd.NameParam(hook.Name), wd.ErrParam(err))
+			}
+		}
+
 		logger.Info("Graceful shutdown succeed, exiting", wd.StringParam("signal", s.String()))
 		close(done)
 	}()
@@ -54,6 +93,7 @@ func waitForExitSignal() os.Signal {
 type loggingResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	size       int
 }
 
 func (lrw *loggingResponseWriter) WriteHeader(code int) {
@@ -61,21 +101,74 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.ResponseWriter.WriteHeader(code)
 }
 
+func (lrw *loggingResponseWriter) Write(data []byte) (int, error) {
+	n, err := lrw.ResponseWriter.Write(data)
+	lrw.size += n
+
+	return n, err
+}
+
+// CreateRequestEventsMiddleware emits events before and after the request processing.
+// The after event receives the request, the response status code, the processing duration
+// and the number of bytes written to the response body.
+//
+// The middleware can be additionally attached to the mounted routers: in this case it doesn't emit anything
+// and only refines the route template of the request, since the root router knows only about the mount prefix
 func CreateRequestEventsMiddleware(emitter Emitter, prefix string) mux.MiddlewareFunc {
 	beforeTopic := strings.Join([]string{prefix, "before_request"}, ":")
 	afterTopic := strings.Join([]string{prefix, "after_request"}, ":")
 
 	return func(handler http.Handler) http.Handler {
 		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			if info := requestinfo.FromContext(req.Context()); info != nil {
+				info.SetRouteTemplate(req, getRouteTemplate(req))
+				handler.ServeHTTP(resp, req)
+				return
+			}
+
+			req, info := requestinfo.WithInfo(req)
+			info.SetRouteTemplate(req, getRouteTemplate(req))
+
 			emitter.Emit(beforeTopic, req)
 
+			startedAt := timeNow()
 			lrw := &loggingResponseWriter{
 				ResponseWriter: resp,
 				statusCode:     http.StatusOK,
 			}
 			handler.ServeHTTP(lrw, req)
 
-			emitter.Emit(afterTopic, req, lrw.statusCode)
+			emitter.Emit(afterTopic, req, lrw.statusCode, timeNow().Sub(startedAt), lrw.size)
+		})
+	}
+}
+
+func getRouteTemplate(req *http.Request) string {
+	route := mux.CurrentRoute(req)
+	if route == nil {
+		return ""
+	}
+
+	template, _ := route.GetPathTemplate()
+
+	return template
+}
+
+// CreateRequestIdMiddleware assigns a correlation id to each request. The id is taken from the X-Request-Id header
+// when it's passed and has a valid format, otherwise a new one will be generated. The id is stored
+// in the request context and sent back with the response headers
+func CreateRequestIdMiddleware() mux.MiddlewareFunc {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			requestId := req.Header.Get(requestinfo.RequestIdHeader)
+			if !requestinfo.IsValidRequestId(requestId) {
+				requestId = requestinfo.GenerateRequestId()
+			}
+
+			resp.Header().Set(requestinfo.RequestIdHeader, requestId)
+			req = req.WithContext(requestinfo.WithRequestId(req.Context(), requestId))
+
+			handler.ServeHTTP(resp, req)
 		})
 	}
 }
@@ -84,6 +177,22 @@ type Authenticator interface {
 	Authenticate(req *http.Request) error
 }
 
+// AnyAuthenticator passes the request when any of the authenticators accepts it.
+// The error of the last authenticator is returned when none of them does
+type AnyAuthenticator []Authenticator
+
+func (a AnyAuthenticator) Authenticate(req *http.Request) error {
+	err := errors.New("No authentication methods available")
+	for _, authenticator := range a {
+		err = authenticator.Authenticate(req)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
 func CreateAuthenticationMiddleware(checker Authenticator) mux.MiddlewareFunc {
 	return func(handler http.Handler) http.Handler {
 		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
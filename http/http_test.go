@@ -7,8 +7,11 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/gorilla/mux"
 	testify "github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+
+	"github.com/elyby/chrly/requestinfo"
 )
 
 type emitterMock struct {
@@ -23,14 +26,19 @@ func TestCreateRequestEventsMiddleware(t *testing.T) {
 	req := httptest.NewRequest("GET", "http://example.com", nil)
 	resp := httptest.NewRecorder()
 
+	isSameRequest := mock.MatchedBy(func(r *http.Request) bool {
+		return r.URL == req.URL
+	})
+
 	emitter := &emitterMock{}
-	emitter.On("Emit", "test_prefix:before_request", req)
-	emitter.On("Emit", "test_prefix:after_request", req, 400)
+	emitter.On("Emit", "test_prefix:before_request", isSameRequest)
+	emitter.On("Emit", "test_prefix:after_request", isSameRequest, 400, mock.AnythingOfType("time.Duration"), 11)
 
 	isHandlerCalled := false
 	middlewareFunc := CreateRequestEventsMiddleware(emitter, "test_prefix")
 	middlewareFunc.Middleware(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		resp.WriteHeader(400)
+		_, _ = resp.Write([]byte("bad request"))
 		isHandlerCalled = true
 	})).ServeHTTP(resp, req)
 
@@ -41,6 +49,63 @@ func TestCreateRequestEventsMiddleware(t *testing.T) {
 	emitter.AssertExpectations(t)
 }
 
+func TestCreateRequestEventsMiddlewareOnMountedRouter(t *testing.T) {
+	emitter := &emitterMock{}
+	middleware := CreateRequestEventsMiddleware(emitter, "test_prefix")
+
+	var routeTemplate string
+	emitter.On("Emit", "test_prefix:before_request", mock.Anything).Once()
+	emitter.On("Emit", "test_prefix:after_request", mock.Anything, 200, mock.Anything, 0).Once().Run(func(args mock.Arguments) {
+		req := args.Get(1).(*http.Request)
+		routeTemplate = requestinfo.FromContext(req.Context()).RouteTemplate()
+	})
+
+	mountedRouter := mux.NewRouter()
+	mountedRouter.HandleFunc("/skins/{username}", func(resp http.ResponseWriter, req *http.Request) {})
+	mountedRouter.Use(middleware)
+
+	router := mux.NewRouter()
+	router.PathPrefix("/api").Handler(http.StripPrefix("/api", mountedRouter))
+	router.Use(middleware)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "http://example.com/api/skins/mock", nil))
+
+	emitter.AssertExpectations(t)
+	testify.Equal(t, "/api/skins/{username}", routeTemplate)
+}
+
+func TestCreateRequestIdMiddleware(t *testing.T) {
+	t.Run("should use the passed id", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+		req.Header.Set("X-Request-Id", "f3b4a1c2-0d9e-4c1b-8a7e-2f6d5c4b3a21")
+		resp := httptest.NewRecorder()
+
+		var requestId string
+		CreateRequestIdMiddleware().Middleware(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			requestId = requestinfo.RequestId(req.Context())
+		})).ServeHTTP(resp, req)
+
+		testify.Equal(t, "f3b4a1c2-0d9e-4c1b-8a7e-2f6d5c4b3a21", requestId)
+		testify.Equal(t, "f3b4a1c2-0d9e-4c1b-8a7e-2f6d5c4b3a21", resp.Header().Get("X-Request-Id"))
+	})
+
+	t.Run("should generate a new id when it isn't passed or invalid", func(t *testing.T) {
+		for _, passedId := range []string{"", "invalid request id"} {
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			req.Header.Set("X-Request-Id", passedId)
+			resp := httptest.NewRecorder()
+
+			var requestId string
+			CreateRequestIdMiddleware().Middleware(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+				requestId = requestinfo.RequestId(req.Context())
+			})).ServeHTTP(resp, req)
+
+			testify.Regexp(t, "^[0-9a-f]{32}$", requestId)
+			testify.Equal(t, requestId, resp.Header().Get("X-Request-Id"))
+		}
+	})
+}
+
 type authCheckerMock struct {
 	mock.Mock
 }
@@ -93,6 +158,42 @@ func TestCreateAuthenticationMiddleware(t *testing.T) {
 	})
 }
 
+func TestAnyAuthenticator(t *testing.T) {
+	t.Run("pass by the second authenticator", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+
+		first := &authCheckerMock{}
+		first.On("Authenticate", req).Once().Return(errors.New("first error"))
+		second := &authCheckerMock{}
+		second.On("Authenticate", req).Once().Return(nil)
+
+		err := AnyAuthenticator{first, second}.Authenticate(req)
+		testify.Nil(t, err)
+
+		first.AssertExpectations(t)
+		second.AssertExpectations(t)
+	})
+
+	t.Run("return the last error", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+
+		first := &authCheckerMock{}
+		first.On("Authenticate", req).Once().Return(errors.New("first error"))
+		second := &authCheckerMock{}
+		second.On("Authenticate", req).Once().Return(errors.New("second error"))
+
+		err := AnyAuthenticator{first, second}.Authenticate(req)
+		testify.EqualError(t, err, "second error")
+	})
+
+	t.Run("no authenticators", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+
+		err := AnyAuthenticator{}.Authenticate(req)
+		testify.Error(t, err)
+	})
+}
+
 func TestNotFoundHandler(t *testing.T) {
 	assert := testify.New(t)
 
@@ -17,7 +17,8 @@ const scopesClaim = "scopes"
 type Scope string
 
 var (
-	SkinScope = Scope("skin")
+	SkinScope   = Scope("skin")
+	WorkerScope = Scope("worker")
 )
 
 type JwtAuth struct {
@@ -43,6 +44,15 @@ func (t *JwtAuth) NewToken(scopes ...Scope) ([]byte, error) {
 }
 
 func (t *JwtAuth) Authenticate(req *http.Request) error {
+	return t.authenticate(req, "")
+}
+
+// RequireScope returns an Authenticator, which additionally checks that the token has been issued for the passed scope
+func (t *JwtAuth) RequireScope(scope Scope) Authenticator {
+	return &scopedJwtAuth{t, scope}
+}
+
+func (t *JwtAuth) authenticate(req *http.Request, scope Scope) error {
 	if len(t.Key) == 0 {
 		return t.emitErr(errors.New("Signing key not set"))
 	}
@@ -67,6 +77,10 @@ func (t *JwtAuth) Authenticate(req *http.Request) error {
 		return t.emitErr(errors.New("JWT token have invalid signature. It may be corrupted or expired"))
 	}
 
+	if scope != "" && !hasScope(token.Claims().Get(scopesClaim), scope) {
+		return t.emitErr(errors.New("JWT token doesn't have the required scope"))
+	}
+
 	t.Emit("authentication:success")
 
 	return nil
@@ -76,3 +90,29 @@ func (t *JwtAuth) emitErr(err error) error {
 	t.Emit("authentication:error", err)
 	return err
 }
+
+type scopedJwtAuth struct {
+	*JwtAuth
+	scope Scope
+}
+
+func (t *scopedJwtAuth) Authenticate(req *http.Request) error {
+	return t.authenticate(req, t.scope)
+}
+
+// hasScope accepts both the list of scopes and a single scope, passed as a string,
+// because the tokens issued by the other services may encode the claim in that way
+func hasScope(claim interface{}, scope Scope) bool {
+	switch scopes := claim.(type) {
+	case string:
+		return Scope(scopes) == scope
+	case []interface{}:
+		for _, value := range scopes {
+			if str, ok := value.(string); ok && Scope(str) == scope {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -125,3 +125,53 @@ func TestJwtAuth_Authenticate(t *testing.T) {
 		emitter.AssertExpectations(t)
 	})
 }
+
+func TestJwtAuth_RequireScope(t *testing.T) {
+	auth := &JwtAuth{Key: []byte("secret")}
+	workerToken, _ := auth.NewToken(WorkerScope)
+
+	t.Run("token has the required scope", func(t *testing.T) {
+		emitter := &emitterMock{}
+		emitter.On("Emit", "authentication:success")
+
+		req := httptest.NewRequest("POST", "http://localhost", nil)
+		req.Header.Add("Authorization", "Bearer "+string(workerToken))
+		jwt := &JwtAuth{Key: []byte("secret"), Emitter: emitter}
+
+		err := jwt.RequireScope(WorkerScope).Authenticate(req)
+		assert.Nil(t, err)
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("token has the required scope passed as a string", func(t *testing.T) {
+		emitter := &emitterMock{}
+		emitter.On("Emit", "authentication:success")
+
+		req := httptest.NewRequest("POST", "http://localhost", nil)
+		req.Header.Add("Authorization", "Bearer "+jwt)
+		jwt := &JwtAuth{Key: []byte("secret"), Emitter: emitter}
+
+		err := jwt.RequireScope(SkinScope).Authenticate(req)
+		assert.Nil(t, err)
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("token doesn't have the required scope", func(t *testing.T) {
+		emitter := &emitterMock{}
+		emitter.On("Emit", "authentication:error", mock.MatchedBy(func(err error) bool {
+			assert.EqualError(t, err, "JWT token doesn't have the required scope")
+			return true
+		}))
+
+		req := httptest.NewRequest("POST", "http://localhost", nil)
+		req.Header.Add("Authorization", "Bearer "+string(workerToken))
+		jwt := &JwtAuth{Key: []byte("secret"), Emitter: emitter}
+
+		err := jwt.RequireScope(SkinScope).Authenticate(req)
+		assert.EqualError(t, err, "JWT token doesn't have the required scope")
+
+		emitter.AssertExpectations(t)
+	})
+}
@@ -1,7 +1,9 @@
 package http
 
 import (
+	"context"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
@@ -9,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,11 +19,21 @@ import (
 
 	"github.com/elyby/chrly/api/mojang"
 	"github.com/elyby/chrly/model"
+	"github.com/elyby/chrly/requestinfo"
 	"github.com/elyby/chrly/utils"
 )
 
 var timeNow = time.Now
 
+// The names of the routes, whose responses are cached according to the Skinsystem.CachePolicies
+const (
+	RouteSkins          = "skins"
+	RouteCloaks         = "cloaks"
+	RouteTextures       = "textures"
+	RouteSignedTextures = "signed_textures"
+	RouteProfile        = "profile"
+)
+
 type SkinsRepository interface {
 	FindSkinByUsername(username string) (*model.Skin, error)
 	FindSkinByUserId(id int) (*model.Skin, error)
@@ -34,7 +47,14 @@ type CapesRepository interface {
 }
 
 type MojangTexturesProvider interface {
-	GetForUsername(username string) (*mojang.SignedTexturesResponse, error)
+	GetForUsername(ctx context.Context, username string) (*mojang.SignedTexturesResponse, error)
+}
+
+type TexturesMirror interface {
+	// Mirror returns the hash, by which the texture can be opened
+	Mirror(ctx context.Context, url string) (string, error)
+	// Open returns nil nil when there is no texture with such hash
+	Open(hash string) (io.ReadCloser, error)
 }
 
 type TexturesSigner interface {
@@ -44,12 +64,22 @@ type TexturesSigner interface {
 
 type Skinsystem struct {
 	Emitter
-	SkinsRepo                   SkinsRepository
-	CapesRepo                   CapesRepository
-	MojangTexturesProvider      MojangTexturesProvider
-	TexturesSigner              TexturesSigner
-	TexturesExtraParamName      string
-	TexturesExtraParamValue     string
+	SkinsRepo               SkinsRepository
+	CapesRepo               CapesRepository
+	MojangTexturesProvider  MojangTexturesProvider
+	TexturesSigner          TexturesSigner
+	TexturesExtraParamName  string
+	TexturesExtraParamValue string
+	TrustedProxies          requestinfo.TrustedProxies
+	// When set, the Mojang textures are served from the local mirror instead of redirecting to the Mojang's servers
+	TexturesMirror TexturesMirror
+	// CachePolicies are keyed by the route names. The Cache-Control header isn't sent for the missing routes
+	CachePolicies map[string]*RouteCachePolicy
+	// RedirectStatus is used for the redirects to the skin and cape urls. 302 is used when it's zero
+	RedirectStatus int
+	// MojangTexturesTimeout limits the wait of the Mojang textures, so the handler is finished
+	// before the server's write timeout. The wait isn't limited when it's zero
+	MojangTexturesTimeout       time.Duration
 	texturesExtraParamSignature string
 }
 
@@ -86,6 +116,10 @@ type profile struct {
 	CapeFile        io.Reader
 	MojangTextures  string
 	MojangSignature string
+	// UpdatedAt is zero when the textures aren't taken from the local storage or the time of their change is unknown
+	UpdatedAt time.Time
+	// IsMojang is true when the textures were requested from Mojang instead of the local storage
+	IsMojang bool
 }
 
 func (ctx *Skinsystem) Handler() *mux.Router {
@@ -96,6 +130,9 @@ func (ctx *Skinsystem) Handler() *mux.Router {
 	router.HandleFunc("/textures/{username}", ctx.texturesHandler).Methods(http.MethodGet)
 	router.HandleFunc("/textures/signed/{username}", ctx.signedTexturesHandler).Methods(http.MethodGet)
 	router.HandleFunc("/profile/{username}", ctx.profileHandler).Methods(http.MethodGet)
+	if ctx.TexturesMirror != nil {
+		router.HandleFunc("/mojang-textures/{hash}.png", ctx.mirroredTextureHandler).Methods(http.MethodGet)
+	}
 	// Legacy
 	router.HandleFunc("/skins", ctx.skinGetHandler).Methods(http.MethodGet)
 	router.HandleFunc("/cloaks", ctx.capeGetHandler).Methods(http.MethodGet)
@@ -117,7 +154,8 @@ func (ctx *Skinsystem) skinHandler(response http.ResponseWriter, request *http.R
 		return
 	}
 
-	http.Redirect(response, request, profile.Textures.Skin.Url, 301)
+	ctx.CachePolicies[RouteSkins].write(response, profile.IsMojang)
+	http.Redirect(response, request, profile.Textures.Skin.Url, ctx.redirectStatus())
 }
 
 func (ctx *Skinsystem) skinGetHandler(response http.ResponseWriter, request *http.Request) {
@@ -143,8 +181,9 @@ func (ctx *Skinsystem) capeHandler(response http.ResponseWriter, request *http.R
 		return
 	}
 
+	ctx.CachePolicies[RouteCloaks].write(response, profile.IsMojang)
 	if profile.CapeFile == nil {
-		http.Redirect(response, request, profile.Textures.Cape.Url, 301)
+		http.Redirect(response, request, profile.Textures.Cape.Url, ctx.redirectStatus())
 	} else {
 		request.Header.Set("Content-Type", "image/png")
 		_, _ = io.Copy(response, profile.CapeFile)
@@ -175,6 +214,11 @@ func (ctx *Skinsystem) texturesHandler(response http.ResponseWriter, request *ht
 	}
 
 	responseData, _ := json.Marshal(profile.Textures)
+	ctx.CachePolicies[RouteTextures].write(response, profile.IsMojang)
+	if writeNotModified(response, request, computeETag(responseData), profile.UpdatedAt) {
+		return
+	}
+
 	response.Header().Set("Content-Type", "application/json")
 	_, _ = response.Write(responseData)
 }
@@ -207,6 +251,11 @@ func (ctx *Skinsystem) signedTexturesHandler(response http.ResponseWriter, reque
 	}
 
 	responseJson, _ := json.Marshal(profileResponse)
+	ctx.CachePolicies[RouteSignedTextures].write(response, profile.IsMojang)
+	if writeNotModified(response, request, computeETag(responseJson), profile.UpdatedAt) {
+		return
+	}
+
 	response.Header().Set("Content-Type", "application/json")
 	_, _ = response.Write(responseJson)
 }
@@ -227,15 +276,27 @@ func (ctx *Skinsystem) profileHandler(response http.ResponseWriter, request *htt
 		profile = createEmptyProfile()
 		profile.Id = formatUuid(forceResponseWithUuid)
 		profile.Username = parseUsername(mux.Vars(request)["username"])
+		// The username wasn't found anywhere, so it gets the cache policy of the profiles requested from Mojang
+		profile.IsMojang = true
 	}
 
+	isSigned := request.URL.Query().Get("unsigned") == "false"
 	texturesPropContent := &mojang.TexturesProp{
-		Timestamp:   utils.UnixMillisecond(timeNow()),
 		ProfileID:   profile.Id,
 		ProfileName: profile.Username,
 		Textures:    profile.Textures,
 	}
 
+	// The timestamp and so the response body are changed on each request, so the tag is computed
+	// from the content without the timestamp and marked as weak
+	etagContent, _ := json.Marshal(texturesPropContent)
+	etag := "W/" + computeETag(etagContent, []byte(ctx.TexturesExtraParamValue), []byte(strconv.FormatBool(isSigned)))
+	ctx.CachePolicies[RouteProfile].write(response, profile.IsMojang)
+	if writeNotModified(response, request, etag, profile.UpdatedAt) {
+		return
+	}
+
+	texturesPropContent.Timestamp = utils.UnixMillisecond(timeNow())
 	texturesPropValueJson, _ := json.Marshal(texturesPropContent)
 	texturesPropEncodedValue := base64.StdEncoding.EncodeToString(texturesPropValueJson)
 
@@ -248,7 +309,7 @@ func (ctx *Skinsystem) profileHandler(response http.ResponseWriter, request *htt
 		Value: ctx.TexturesExtraParamValue,
 	}
 
-	if request.URL.Query().Get("unsigned") == "false" {
+	if isSigned {
 		customProp.Signature = ctx.texturesExtraParamSignature
 
 		texturesSignature, err := ctx.TexturesSigner.SignTextures(texturesProp.Value)
@@ -273,6 +334,24 @@ func (ctx *Skinsystem) profileHandler(response http.ResponseWriter, request *htt
 	_, _ = response.Write(responseJson)
 }
 
+func (ctx *Skinsystem) mirroredTextureHandler(response http.ResponseWriter, request *http.Request) {
+	file, err := ctx.TexturesMirror.Open(mux.Vars(request)["hash"])
+	if err != nil {
+		panic(err)
+	}
+
+	if file == nil {
+		response.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	response.Header().Set("Content-Type", "image/png")
+	// The texture is stored under the hash of its content, so it never changes
+	response.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	_, _ = io.Copy(response, file)
+}
+
 func (ctx *Skinsystem) signatureVerificationKeyHandler(response http.ResponseWriter, request *http.Request) {
 	publicKey, err := ctx.TexturesSigner.GetPublicKey()
 	if err != nil {
@@ -334,15 +413,19 @@ func (ctx *Skinsystem) getProfile(request *http.Request, proxy bool) (*profile,
 		if cape != nil {
 			profile.CapeFile = cape.File
 			profile.Textures.Cape = &mojang.CapeTexturesResponse{
-				// Use statically http since the application doesn't support TLS
-				Url: "http://" + request.Host + "/cloaks/" + username,
+				Url: ctx.TrustedProxies.Scheme(request) + "://" + request.Host + "/cloaks/" + username,
 			}
 		}
 
 		profile.MojangTextures = skin.MojangTextures
 		profile.MojangSignature = skin.MojangSignature
+		// The change of the cape doesn't update the skin, so the time of the textures is unknown
+		if profile.CapeFile == nil {
+			profile.UpdatedAt = skinUpdatedAt(skin)
+		}
 	} else if proxy {
-		mojangProfile, err := ctx.MojangTexturesProvider.GetForUsername(username)
+		profile.IsMojang = true
+		mojangProfile, err := ctx.getMojangTextures(request.Context(), username)
 		// If we at least know something about a user,
 		// than we can ignore an error and return profile without textures
 		if err != nil && profile.Id != "" {
@@ -376,12 +459,20 @@ func (ctx *Skinsystem) getProfile(request *http.Request, proxy bool) (*profile,
 			profile.MojangSignature = texturesProp.Signature
 		}
 
+		if ctx.TexturesMirror != nil && decodedTextures != nil && decodedTextures.Textures != nil {
+			err = ctx.mirrorTextures(request, profile, decodedTextures)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		// If user id is unknown at this point, then use values from Mojang profile
 		if profile.Id == "" {
 			profile.Id = mojangProfile.Id
 			profile.Username = mojangProfile.Name
 		}
 	} else if profile.Id != "" {
+		profile.UpdatedAt = skinUpdatedAt(skin)
 		return profile, nil
 	} else {
 		return nil, nil
@@ -390,6 +481,147 @@ func (ctx *Skinsystem) getProfile(request *http.Request, proxy bool) (*profile,
 	return profile, nil
 }
 
+// mirrorTextures replaces the Mojang textures urls with the local mirror urls. Since the textures property
+// is changed, it's signed with our key. When the textures can't be mirrored, the original urls are kept
+func (ctx *Skinsystem) mirrorTextures(request *http.Request, profile *profile, decodedTextures *mojang.TexturesProp) error {
+	// The decoded textures are cached together with the Mojang's response, so they must not be modified
+	textures := &mojang.TexturesResponse{}
+	if decodedTextures.Textures.Skin != nil {
+		skin := *decodedTextures.Textures.Skin
+		url, err := ctx.mirrorTexture(request, skin.Url)
+		if err != nil {
+			// Keep the original urls and signature
+			return nil
+		}
+
+		skin.Url = url
+		textures.Skin = &skin
+	}
+
+	if decodedTextures.Textures.Cape != nil {
+		cape := *decodedTextures.Textures.Cape
+		url, err := ctx.mirrorTexture(request, cape.Url)
+		if err != nil {
+			// Keep the original urls and signature
+			return nil
+		}
+
+		cape.Url = url
+		textures.Cape = &cape
+	}
+
+	texturesPropValueJson, _ := json.Marshal(&mojang.TexturesProp{
+		Timestamp:   decodedTextures.Timestamp,
+		ProfileID:   decodedTextures.ProfileID,
+		ProfileName: decodedTextures.ProfileName,
+		Textures:    textures,
+	})
+	texturesPropEncodedValue := base64.StdEncoding.EncodeToString(texturesPropValueJson)
+	signature, err := ctx.TexturesSigner.SignTextures(texturesPropEncodedValue)
+	if err != nil {
+		return err
+	}
+
+	profile.Textures = textures
+	profile.MojangTextures = texturesPropEncodedValue
+	profile.MojangSignature = signature
+
+	return nil
+}
+
+func (ctx *Skinsystem) mirrorTexture(request *http.Request, url string) (string, error) {
+	hash, err := ctx.TexturesMirror.Mirror(request.Context(), url)
+	if err != nil {
+		ctx.Emit("skinsystem:mirror_error", url, err)
+		return "", err
+	}
+
+	return ctx.TrustedProxies.Scheme(request) + "://" + request.Host + "/mojang-textures/" + hash + ".png", nil
+}
+
+func (ctx *Skinsystem) getMojangTextures(c context.Context, username string) (*mojang.SignedTexturesResponse, error) {
+	if ctx.MojangTexturesTimeout > 0 {
+		var cancel context.CancelFunc
+		c, cancel = context.WithTimeout(c, ctx.MojangTexturesTimeout)
+		defer cancel()
+	}
+
+	return ctx.MojangTexturesProvider.GetForUsername(c, username)
+}
+
+func (ctx *Skinsystem) redirectStatus() int {
+	if ctx.RedirectStatus == 0 {
+		return http.StatusFound
+	}
+
+	return ctx.RedirectStatus
+}
+
+func skinUpdatedAt(skin *model.Skin) time.Time {
+	if skin.UpdatedAt == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(skin.UpdatedAt, 0)
+}
+
+// computeETag returns the quoted strong entity tag of the content
+func computeETag(content ...[]byte) string {
+	hash := sha256.New()
+	for _, part := range content {
+		hash.Write(part)
+		// Separates the parts, so the different splits of the same bytes give different tags
+		hash.Write([]byte{0})
+	}
+
+	return fmt.Sprintf(`"%x"`, hash.Sum(nil)[:16])
+}
+
+// writeNotModified sets the validators of the response. When the client's copy is still fresh, it finishes
+// the response with 304 Not Modified and returns true. The zero lastModified isn't sent
+func writeNotModified(response http.ResponseWriter, request *http.Request, etag string, lastModified time.Time) bool {
+	response.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		response.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if !isNotModified(request, etag, lastModified) {
+		return false
+	}
+
+	response.WriteHeader(http.StatusNotModified)
+
+	return true
+}
+
+// isNotModified evaluates the conditional headers according to the RFC 7232.
+// The If-Modified-Since is ignored when the If-None-Match is present
+func isNotModified(request *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := request.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// The weak comparison is used, so the weak tags of the client match the same strong ones
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+
+	ifModifiedSince, err := http.ParseTime(request.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// The header has only the seconds precision
+	return !lastModified.Truncate(time.Second).After(ifModifiedSince)
+}
+
 func createEmptyProfile() *profile {
 	return &profile{
 		Textures: &mojang.TexturesResponse{}, // Field must be initialized to avoid "null" after json encoding
@@ -2,12 +2,15 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"image"
 	"image/png"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -22,6 +25,7 @@ import (
 
 	"github.com/elyby/chrly/api/mojang"
 	"github.com/elyby/chrly/model"
+	"github.com/elyby/chrly/requestinfo"
 )
 
 /***************
@@ -85,7 +89,7 @@ type mojangTexturesProviderMock struct {
 	mock.Mock
 }
 
-func (m *mojangTexturesProviderMock) GetForUsername(username string) (*mojang.SignedTexturesResponse, error) {
+func (m *mojangTexturesProviderMock) GetForUsername(_ context.Context, username string) (*mojang.SignedTexturesResponse, error) {
 	args := m.Called(username)
 	var result *mojang.SignedTexturesResponse
 	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
@@ -196,7 +200,7 @@ var skinsTestsCases = []*skinsystemTestCase{
 			suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
 		},
 		AfterTest: func(suite *skinsystemTestSuite, response *http.Response) {
-			suite.Equal(301, response.StatusCode)
+			suite.Equal(302, response.StatusCode)
 			suite.Equal("http://chrly/skin.png", response.Header.Get("Location"))
 		},
 	},
@@ -207,7 +211,7 @@ var skinsTestsCases = []*skinsystemTestCase{
 			suite.MojangTexturesProvider.On("GetForUsername", "mock_username").Return(createMojangResponseWithTextures(true, false), nil)
 		},
 		AfterTest: func(suite *skinsystemTestSuite, response *http.Response) {
-			suite.Equal(301, response.StatusCode)
+			suite.Equal(302, response.StatusCode)
 			suite.Equal("http://mojang/skin.png", response.Header.Get("Location"))
 		},
 	},
@@ -279,7 +283,7 @@ func (suite *skinsystemTestSuite) TestSkin() {
 		suite.App.Handler().ServeHTTP(w, req)
 
 		resp := w.Result()
-		suite.Equal(301, resp.StatusCode)
+		suite.Equal(302, resp.StatusCode)
 		suite.Equal("http://chrly/skin.png", resp.Header.Get("Location"))
 	})
 }
@@ -339,7 +343,7 @@ var capesTestsCases = []*skinsystemTestCase{
 			suite.MojangTexturesProvider.On("GetForUsername", "mock_username").Return(createMojangResponseWithTextures(true, true), nil)
 		},
 		AfterTest: func(suite *skinsystemTestSuite, response *http.Response) {
-			suite.Equal(301, response.StatusCode)
+			suite.Equal(302, response.StatusCode)
 			suite.Equal("http://mojang/cape.png", response.Header.Get("Location"))
 		},
 	},
@@ -594,6 +598,312 @@ func (suite *skinsystemTestSuite) TestTextures() {
 	}
 }
 
+func (suite *skinsystemTestSuite) TestTexturesConditionalRequests() {
+	suite.RunSubTest("Send the validators of the local textures", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(200, resp.StatusCode)
+		suite.Regexp(`^"[0-9a-f]{32}"$`, resp.Header.Get("ETag"))
+		suite.Equal("Thu, 25 Feb 2021 00:50:23 GMT", resp.Header.Get("Last-Modified"))
+	})
+
+	suite.RunSubTest("Respond with 304 when the ETag matches", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+		w := httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+		etag := w.Result().Header.Get("ETag")
+
+		req = httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+		req.Header.Set("If-None-Match", `"outdated", W/`+etag)
+		w = httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(304, resp.StatusCode)
+		suite.Equal(etag, resp.Header.Get("ETag"))
+		suite.Empty(resp.Header.Get("Content-Type"))
+		body, _ := ioutil.ReadAll(resp.Body)
+		suite.Empty(body)
+	})
+
+	suite.RunSubTest("Respond with the body when the ETag doesn't match", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+		req.Header.Set("If-None-Match", `"outdated"`)
+		// If-Modified-Since must be ignored when If-None-Match is present
+		req.Header.Set("If-Modified-Since", "Thu, 25 Feb 2021 00:50:23 GMT")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		suite.Equal(200, w.Result().StatusCode)
+	})
+
+	suite.RunSubTest("Respond with 304 when the textures weren't modified since", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+		req.Header.Set("If-Modified-Since", "Thu, 25 Feb 2021 00:50:23 GMT")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		suite.Equal(304, w.Result().StatusCode)
+	})
+
+	suite.RunSubTest("Respond with the body when the textures were modified since", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+		req.Header.Set("If-Modified-Since", "Thu, 25 Feb 2021 00:50:22 GMT")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		suite.Equal(200, w.Result().StatusCode)
+	})
+
+	suite.RunSubTest("Don't send Last-Modified for the textures with the cape", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(createCapeModel(), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+		req.Header.Set("If-Modified-Since", "Thu, 25 Feb 2021 00:50:23 GMT")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(200, resp.StatusCode)
+		suite.NotEmpty(resp.Header.Get("ETag"))
+		suite.Empty(resp.Header.Get("Last-Modified"))
+	})
+
+	suite.RunSubTest("Don't send Last-Modified for the Mojang textures", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(nil, nil)
+		suite.MojangTexturesProvider.On("GetForUsername", "mock_username").Once().Return(createMojangResponseWithTextures(true, true), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+		req.Header.Set("If-Modified-Since", "Thu, 25 Feb 2021 00:50:23 GMT")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(200, resp.StatusCode)
+		suite.NotEmpty(resp.Header.Get("ETag"))
+		suite.Empty(resp.Header.Get("Last-Modified"))
+	})
+}
+
+func (suite *skinsystemTestSuite) TestCachePolicies() {
+	policies := map[string]*RouteCachePolicy{
+		RouteSkins: {
+			Local:  &CachePolicy{MaxAge: time.Hour, SharedMaxAge: 24 * time.Hour},
+			Mojang: &CachePolicy{MaxAge: time.Minute, StaleWhileRevalidate: 30 * time.Second},
+		},
+		RouteCloaks:         {Local: &CachePolicy{MaxAge: time.Hour}},
+		RouteTextures:       {Local: &CachePolicy{MaxAge: 2 * time.Hour}, Mojang: &CachePolicy{MaxAge: 2 * time.Minute}},
+		RouteSignedTextures: {Local: &CachePolicy{MaxAge: 3 * time.Hour}},
+		RouteProfile:        {Local: &CachePolicy{MaxAge: 4 * time.Hour}, Mojang: &CachePolicy{MaxAge: 4 * time.Minute}},
+	}
+
+	suite.RunSubTest("Don't send Cache-Control by default", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/skins/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		suite.Empty(w.Result().Header.Get("Cache-Control"))
+	})
+
+	suite.RunSubTest("Redirect to the local skin with the configured status", func() {
+		suite.App.CachePolicies = policies
+		suite.App.RedirectStatus = 307
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/skins/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(307, resp.StatusCode)
+		suite.Equal("http://chrly/skin.png", resp.Header.Get("Location"))
+		suite.Equal("max-age=3600, s-maxage=86400", resp.Header.Get("Cache-Control"))
+	})
+
+	suite.RunSubTest("Redirect to the Mojang cape with the configured status", func() {
+		suite.App.CachePolicies = policies
+		suite.App.RedirectStatus = 301
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(nil, nil)
+		suite.MojangTexturesProvider.On("GetForUsername", "mock_username").Return(createMojangResponseWithTextures(true, true), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/cloaks/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(301, resp.StatusCode)
+		suite.Equal("http://mojang/cape.png", resp.Header.Get("Location"))
+		suite.Empty(resp.Header.Get("Cache-Control"), "there is no policy for the Mojang capes")
+	})
+
+	suite.RunSubTest("Use the Mojang policy for the Mojang skin", func() {
+		suite.App.CachePolicies = policies
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(nil, nil)
+		suite.MojangTexturesProvider.On("GetForUsername", "mock_username").Return(createMojangResponseWithTextures(true, false), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/skins/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(302, resp.StatusCode)
+		suite.Equal("max-age=60, stale-while-revalidate=30", resp.Header.Get("Cache-Control"))
+	})
+
+	suite.RunSubTest("Send the policy of the local textures with 304 response", func() {
+		suite.App.CachePolicies = policies
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+		req.Header.Set("If-Modified-Since", "Thu, 25 Feb 2021 00:50:23 GMT")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(304, resp.StatusCode)
+		suite.Equal("max-age=7200", resp.Header.Get("Cache-Control"))
+	})
+
+	suite.RunSubTest("Use the policy of the route", func() {
+		suite.App.CachePolicies = policies
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/signed/mock_username", nil)
+		w := httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+		suite.Equal("max-age=10800", w.Result().Header.Get("Cache-Control"))
+
+		req = httptest.NewRequest("GET", "http://chrly/profile/mock_username", nil)
+		w = httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+		suite.Equal("max-age=14400", w.Result().Header.Get("Cache-Control"))
+	})
+
+	suite.RunSubTest("Use the Mojang policy for the unknown profile", func() {
+		suite.App.CachePolicies = policies
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(nil, nil)
+		suite.MojangTexturesProvider.On("GetForUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/profile/mock_username?onUnknownProfileRespondWithUuid=0f657aa8-bfbe-415d-b700-5750090d3af3", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(200, resp.StatusCode)
+		suite.Equal("max-age=240", resp.Header.Get("Cache-Control"))
+	})
+}
+
+type deadlineAwareTexturesProvider struct {
+	deadline    time.Time
+	hasDeadline bool
+}
+
+func (p *deadlineAwareTexturesProvider) GetForUsername(ctx context.Context, _ string) (*mojang.SignedTexturesResponse, error) {
+	p.deadline, p.hasDeadline = ctx.Deadline()
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+func (suite *skinsystemTestSuite) TestMojangTexturesTimeout() {
+	suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(nil, nil)
+	provider := &deadlineAwareTexturesProvider{}
+	suite.App.MojangTexturesProvider = provider
+	suite.App.MojangTexturesTimeout = 10 * time.Millisecond
+
+	req := httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+	w := httptest.NewRecorder()
+
+	startedAt := time.Now()
+	suite.PanicsWithError(context.DeadlineExceeded.Error(), func() {
+		suite.App.Handler().ServeHTTP(w, req)
+	})
+	suite.True(provider.hasDeadline)
+	suite.WithinDuration(startedAt.Add(10*time.Millisecond), provider.deadline, 10*time.Millisecond)
+}
+
+func (suite *skinsystemTestSuite) TestTexturesCapeUrlScheme() {
+	suite.RunSubTest("TLS connection", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(createCapeModel(), nil)
+
+		req := httptest.NewRequest("GET", "https://chrly/textures/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		body, _ := ioutil.ReadAll(w.Result().Body)
+		suite.Contains(string(body), `"url":"https://chrly/cloaks/mock_username"`)
+	})
+
+	suite.RunSubTest("X-Forwarded-Proto from the trusted proxy", func() {
+		suite.App.TrustedProxies, _ = requestinfo.ParseTrustedProxies([]string{"192.0.2.1"})
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(createCapeModel(), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		body, _ := ioutil.ReadAll(w.Result().Body)
+		suite.Contains(string(body), `"url":"https://chrly/cloaks/mock_username"`)
+	})
+
+	suite.RunSubTest("X-Forwarded-Proto from the untrusted address", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(createCapeModel(), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/mock_username", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		body, _ := ioutil.ReadAll(w.Result().Body)
+		suite.Contains(string(body), `"url":"http://chrly/cloaks/mock_username"`)
+	})
+}
+
 /***********************************
  * Get signed textures tests cases *
  ***********************************/
@@ -740,6 +1050,29 @@ func (suite *skinsystemTestSuite) TestSignedTextures() {
 	}
 }
 
+func (suite *skinsystemTestSuite) TestSignedTexturesConditionalRequests() {
+	suite.RunSubTest("Respond with 304 when the ETag matches", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/signed/mock_username", nil)
+		w := httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		etag := resp.Header.Get("ETag")
+		suite.Regexp(`^"[0-9a-f]{32}"$`, etag)
+		suite.Equal("Thu, 25 Feb 2021 00:50:23 GMT", resp.Header.Get("Last-Modified"))
+
+		req = httptest.NewRequest("GET", "http://chrly/textures/signed/mock_username", nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+
+		suite.Equal(304, w.Result().StatusCode)
+	})
+}
+
 /***************************
  * Get profile tests cases *
  ***************************/
@@ -1129,6 +1462,67 @@ func (suite *skinsystemTestSuite) TestProfile() {
 	}
 }
 
+func (suite *skinsystemTestSuite) TestProfileConditionalRequests() {
+	suite.RunSubTest("Keep the ETag when only the timestamp is changed", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/profile/mock_username", nil)
+		w := httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		etag := resp.Header.Get("ETag")
+		suite.Regexp(`^W/"[0-9a-f]{32}"$`, etag)
+		suite.Equal("Thu, 25 Feb 2021 00:50:23 GMT", resp.Header.Get("Last-Modified"))
+
+		timeNow = func() time.Time {
+			return time.Date(2021, 02, 26, 01, 50, 23, 0, time.UTC)
+		}
+
+		req = httptest.NewRequest("GET", "http://chrly/profile/mock_username", nil)
+		w = httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+
+		suite.Equal(etag, w.Result().Header.Get("ETag"))
+	})
+
+	suite.RunSubTest("Use the different ETags for the signed and the unsigned profiles", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+		suite.TexturesSigner.On("SignTextures", mock.Anything).Return("textures signature", nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/profile/mock_username", nil)
+		w := httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+		unsignedEtag := w.Result().Header.Get("ETag")
+
+		req = httptest.NewRequest("GET", "http://chrly/profile/mock_username?unsigned=false", nil)
+		w = httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+
+		suite.NotEqual(unsignedEtag, w.Result().Header.Get("ETag"))
+	})
+
+	suite.RunSubTest("Respond with 304 without signing the textures when the ETag matches", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+		suite.TexturesSigner.On("SignTextures", mock.Anything).Once().Return("textures signature", nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/profile/mock_username?unsigned=false", nil)
+		w := httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+		etag := w.Result().Header.Get("ETag")
+
+		req = httptest.NewRequest("GET", "http://chrly/profile/mock_username?unsigned=false", nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		suite.App.Handler().ServeHTTP(w, req)
+
+		suite.Equal(304, w.Result().StatusCode)
+	})
+}
+
 /***************************
  * Get profile tests cases *
  ***************************/
@@ -1206,6 +1600,134 @@ func (suite *skinsystemTestSuite) TestSignatureVerificationKey() {
 	}
 }
 
+/**************************************
+ * Mojang textures mirror tests cases *
+ **************************************/
+
+type texturesMirrorMock struct {
+	mock.Mock
+}
+
+func (m *texturesMirrorMock) Mirror(_ context.Context, url string) (string, error) {
+	args := m.Called(url)
+	return args.String(0), args.Error(1)
+}
+
+func (m *texturesMirrorMock) Open(hash string) (io.ReadCloser, error) {
+	args := m.Called(hash)
+	var result io.ReadCloser
+	if casted, ok := args.Get(0).(io.ReadCloser); ok {
+		result = casted
+	}
+
+	return result, args.Error(1)
+}
+
+func (suite *skinsystemTestSuite) TestMojangTexturesMirror() {
+	var mirror *texturesMirrorMock
+	setupMirror := func() {
+		mirror = &texturesMirrorMock{}
+		suite.App.TexturesMirror = mirror
+	}
+
+	suite.RunSubTest("Redirect to the mirrored skin", func() {
+		setupMirror()
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(nil, nil)
+		suite.MojangTexturesProvider.On("GetForUsername", "mock_username").Return(createMojangResponseWithTextures(true, false), nil)
+		mirror.On("Mirror", "http://mojang/skin.png").Once().Return("mock-hash", nil)
+		suite.TexturesSigner.On("SignTextures", mock.Anything).Once().Return("chrly signature", nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/skins/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(302, resp.StatusCode)
+		suite.Equal("http://chrly/mojang-textures/mock-hash.png", resp.Header.Get("Location"))
+		mirror.AssertExpectations(suite.T())
+	})
+
+	suite.RunSubTest("Re-sign the mirrored textures", func() {
+		setupMirror()
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(nil, nil)
+		suite.MojangTexturesProvider.On("GetForUsername", "mock_username").Return(createMojangResponseWithTextures(true, true), nil)
+		mirror.On("Mirror", "http://mojang/skin.png").Once().Return("skin-hash", nil)
+		mirror.On("Mirror", "http://mojang/cape.png").Once().Return("cape-hash", nil)
+		suite.TexturesSigner.On("SignTextures", mock.Anything).Once().Return("chrly signature", nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/textures/signed/mock_username?proxy=true", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(200, resp.StatusCode)
+
+		var result *mojang.SignedTexturesResponse
+		body, _ := ioutil.ReadAll(resp.Body)
+		suite.Require().NoError(json.Unmarshal(body, &result))
+		suite.Equal("chrly signature", result.Props[0].Signature)
+
+		textures, err := result.DecodeTextures()
+		suite.Require().NoError(err)
+		suite.Equal("http://chrly/mojang-textures/skin-hash.png", textures.Textures.Skin.Url)
+		suite.Equal("http://chrly/mojang-textures/cape-hash.png", textures.Textures.Cape.Url)
+		suite.Equal("292a1db7353d476ca99cab8f57mojang", textures.ProfileID)
+		mirror.AssertExpectations(suite.T())
+	})
+
+	suite.RunSubTest("Keep the original textures when the mirroring fails", func() {
+		setupMirror()
+		err := errors.New("mirror error")
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(nil, nil)
+		suite.MojangTexturesProvider.On("GetForUsername", "mock_username").Return(createMojangResponseWithTextures(true, false), nil)
+		mirror.On("Mirror", "http://mojang/skin.png").Once().Return("", err)
+		suite.Emitter.On("Emit", "skinsystem:mirror_error", "http://mojang/skin.png", err).Once()
+
+		req := httptest.NewRequest("GET", "http://chrly/skins/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(302, resp.StatusCode)
+		suite.Equal("http://mojang/skin.png", resp.Header.Get("Location"))
+		mirror.AssertExpectations(suite.T())
+	})
+
+	suite.RunSubTest("Serve the mirrored texture", func() {
+		setupMirror()
+		mirror.On("Open", "mock-hash").Once().Return(io.NopCloser(bytes.NewReader(createCape())), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/mojang-textures/mock-hash.png", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(200, resp.StatusCode)
+		suite.Equal("image/png", resp.Header.Get("Content-Type"))
+		suite.Equal("public, max-age=31536000, immutable", resp.Header.Get("Cache-Control"))
+		responseData, _ := ioutil.ReadAll(resp.Body)
+		suite.Equal(createCape(), responseData)
+		mirror.AssertExpectations(suite.T())
+	})
+
+	suite.RunSubTest("Respond with 404 for not mirrored texture", func() {
+		setupMirror()
+		mirror.On("Open", "mock-hash").Once().Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/mojang-textures/mock-hash.png", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		suite.Equal(404, w.Result().StatusCode)
+		mirror.AssertExpectations(suite.T())
+	})
+}
+
 /****************
  * Custom tests *
  ****************/
@@ -1230,6 +1752,7 @@ func createSkinModel(username string, isSlim bool) *model.Skin {
 		MojangTextures:  "mocked textures base64",
 		MojangSignature: "mocked signature",
 		IsSlim:          isSlim,
+		UpdatedAt:       1614214223,
 	}
 }
 
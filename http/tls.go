@@ -0,0 +1,121 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/mono83/slf"
+	"github.com/mono83/slf/wd"
+)
+
+// CertificateReloader keeps the TLS certificate loaded from the files and allows to reload it
+// without restarting the server, e.g. after the certificate has been renewed
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+	lock     sync.RWMutex
+	cert     *tls.Certificate
+}
+
+func NewCertificateReloader(certFile string, keyFile string) (*CertificateReloader, error) {
+	reloader := &CertificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// Reload reads the certificate and the key files again. When they can't be loaded,
+// the previously loaded certificate is kept
+func (r *CertificateReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load TLS certificate: %w", err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.cert = &cert
+
+	return nil
+}
+
+// GetCertificate should be used as the tls.Config.GetCertificate callback
+func (r *CertificateReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.cert, nil
+}
+
+// ReloadOnSighup starts listening for the SIGHUP signal and reloads the certificate each time it's received
+func (r *CertificateReloader) ReloadOnSighup(logger slf.Logger) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			if err := r.Reload(); err != nil {
+				logger.Error("Unable to reload TLS certificate: :err", wd.ErrParam(err))
+				continue
+			}
+
+			logger.Info("TLS certificate has been reloaded")
+		}
+	}()
+}
+
+// TLSConfig returns the server config, which uses the reloadable certificate. HTTP/2 is enabled
+// in addition to HTTP/1.1 since the server is going to handle the TLS connections itself
+func (r *CertificateReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+}
+
+// LoadCertPool reads the PEM encoded CA certificates from the file
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	pemCerts, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CA certificates: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemCerts) {
+		return nil, fmt.Errorf("no CA certificates found in %s", file)
+	}
+
+	return pool, nil
+}
+
+// ClientCertificateAuth authenticates the requests, which have presented a client certificate
+// during the TLS handshake. The certificate itself is verified by the server against tls.Config.ClientCAs.
+// The missing certificate isn't reported as the authentication error, since this authenticator is usually
+// combined with the token one, which will report its own error
+type ClientCertificateAuth struct {
+	Emitter
+}
+
+func (a *ClientCertificateAuth) Authenticate(req *http.Request) error {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return errors.New("Client certificate not presented")
+	}
+
+	a.Emit("authentication:success")
+
+	return nil
+}
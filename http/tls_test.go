@@ -0,0 +1,140 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSelfSignedCertificate(t *testing.T, dir string, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return certFile, keyFile
+}
+
+func getCertificateCommonName(t *testing.T, reloader *CertificateReloader) string {
+	cert, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return parsed.Subject.CommonName
+}
+
+func TestCertificateReloader(t *testing.T) {
+	t.Run("load and reload the certificate", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := writeSelfSignedCertificate(t, dir, "first")
+
+		reloader, err := NewCertificateReloader(certFile, keyFile)
+		require.NoError(t, err)
+		assert.Equal(t, "first", getCertificateCommonName(t, reloader))
+
+		writeSelfSignedCertificate(t, dir, "second")
+		require.NoError(t, reloader.Reload())
+		assert.Equal(t, "second", getCertificateCommonName(t, reloader))
+	})
+
+	t.Run("keep the previous certificate when the new one is invalid", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := writeSelfSignedCertificate(t, dir, "first")
+
+		reloader, err := NewCertificateReloader(certFile, keyFile)
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0600))
+		assert.Error(t, reloader.Reload())
+		assert.Equal(t, "first", getCertificateCommonName(t, reloader))
+	})
+
+	t.Run("return an error when the files don't exist", func(t *testing.T) {
+		_, err := NewCertificateReloader("/not/exists/cert.pem", "/not/exists/key.pem")
+		assert.Error(t, err)
+	})
+
+	t.Run("tls config enables http2", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := writeSelfSignedCertificate(t, dir, "first")
+
+		reloader, err := NewCertificateReloader(certFile, keyFile)
+		require.NoError(t, err)
+
+		assert.Contains(t, reloader.TLSConfig().NextProtos, "h2")
+	})
+}
+
+func TestLoadCertPool(t *testing.T) {
+	t.Run("load the certificates", func(t *testing.T) {
+		certFile, _ := writeSelfSignedCertificate(t, t.TempDir(), "ca")
+
+		pool, err := LoadCertPool(certFile)
+		assert.NoError(t, err)
+		assert.NotNil(t, pool)
+	})
+
+	t.Run("return an error when the file has no certificates", func(t *testing.T) {
+		_, keyFile := writeSelfSignedCertificate(t, t.TempDir(), "ca")
+
+		_, err := LoadCertPool(keyFile)
+		assert.Error(t, err)
+	})
+}
+
+func TestClientCertificateAuth(t *testing.T) {
+	t.Run("certificate verified", func(t *testing.T) {
+		emitter := &emitterMock{}
+		emitter.On("Emit", "authentication:success")
+
+		req := httptest.NewRequest("GET", "https://localhost", nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+
+		err := (&ClientCertificateAuth{Emitter: emitter}).Authenticate(req)
+		assert.NoError(t, err)
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("certificate not presented", func(t *testing.T) {
+		emitter := &emitterMock{}
+
+		req := httptest.NewRequest("GET", "https://localhost", nil)
+		req.TLS = &tls.ConnectionState{}
+
+		err := (&ClientCertificateAuth{Emitter: emitter}).Authenticate(req)
+		assert.EqualError(t, err, "Client certificate not presented")
+
+		emitter.AssertExpectations(t)
+	})
+}
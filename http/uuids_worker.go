@@ -1,8 +1,15 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/gorilla/mux"
 
@@ -10,7 +17,7 @@ import (
 )
 
 type MojangUuidsProvider interface {
-	GetUuid(username string) (*mojang.ProfileInfo, error)
+	GetUuid(ctx context.Context, username string) (*mojang.ProfileInfo, error)
 }
 
 type UUIDsWorker struct {
@@ -20,25 +27,16 @@ type UUIDsWorker struct {
 func (ctx *UUIDsWorker) Handler() *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
 	router.Handle("/mojang-uuid/{username}", http.HandlerFunc(ctx.getUUIDHandler)).Methods("GET")
+	router.Handle("/mojang-uuids", http.HandlerFunc(ctx.getUUIDsHandler)).Methods("POST")
 
 	return router
 }
 
 func (ctx *UUIDsWorker) getUUIDHandler(response http.ResponseWriter, request *http.Request) {
 	username := mux.Vars(request)["username"]
-	profile, err := ctx.GetUuid(username)
+	profile, err := ctx.GetUuid(request.Context(), username)
 	if err != nil {
-		if _, ok := err.(*mojang.TooManyRequestsError); ok {
-			response.WriteHeader(http.StatusTooManyRequests)
-			return
-		}
-
-		response.Header().Set("Content-Type", "application/json")
-		response.WriteHeader(http.StatusInternalServerError)
-		result, _ := json.Marshal(map[string]interface{}{
-			"provider": err.Error(),
-		})
-		_, _ = response.Write(result)
+		uuidsProviderError(response, err)
 		return
 	}
 
@@ -51,3 +49,92 @@ func (ctx *UUIDsWorker) getUUIDHandler(response http.ResponseWriter, request *ht
 	responseData, _ := json.Marshal(profile)
 	_, _ = response.Write(responseData)
 }
+
+// MaxUsernamesPerBatchRequest limits the number of usernames, which can be passed to the batch endpoint
+const MaxUsernamesPerBatchRequest = 100
+
+// getUUIDsHandler accepts the same payload and responds in the same format as the Mojang's bulk usernames endpoint.
+// The usernames are passed to the provider all at once to let them be processed within the same batch
+func (ctx *UUIDsWorker) getUUIDsHandler(response http.ResponseWriter, request *http.Request) {
+	var usernames []string
+	if err := json.NewDecoder(request.Body).Decode(&usernames); err != nil {
+		apiBadRequest(response, map[string][]string{
+			"usernames": {"the body must be a JSON array of usernames"},
+		})
+		return
+	}
+
+	usernames = uniqueUsernames(usernames)
+	if len(usernames) == 0 || len(usernames) > MaxUsernamesPerBatchRequest {
+		apiBadRequest(response, map[string][]string{
+			"usernames": {fmt.Sprintf("the number of usernames must be between 1 and %d", MaxUsernamesPerBatchRequest)},
+		})
+		return
+	}
+
+	profiles := make([]*mojang.ProfileInfo, len(usernames))
+	errs := make([]error, len(usernames))
+	var wg sync.WaitGroup
+	wg.Add(len(usernames))
+	for i, username := range usernames {
+		go func(i int, username string) {
+			defer wg.Done()
+			profiles[i], errs[i] = ctx.GetUuid(request.Context(), username)
+		}(i, username)
+	}
+
+	wg.Wait()
+
+	result := make([]*mojang.ProfileInfo, 0, len(profiles))
+	for i, profile := range profiles {
+		if errs[i] != nil {
+			uuidsProviderError(response, errs[i])
+			return
+		}
+
+		if profile != nil {
+			result = append(result, profile)
+		}
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	responseData, _ := json.Marshal(result)
+	_, _ = response.Write(responseData)
+}
+
+func uuidsProviderError(response http.ResponseWriter, err error) {
+	var tooManyRequestsErr *mojang.TooManyRequestsError
+	if errors.As(err, &tooManyRequestsErr) {
+		if tooManyRequestsErr.RetryAfter > 0 {
+			seconds := int64(math.Ceil(tooManyRequestsErr.RetryAfter.Seconds()))
+			response.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+		}
+
+		response.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusInternalServerError)
+	result, _ := json.Marshal(map[string]interface{}{
+		"provider": err.Error(),
+	})
+	_, _ = response.Write(result)
+}
+
+// uniqueUsernames removes empty and case-insensitive duplicated usernames keeping the order of the first occurrences
+func uniqueUsernames(usernames []string) []string {
+	seen := make(map[string]bool, len(usernames))
+	result := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		key := strings.ToLower(username)
+		if username == "" || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		result = append(result, username)
+	}
+
+	return result
+}
@@ -1,11 +1,14 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -21,7 +24,7 @@ type uuidsProviderMock struct {
 	mock.Mock
 }
 
-func (m *uuidsProviderMock) GetUuid(username string) (*mojang.ProfileInfo, error) {
+func (m *uuidsProviderMock) GetUuid(_ context.Context, username string) (*mojang.ProfileInfo, error) {
 	args := m.Called(username)
 	var result *mojang.ProfileInfo
 	if casted, ok := args.Get(0).(*mojang.ProfileInfo); ok {
@@ -132,10 +135,22 @@ var getUuidTestsCases = []*uuidsWorkerTestCase{
 		},
 		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
 			suite.Equal(429, response.StatusCode)
+			suite.Empty(response.Header.Get("Retry-After"))
 			body, _ := ioutil.ReadAll(response.Body)
 			suite.Empty(body)
 		},
 	},
+	{
+		Name: "Receive Too Many Requests with the delay from UUIDs provider",
+		BeforeTest: func(suite *uuidsWorkerTestSuite) {
+			err := &mojang.TooManyRequestsError{RetryAfter: 1500 * time.Millisecond}
+			suite.UuidsProvider.On("GetUuid", "mock_username").Return(nil, err)
+		},
+		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
+			suite.Equal(429, response.StatusCode)
+			suite.Equal("2", response.Header.Get("Retry-After"))
+		},
+	},
 }
 
 func (suite *uuidsWorkerTestSuite) TestGetUUID() {
@@ -152,3 +167,123 @@ func (suite *uuidsWorkerTestSuite) TestGetUUID() {
 		})
 	}
 }
+
+/*************************
+ * Get UUIDs tests cases *
+ *************************/
+
+type getUuidsTestCase struct {
+	Name       string
+	Body       string
+	BeforeTest func(suite *uuidsWorkerTestSuite)
+	AfterTest  func(suite *uuidsWorkerTestSuite, response *http.Response)
+}
+
+var getUuidsTestsCases = []*getUuidsTestCase{
+	{
+		Name: "Success provider response",
+		Body: `["mock_username1", "mock_username2", "MOCK_USERNAME1", "mock_username3"]`,
+		BeforeTest: func(suite *uuidsWorkerTestSuite) {
+			suite.UuidsProvider.On("GetUuid", "mock_username1").Once().Return(&mojang.ProfileInfo{
+				Id:   "0fcc38620f1845f3a54e1b523c1bd1c7",
+				Name: "mock_username1",
+			}, nil)
+			suite.UuidsProvider.On("GetUuid", "mock_username2").Once().Return(nil, nil)
+			suite.UuidsProvider.On("GetUuid", "mock_username3").Once().Return(&mojang.ProfileInfo{
+				Id:   "4566e69fc90748ee8d71d7ba5aa00d20",
+				Name: "mock_username3",
+			}, nil)
+		},
+		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
+			suite.Equal(200, response.StatusCode)
+			suite.Equal("application/json", response.Header.Get("Content-Type"))
+			body, _ := ioutil.ReadAll(response.Body)
+			suite.JSONEq(`[
+				{"id": "0fcc38620f1845f3a54e1b523c1bd1c7", "name": "mock_username1"},
+				{"id": "4566e69fc90748ee8d71d7ba5aa00d20", "name": "mock_username3"}
+			]`, string(body))
+		},
+	},
+	{
+		Name: "No profiles found",
+		Body: `["mock_username"]`,
+		BeforeTest: func(suite *uuidsWorkerTestSuite) {
+			suite.UuidsProvider.On("GetUuid", "mock_username").Once().Return(nil, nil)
+		},
+		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
+			suite.Equal(200, response.StatusCode)
+			body, _ := ioutil.ReadAll(response.Body)
+			suite.JSONEq(`[]`, string(body))
+		},
+	},
+	{
+		Name: "Receive Too Many Requests from UUIDs provider",
+		Body: `["mock_username1", "mock_username2"]`,
+		BeforeTest: func(suite *uuidsWorkerTestSuite) {
+			err := &mojang.TooManyRequestsError{RetryAfter: 10 * time.Second}
+			suite.UuidsProvider.On("GetUuid", "mock_username1").Once().Return(nil, err)
+			suite.UuidsProvider.On("GetUuid", "mock_username2").Once().Return(nil, err)
+		},
+		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
+			suite.Equal(429, response.StatusCode)
+			suite.Equal("10", response.Header.Get("Retry-After"))
+		},
+	},
+	{
+		Name: "Receive error from UUIDs provider",
+		Body: `["mock_username"]`,
+		BeforeTest: func(suite *uuidsWorkerTestSuite) {
+			suite.UuidsProvider.On("GetUuid", "mock_username").Once().Return(nil, errors.New("this is an error"))
+		},
+		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
+			suite.Equal(500, response.StatusCode)
+			body, _ := ioutil.ReadAll(response.Body)
+			suite.JSONEq(`{
+				"provider": "this is an error"
+			}`, string(body))
+		},
+	},
+	{
+		Name:       "Invalid body",
+		Body:       `{"username": "mock_username"}`,
+		BeforeTest: func(suite *uuidsWorkerTestSuite) {},
+		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
+			suite.Equal(400, response.StatusCode)
+			body, _ := ioutil.ReadAll(response.Body)
+			suite.JSONEq(`{
+				"errors": {
+					"usernames": ["the body must be a JSON array of usernames"]
+				}
+			}`, string(body))
+		},
+	},
+	{
+		Name:       "Empty usernames list",
+		Body:       `[""]`,
+		BeforeTest: func(suite *uuidsWorkerTestSuite) {},
+		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
+			suite.Equal(400, response.StatusCode)
+			body, _ := ioutil.ReadAll(response.Body)
+			suite.JSONEq(`{
+				"errors": {
+					"usernames": ["the number of usernames must be between 1 and 100"]
+				}
+			}`, string(body))
+		},
+	},
+}
+
+func (suite *uuidsWorkerTestSuite) TestGetUUIDs() {
+	for _, testCase := range getUuidsTestsCases {
+		suite.RunSubTest(testCase.Name, func() {
+			testCase.BeforeTest(suite)
+
+			req := httptest.NewRequest("POST", "http://chrly/mojang-uuids", strings.NewReader(testCase.Body))
+			w := httptest.NewRecorder()
+
+			suite.App.Handler().ServeHTTP(w, req)
+
+			testCase.AfterTest(suite, w.Result())
+		})
+	}
+}
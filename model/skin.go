@@ -10,5 +10,7 @@ type Skin struct {
 	IsSlim          bool   `json:"isSlim"`
 	MojangTextures  string `json:"mojangTextures"`
 	MojangSignature string `json:"mojangSignature"`
-	OldUsername     string
+	// UpdatedAt is the unix timestamp of the last save. It's zero for the records saved by the older versions
+	UpdatedAt   int64 `json:"updatedAt,omitempty"`
+	OldUsername string
 }
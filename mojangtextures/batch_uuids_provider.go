@@ -2,6 +2,8 @@ package mojangtextures
 
 import (
 	"context"
+	"errors"
+	"math"
 	"strings"
 	"sync"
 	"time"
@@ -14,54 +16,189 @@ type jobResult struct {
 	Error   error
 }
 
+// Priority is a lane of the jobs queue. Each round is filled from the high priority lane first,
+// so the interactive requests don't wait behind the background work
+type Priority string
+
+const (
+	PriorityHigh Priority = "high"
+	PriorityLow  Priority = "low"
+)
+
+// The lanes in the order they're filled
+var priorities = []Priority{PriorityHigh, PriorityLow}
+
+type priorityContextKey struct{}
+
+// WithPriority returns the context, which makes the usernames requested with it be queued into the lane
+// of the passed priority. Without it the usernames are queued with the PriorityHigh
+func WithPriority(c context.Context, priority Priority) context.Context {
+	return context.WithValue(c, priorityContextKey{}, priority)
+}
+
+func priorityFromContext(c context.Context) Priority {
+	if priority, ok := c.Value(priorityContextKey{}).(Priority); ok {
+		return priority
+	}
+
+	return PriorityHigh
+}
+
 type job struct {
+	// Context of the caller. The job is dropped from the queue when the caller has gone
+	Context     context.Context
 	Username    string
 	RespondChan chan *jobResult
+	Priority    Priority
+	// Retries is the number of times the job was queued again after the transient failure
+	Retries int
+
+	// The jobs for the same username, which share the slot in the queue with this job
+	duplicates []*job
+}
+
+// isCanceled returns true only when the callers of all the merged jobs have gone
+func (j *job) isCanceled() bool {
+	if j.Context == nil || j.Context.Err() == nil {
+		return false
+	}
+
+	for _, duplicate := range j.duplicates {
+		if !duplicate.isCanceled() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// respond sends the result to the callers of this job and all the merged jobs
+func (j *job) respond(result *jobResult) {
+	j.RespondChan <- result
+	close(j.RespondChan)
+	for _, duplicate := range j.duplicates {
+		duplicate.respond(result)
+	}
+}
+
+// merge attaches the job for the same username, so its caller will get the result of this job
+func (j *job) merge(duplicate *job) {
+	j.duplicates = append(j.duplicates, duplicate)
 }
 
 type jobsQueue struct {
 	lock  sync.Mutex
-	items []*job
+	lanes map[Priority][]*job
+	// The queued jobs by their lowercased usernames
+	index map[string]*job
 }
 
 func newJobsQueue() *jobsQueue {
+	lanes := make(map[Priority][]*job, len(priorities))
+	for _, priority := range priorities {
+		lanes[priority] = []*job{}
+	}
+
 	return &jobsQueue{
-		items: []*job{},
+		lanes: lanes,
+		index: map[string]*job{},
 	}
 }
 
-func (s *jobsQueue) Enqueue(job *job) int {
+// Enqueue returns the number of jobs in all lanes and whether the job has taken a new slot in them.
+// The job without a known priority is queued with the PriorityHigh. The job for the already queued username
+// is merged with the queued one, which is moved into the lane of the higher priority when it's needed
+func (s *jobsQueue) Enqueue(job *job) (int, bool) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	s.items = append(s.items, job)
+	priority := job.Priority
+	if _, ok := s.lanes[priority]; !ok {
+		priority = PriorityHigh
+	}
+
+	key := strings.ToLower(job.Username)
+	if queued, ok := s.index[key]; ok {
+		queued.merge(job)
+		if priority == PriorityHigh && queued.Priority != PriorityHigh {
+			s.remove(queued)
+			queued.Priority = PriorityHigh
+			s.lanes[PriorityHigh] = append(s.lanes[PriorityHigh], queued)
+		}
+
+		return s.len(), false
+	}
 
-	return len(s.items)
+	job.Priority = priority
+	s.lanes[priority] = append(s.lanes[priority], job)
+	s.index[key] = job
+
+	return s.len(), true
 }
 
-func (s *jobsQueue) Dequeue(n int) ([]*job, int) {
+// Dequeue returns up to n jobs and the number of jobs left in each lane. The jobs are taken
+// from the high priority lane first. The canceled jobs are skipped
+func (s *jobsQueue) Dequeue(n int) ([]*job, map[Priority]int) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	l := len(s.items)
-	if n > l {
-		n = l
+	items := make([]*job, 0, n)
+	lengths := make(map[Priority]int, len(priorities))
+	for _, priority := range priorities {
+		lane := s.lanes[priority]
+		i := 0
+		for ; i < len(lane) && len(items) < n; i++ {
+			delete(s.index, strings.ToLower(lane[i].Username))
+			if !lane[i].isCanceled() {
+				items = append(items, lane[i])
+			}
+		}
+
+		s.lanes[priority] = lane[i:]
+		lengths[priority] = len(s.lanes[priority])
+	}
+
+	return items, lengths
+}
+
+func (s *jobsQueue) remove(job *job) {
+	lane := s.lanes[job.Priority]
+	for i, queued := range lane {
+		if queued == job {
+			s.lanes[job.Priority] = append(lane[:i:i], lane[i+1:]...)
+			return
+		}
 	}
+}
 
-	items := s.items[0:n]
-	s.items = s.items[n:l]
+func (s *jobsQueue) len() int {
+	total := 0
+	for _, lane := range s.lanes {
+		total += len(lane)
+	}
 
-	return items, l - n
+	return total
 }
 
 var usernamesToUuids = mojang.UsernamesToUuids
 
 type JobsIteration struct {
-	Jobs  []*job
+	Jobs []*job
+	// Queue is the number of jobs left in all lanes and Lanes is the number of jobs left in each of them
 	Queue int
+	Lanes map[Priority]int
 	c     chan struct{}
 }
 
+func newJobsIteration(jobs []*job, lanes map[Priority]int, c chan struct{}) *JobsIteration {
+	queue := 0
+	for _, length := range lanes {
+		queue += length
+	}
+
+	return &JobsIteration{Jobs: jobs, Queue: queue, Lanes: lanes, c: c}
+}
+
 func (j *JobsIteration) Done() {
 	if j.c != nil {
 		close(j.c)
@@ -101,9 +238,9 @@ func (ctx *PeriodicStrategy) GetJobs(abort context.Context) <-chan *JobsIteratio
 				close(ch)
 				return
 			case <-time.After(ctx.Delay):
-				jobs, queueLen := ctx.queue.Dequeue(ctx.Batch)
+				jobs, lanes := ctx.queue.Dequeue(ctx.Batch)
 				jobDoneChan := make(chan struct{})
-				ch <- &JobsIteration{jobs, queueLen, jobDoneChan}
+				ch <- newJobsIteration(jobs, lanes, jobDoneChan)
 				<-jobDoneChan
 			}
 		}
@@ -129,8 +266,9 @@ func NewFullBusStrategy(delay time.Duration, batch int) *FullBusStrategy {
 }
 
 func (ctx *FullBusStrategy) Queue(job *job) {
-	n := ctx.queue.Enqueue(job)
-	if n%ctx.Batch == 0 {
+	// The merged job doesn't change the load of the bus, which has been already reported
+	n, isAdded := ctx.queue.Enqueue(job)
+	if isAdded && n%ctx.Batch == 0 {
 		ctx.busIsFull <- true
 	}
 }
@@ -158,39 +296,261 @@ func (ctx *FullBusStrategy) GetJobs(abort context.Context) <-chan *JobsIteration
 }
 
 func (ctx *FullBusStrategy) sendJobs(ch chan *JobsIteration) {
-	jobs, queueLen := ctx.queue.Dequeue(ctx.Batch)
-	ch <- &JobsIteration{jobs, queueLen, nil}
+	jobs, lanes := ctx.queue.Dequeue(ctx.Batch)
+	ch <- newJobsIteration(jobs, lanes, nil)
 }
 
+var adaptiveStrategyNow = time.Now
+
+// ResultAwareStrategy is implemented by the strategies, which adjust their behavior
+// according to the results of the performed requests
+type ResultAwareStrategy interface {
+	ReportResult(err error)
+}
+
+// AdaptiveStrategy sends requests as fast as its token bucket allows. The rate of the bucket is halved each time
+// the request is rejected with the 429 status code and slowly ramps back up after each successful request.
+// When the response contains the Retry-After header, no requests are sent until the specified time.
+// The bucket holds a single token, so the requests are never sent in bursts
+type AdaptiveStrategy struct {
+	Emitter
+	Batch int
+	// MaxRate and MinRate are the bounds of the rate in requests per second
+	MaxRate float64
+	MinRate float64
+	// RampUp is added to the rate after each successful request
+	RampUp float64
+
+	queue *jobsQueue
+
+	lock        sync.Mutex
+	rate        float64
+	tokens      float64
+	refilledAt  time.Time
+	pausedUntil time.Time
+}
+
+func NewAdaptiveStrategy(emitter Emitter, batch int, maxRate float64, minRate float64, rampUp float64) *AdaptiveStrategy {
+	return &AdaptiveStrategy{
+		Emitter:    emitter,
+		Batch:      batch,
+		MaxRate:    maxRate,
+		MinRate:    minRate,
+		RampUp:     rampUp,
+		queue:      newJobsQueue(),
+		rate:       maxRate,
+		tokens:     1,
+		refilledAt: adaptiveStrategyNow(),
+	}
+}
+
+func (ctx *AdaptiveStrategy) Queue(job *job) {
+	ctx.queue.Enqueue(job)
+}
+
+func (ctx *AdaptiveStrategy) GetJobs(abort context.Context) <-chan *JobsIteration {
+	ch := make(chan *JobsIteration)
+	ctx.Emit("mojang_textures:batch_uuids_provider:rate", ctx.Rate())
+	go func() {
+		for {
+			select {
+			case <-abort.Done():
+				close(ch)
+				return
+			case <-time.After(ctx.delay()):
+				if !ctx.hasToken() {
+					continue
+				}
+
+				jobs, lanes := ctx.queue.Dequeue(ctx.Batch)
+				if len(jobs) != 0 {
+					ctx.takeToken()
+				}
+
+				jobDoneChan := make(chan struct{})
+				ch <- newJobsIteration(jobs, lanes, jobDoneChan)
+				<-jobDoneChan
+			}
+		}
+	}()
+
+	return ch
+}
+
+// Rate returns the current rate in requests per second
+func (ctx *AdaptiveStrategy) Rate() float64 {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	return ctx.rate
+}
+
+func (ctx *AdaptiveStrategy) ReportResult(err error) {
+	ctx.lock.Lock()
+	ctx.refill()
+	prevRate := ctx.rate
+	var tooManyRequestsErr *mojang.TooManyRequestsError
+	if errors.As(err, &tooManyRequestsErr) {
+		ctx.rate = math.Max(ctx.rate/2, ctx.MinRate)
+		ctx.tokens = 0
+		if tooManyRequestsErr.RetryAfter > 0 {
+			ctx.pausedUntil = adaptiveStrategyNow().Add(tooManyRequestsErr.RetryAfter)
+		}
+	} else if err == nil {
+		ctx.rate = math.Min(ctx.rate+ctx.RampUp, ctx.MaxRate)
+	}
+
+	rate := ctx.rate
+	ctx.lock.Unlock()
+
+	if rate != prevRate {
+		ctx.Emit("mojang_textures:batch_uuids_provider:rate", rate)
+	}
+}
+
+// delay returns the time to wait until the next token. When the token is already available,
+// the queue is checked for new jobs with the max rate
+func (ctx *AdaptiveStrategy) delay() time.Duration {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	ctx.refill()
+	now := adaptiveStrategyNow()
+	if now.Before(ctx.pausedUntil) {
+		return ctx.pausedUntil.Sub(now)
+	}
+
+	if ctx.tokens >= 1 {
+		return rateToDelay(ctx.MaxRate)
+	}
+
+	return time.Duration((1 - ctx.tokens) * float64(rateToDelay(ctx.rate)))
+}
+
+func (ctx *AdaptiveStrategy) hasToken() bool {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	ctx.refill()
+
+	return ctx.tokens >= 1 && !adaptiveStrategyNow().Before(ctx.pausedUntil)
+}
+
+func (ctx *AdaptiveStrategy) takeToken() {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	ctx.refill()
+	ctx.tokens = math.Max(ctx.tokens-1, 0)
+}
+
+func (ctx *AdaptiveStrategy) refill() {
+	now := adaptiveStrategyNow()
+	ctx.tokens = math.Min(ctx.tokens+now.Sub(ctx.refilledAt).Seconds()*ctx.rate, 1)
+	ctx.refilledAt = now
+}
+
+func rateToDelay(rate float64) time.Duration {
+	return time.Duration(float64(time.Second) / rate)
+}
+
+var ErrBatchUuidsProviderShutdown = errors.New("batch uuids provider is shutting down")
+
 type BatchUuidsProvider struct {
+	// UsernamesToUuids performs the request for a batch of usernames. Mojang's API is used when it's not set
+	UsernamesToUuids func(c context.Context, usernames []string) ([]*mojang.ProfileInfo, error)
+	// CircuitBreaker is optional. When it's open, the usernames aren't queued and fail right away
+	// with ErrCircuitBreakerOpen
+	CircuitBreaker *CircuitBreaker
+	// MaxRetries is the number of times the jobs are queued again after the transient failure of the request.
+	// Each job is queued again after the RetryBackoff delay. The jobs fail right away when it's zero
+	MaxRetries   int
+	RetryBackoff mojang.Backoff
+
 	context     context.Context
+	stop        context.CancelFunc
 	emitter     Emitter
 	strategy    BatchUuidsProviderStrategy
 	onFirstCall sync.Once
+
+	lock         sync.Mutex
+	shuttingDown bool
+	pendingJobs  sync.WaitGroup
 }
 
 func NewBatchUuidsProvider(
-	context context.Context,
+	ctx context.Context,
 	strategy BatchUuidsProviderStrategy,
 	emitter Emitter,
 ) *BatchUuidsProvider {
+	ctx, stop := context.WithCancel(ctx)
+
 	return &BatchUuidsProvider{
-		context:  context,
+		context:  ctx,
+		stop:     stop,
 		emitter:  emitter,
 		strategy: strategy,
 	}
 }
 
-func (ctx *BatchUuidsProvider) GetUuid(username string) (*mojang.ProfileInfo, error) {
+func (ctx *BatchUuidsProvider) GetUuid(c context.Context, username string) (*mojang.ProfileInfo, error) {
+	ctx.lock.Lock()
+	if ctx.shuttingDown {
+		ctx.lock.Unlock()
+		return nil, ErrBatchUuidsProviderShutdown
+	}
+
+	ctx.pendingJobs.Add(1)
+	ctx.lock.Unlock()
+	defer ctx.pendingJobs.Done()
+
+	if ctx.CircuitBreaker != nil {
+		if err := ctx.CircuitBreaker.Check(); err != nil {
+			return nil, err
+		}
+	}
+
 	ctx.onFirstCall.Do(ctx.startQueue)
 
-	resultChan := make(chan *jobResult)
-	ctx.strategy.Queue(&job{username, resultChan})
+	// The result may be sent after the caller has gone, so the chan is buffered to not block the queue
+	resultChan := make(chan *jobResult, 1)
+	ctx.strategy.Queue(&job{
+		Context:     c,
+		Username:    username,
+		RespondChan: resultChan,
+		Priority:    priorityFromContext(c),
+	})
 	ctx.emitter.Emit("mojang_textures:batch_uuids_provider:queued", username)
 
-	result := <-resultChan
+	select {
+	case result := <-resultChan:
+		return result.Profile, result.Error
+	case <-c.Done():
+		return nil, c.Err()
+	}
+}
+
+// Shutdown stops accepting new jobs and waits until all the already queued jobs will be processed.
+// When the passed context is done, the queue will be stopped without waiting for the rest of jobs
+func (ctx *BatchUuidsProvider) Shutdown(c context.Context) error {
+	ctx.lock.Lock()
+	ctx.shuttingDown = true
+	ctx.lock.Unlock()
+
+	defer ctx.stop()
 
-	return result.Profile, result.Error
+	done := make(chan struct{})
+	go func() {
+		ctx.pendingJobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-c.Done():
+		return c.Err()
+	case <-done:
+		return nil
+	}
 }
 
 func (ctx *BatchUuidsProvider) startQueue() {
@@ -206,7 +566,11 @@ func (ctx *BatchUuidsProvider) startQueue() {
 				return
 			case iteration := <-jobsChan:
 				go func() {
-					ctx.performRequest(iteration)
+					err := ctx.performRequest(iteration)
+					if strategy, ok := ctx.strategy.(ResultAwareStrategy); ok && len(iteration.Jobs) != 0 {
+						strategy.ReportResult(err)
+					}
+
 					iteration.Done()
 				}()
 			}
@@ -216,20 +580,24 @@ func (ctx *BatchUuidsProvider) startQueue() {
 	<-d
 }
 
-func (ctx *BatchUuidsProvider) performRequest(iteration *JobsIteration) {
+func (ctx *BatchUuidsProvider) performRequest(iteration *JobsIteration) error {
 	usernames := make([]string, len(iteration.Jobs))
 	for i, job := range iteration.Jobs {
 		usernames[i] = job.Username
 	}
 
-	ctx.emitter.Emit("mojang_textures:batch_uuids_provider:round", usernames, iteration.Queue)
+	ctx.emitter.Emit("mojang_textures:batch_uuids_provider:round", usernames, iteration.Queue, iteration.Lanes)
 	if len(usernames) == 0 {
-		return
+		return nil
 	}
 
-	profiles, err := usernamesToUuids(usernames)
+	profiles, err := ctx.fetch(usernames)
 	ctx.emitter.Emit("mojang_textures:batch_uuids_provider:result", usernames, profiles, err)
 	for _, job := range iteration.Jobs {
+		if err != nil && ctx.retry(job, err) {
+			continue
+		}
+
 		response := &jobResult{}
 		if err == nil {
 			// The profiles in the response aren't ordered, so we must search each username over full array
@@ -243,7 +611,49 @@ func (ctx *BatchUuidsProvider) performRequest(iteration *JobsIteration) {
 			response.Error = err
 		}
 
-		job.RespondChan <- response
-		close(job.RespondChan)
+		job.respond(response)
+	}
+
+	return err
+}
+
+// retry queues the job again after the delay. Returns false when the job must fail right away
+func (ctx *BatchUuidsProvider) retry(job *job, err error) bool {
+	if job.Retries >= ctx.MaxRetries || !mojang.IsTransientError(err) || job.isCanceled() || ctx.context.Err() != nil {
+		return false
+	}
+
+	job.Retries++
+	ctx.emitter.Emit("mojang_textures:batch_uuids_provider:retry", job.Username, job.Retries, err)
+	time.AfterFunc(ctx.RetryBackoff.Delay(job.Retries-1), func() {
+		// The queue isn't processed anymore, so the job can't wait for its turn
+		if ctx.context.Err() != nil {
+			job.respond(&jobResult{Error: err})
+			return
+		}
+
+		ctx.strategy.Queue(job)
+	})
+
+	return true
+}
+
+func (ctx *BatchUuidsProvider) fetch(usernames []string) ([]*mojang.ProfileInfo, error) {
+	fetch := ctx.UsernamesToUuids
+	if fetch == nil {
+		fetch = usernamesToUuids
+	}
+
+	if ctx.CircuitBreaker == nil {
+		return fetch(ctx.context, usernames)
 	}
+
+	if err := ctx.CircuitBreaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	profiles, err := fetch(ctx.context, usernames)
+	ctx.CircuitBreaker.Report(err)
+
+	return profiles, err
 }
@@ -2,6 +2,7 @@ package mojangtextures
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"sync"
@@ -18,9 +19,13 @@ import (
 func TestJobsQueue(t *testing.T) {
 	t.Run("Enqueue", func(t *testing.T) {
 		s := newJobsQueue()
-		require.Equal(t, 1, s.Enqueue(&job{Username: "username1"}))
-		require.Equal(t, 2, s.Enqueue(&job{Username: "username2"}))
-		require.Equal(t, 3, s.Enqueue(&job{Username: "username3"}))
+		n, isAdded := s.Enqueue(&job{Username: "username1"})
+		require.Equal(t, 1, n)
+		require.True(t, isAdded)
+		n, _ = s.Enqueue(&job{Username: "username2"})
+		require.Equal(t, 2, n)
+		n, _ = s.Enqueue(&job{Username: "username3"})
+		require.Equal(t, 3, n)
 	})
 
 	t.Run("Dequeue", func(t *testing.T) {
@@ -31,26 +36,119 @@ func TestJobsQueue(t *testing.T) {
 		s.Enqueue(&job{Username: "username4"})
 		s.Enqueue(&job{Username: "username5"})
 
-		items, queueLen := s.Dequeue(2)
+		items, lanes := s.Dequeue(2)
 		require.Len(t, items, 2)
-		require.Equal(t, 3, queueLen)
+		require.Equal(t, map[Priority]int{PriorityHigh: 3, PriorityLow: 0}, lanes)
 		require.Equal(t, "username1", items[0].Username)
 		require.Equal(t, "username2", items[1].Username)
 
-		items, queueLen = s.Dequeue(40)
+		items, lanes = s.Dequeue(40)
 		require.Len(t, items, 3)
-		require.Equal(t, 0, queueLen)
+		require.Equal(t, map[Priority]int{PriorityHigh: 0, PriorityLow: 0}, lanes)
 		require.Equal(t, "username3", items[0].Username)
 		require.Equal(t, "username4", items[1].Username)
 		require.Equal(t, "username5", items[2].Username)
 	})
+
+	t.Run("Dequeue should skip canceled jobs", func(t *testing.T) {
+		canceledCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s := newJobsQueue()
+		s.Enqueue(&job{Context: context.Background(), Username: "username1"})
+		s.Enqueue(&job{Context: canceledCtx, Username: "username2"})
+		s.Enqueue(&job{Context: context.Background(), Username: "username3"})
+		s.Enqueue(&job{Context: context.Background(), Username: "username4"})
+
+		items, lanes := s.Dequeue(2)
+		require.Len(t, items, 2)
+		require.Equal(t, map[Priority]int{PriorityHigh: 1, PriorityLow: 0}, lanes)
+		require.Equal(t, "username1", items[0].Username)
+		require.Equal(t, "username3", items[1].Username)
+	})
+
+	t.Run("Dequeue should fill the round from the high priority lane first", func(t *testing.T) {
+		s := newJobsQueue()
+		s.Enqueue(&job{Username: "background1", Priority: PriorityLow})
+		s.Enqueue(&job{Username: "background2", Priority: PriorityLow})
+		s.Enqueue(&job{Username: "interactive1", Priority: PriorityHigh})
+		n, _ := s.Enqueue(&job{Username: "interactive2"})
+		require.Equal(t, 4, n, "the job without priority is queued too")
+
+		items, lanes := s.Dequeue(3)
+		require.Len(t, items, 3)
+		require.Equal(t, "interactive1", items[0].Username)
+		require.Equal(t, "interactive2", items[1].Username)
+		require.Equal(t, "background1", items[2].Username)
+		require.Equal(t, map[Priority]int{PriorityHigh: 0, PriorityLow: 1}, lanes)
+	})
+
+	t.Run("Enqueue should merge the jobs for the same username", func(t *testing.T) {
+		s := newJobsQueue()
+		n, isAdded := s.Enqueue(&job{Username: "username", RespondChan: make(chan *jobResult, 1)})
+		require.Equal(t, 1, n)
+		require.True(t, isAdded)
+		n, isAdded = s.Enqueue(&job{Username: "USERNAME", RespondChan: make(chan *jobResult, 1)})
+		require.Equal(t, 1, n)
+		require.False(t, isAdded)
+		n, isAdded = s.Enqueue(&job{Username: "username2", RespondChan: make(chan *jobResult, 1)})
+		require.Equal(t, 2, n)
+		require.True(t, isAdded)
+
+		items, _ := s.Dequeue(10)
+		require.Len(t, items, 2)
+		require.Equal(t, "username", items[0].Username)
+		require.Len(t, items[0].duplicates, 1)
+
+		n, isAdded = s.Enqueue(&job{Username: "username"})
+		require.Equal(t, 1, n)
+		require.True(t, isAdded, "the dequeued username can be queued again")
+	})
+
+	t.Run("Enqueue should raise the priority of the merged job", func(t *testing.T) {
+		s := newJobsQueue()
+		s.Enqueue(&job{Username: "background", Priority: PriorityLow})
+		s.Enqueue(&job{Username: "interactive"})
+		s.Enqueue(&job{Username: "Background"})
+
+		items, lanes := s.Dequeue(10)
+		require.Len(t, items, 2)
+		require.Equal(t, "interactive", items[0].Username)
+		require.Equal(t, "background", items[1].Username)
+		require.Equal(t, PriorityHigh, items[1].Priority)
+		require.Equal(t, map[Priority]int{PriorityHigh: 0, PriorityLow: 0}, lanes)
+	})
+
+	t.Run("Dequeue should keep the merged job while any of its callers waits", func(t *testing.T) {
+		canceledCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s := newJobsQueue()
+		s.Enqueue(&job{Context: canceledCtx, Username: "username"})
+		s.Enqueue(&job{Context: context.Background(), Username: "username"})
+
+		items, _ := s.Dequeue(10)
+		require.Len(t, items, 1)
+	})
+}
+
+func TestJobRespond(t *testing.T) {
+	j := &job{RespondChan: make(chan *jobResult, 1)}
+	duplicate := &job{RespondChan: make(chan *jobResult, 1)}
+	j.merge(duplicate)
+
+	result := &jobResult{Profile: &mojang.ProfileInfo{Name: "username"}}
+	j.respond(result)
+
+	require.Same(t, result, <-j.RespondChan)
+	require.Same(t, result, <-duplicate.RespondChan)
 }
 
 type mojangUsernamesToUuidsRequestMock struct {
 	mock.Mock
 }
 
-func (o *mojangUsernamesToUuidsRequestMock) UsernamesToUuids(usernames []string) ([]*mojang.ProfileInfo, error) {
+func (o *mojangUsernamesToUuidsRequestMock) UsernamesToUuids(_ context.Context, usernames []string) ([]*mojang.ProfileInfo, error) {
 	args := o.Called(usernames)
 	var result []*mojang.ProfileInfo
 	if casted, ok := args.Get(0).([]*mojang.ProfileInfo); ok {
@@ -88,9 +186,19 @@ func (m *manualStrategy) Iterate(countJobsToReturn int, countLeftJobsInQueue int
 	m.ch <- &JobsIteration{
 		Jobs:  m.jobs[0:countJobsToReturn],
 		Queue: countLeftJobsInQueue,
+		Lanes: map[Priority]int{PriorityHigh: countLeftJobsInQueue},
 	}
 }
 
+type resultAwareManualStrategy struct {
+	*manualStrategy
+	results chan error
+}
+
+func (m *resultAwareManualStrategy) ReportResult(err error) {
+	m.results <- err
+}
+
 type batchUuidsProviderGetUuidResult struct {
 	Result *mojang.ProfileInfo
 	Error  error
@@ -122,7 +230,7 @@ func (suite *batchUuidsProviderTestSuite) GetUuidAsync(username string) <-chan *
 
 	c := make(chan *batchUuidsProviderGetUuidResult)
 	go func() {
-		profile, err := suite.Provider.GetUuid(username)
+		profile, err := suite.Provider.GetUuid(context.Background(), username)
 		c <- &batchUuidsProviderGetUuidResult{
 			Result: profile,
 			Error:  err,
@@ -161,7 +269,7 @@ func (suite *batchUuidsProviderTestSuite) TestGetUuidForFewUsernames() {
 	expectedResult2 := &mojang.ProfileInfo{Id: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", Name: "username2"}
 	expectedResponse := []*mojang.ProfileInfo{expectedResult1, expectedResult2}
 
-	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0).Once()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0, map[Priority]int{PriorityHigh: 0}).Once()
 	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, expectedResponse, nil).Once()
 
 	suite.MojangApi.On("UsernamesToUuids", expectedUsernames).Once().Return([]*mojang.ProfileInfo{
@@ -183,6 +291,25 @@ func (suite *batchUuidsProviderTestSuite) TestGetUuidForFewUsernames() {
 	suite.Assert().Nil(result2.Error)
 }
 
+func (suite *batchUuidsProviderTestSuite) TestGetUuidWithPriority() {
+	queued := make(chan struct{})
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:queued", "username").Once().Run(func(args mock.Arguments) {
+		close(queued)
+	})
+
+	ctx, cancel := context.WithCancel(WithPriority(context.Background(), PriorityLow))
+	go func() {
+		_, _ = suite.Provider.GetUuid(ctx, "username")
+	}()
+
+	<-queued
+	cancel()
+
+	suite.Strategy.lock.Lock()
+	defer suite.Strategy.lock.Unlock()
+	suite.Assert().Equal(PriorityLow, suite.Strategy.jobs[0].Priority)
+}
+
 func (suite *batchUuidsProviderTestSuite) TestShouldNotSendRequestWhenNoJobsAreReturned() {
 	//noinspection GoPreferNilSlice
 	emptyUsernames := []string{}
@@ -191,6 +318,7 @@ func (suite *batchUuidsProviderTestSuite) TestShouldNotSendRequestWhenNoJobsAreR
 		"mojang_textures:batch_uuids_provider:round",
 		emptyUsernames,
 		1,
+		map[Priority]int{PriorityHigh: 1},
 	).Once().Run(func(args mock.Arguments) {
 		close(done)
 	})
@@ -209,7 +337,7 @@ func (suite *batchUuidsProviderTestSuite) TestGetUuidForFewUsernamesWithAnError(
 	expectedError := &mojang.TooManyRequestsError{}
 	var nilProfilesResponse []*mojang.ProfileInfo
 
-	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0).Once()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0, map[Priority]int{PriorityHigh: 0}).Once()
 	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, nilProfilesResponse, expectedError).Once()
 
 	suite.MojangApi.On("UsernamesToUuids", expectedUsernames).Once().Return(nil, expectedError)
@@ -228,6 +356,214 @@ func (suite *batchUuidsProviderTestSuite) TestGetUuidForFewUsernamesWithAnError(
 	suite.Assert().Equal(expectedError, result2.Error)
 }
 
+func (suite *batchUuidsProviderTestSuite) TestGetUuidWithRetryAfterTransientError() {
+	expectedUsernames := []string{"username"}
+	expectedError := &mojang.ServerError{Status: 502}
+	expectedResult := &mojang.ProfileInfo{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	expectedResponse := []*mojang.ProfileInfo{expectedResult}
+	var nilProfilesResponse []*mojang.ProfileInfo
+
+	suite.Provider.MaxRetries = 1
+
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0, map[Priority]int{PriorityHigh: 0}).Twice()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, nilProfilesResponse, expectedError).Once()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:retry", "username", 1, expectedError).Once()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, expectedResponse, nil).Once()
+
+	suite.MojangApi.On("UsernamesToUuids", expectedUsernames).Once().Return(nil, expectedError)
+	suite.MojangApi.On("UsernamesToUuids", expectedUsernames).Once().Return(expectedResponse, nil)
+
+	resultChan := suite.GetUuidAsync("username")
+
+	suite.Strategy.Iterate(1, 0)
+	suite.Require().Eventually(func() bool {
+		suite.Strategy.lock.Lock()
+		defer suite.Strategy.lock.Unlock()
+
+		return len(suite.Strategy.jobs) == 2
+	}, time.Second, 5*time.Millisecond, "the job must be queued again")
+
+	suite.Strategy.Iterate(1, 0)
+
+	result := <-resultChan
+	suite.Assert().Equal(expectedResult, result.Result)
+	suite.Assert().Nil(result.Error)
+}
+
+func (suite *batchUuidsProviderTestSuite) TestGetUuidWhenRetriesAreExhausted() {
+	expectedUsernames := []string{"username"}
+	expectedError := &mojang.ServerError{Status: 502}
+	var nilProfilesResponse []*mojang.ProfileInfo
+
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0, map[Priority]int{PriorityHigh: 0}).Once()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, nilProfilesResponse, expectedError).Once()
+
+	suite.MojangApi.On("UsernamesToUuids", expectedUsernames).Once().Return(nil, expectedError)
+
+	resultChan := suite.GetUuidAsync("username")
+	suite.Strategy.jobs[0].Retries = 2
+	suite.Provider.MaxRetries = 2
+
+	suite.Strategy.Iterate(1, 0)
+
+	result := <-resultChan
+	suite.Assert().Nil(result.Result)
+	suite.Assert().Equal(expectedError, result.Error)
+}
+
+func (suite *batchUuidsProviderTestSuite) TestShouldReportResultToTheStrategy() {
+	expectedUsernames := []string{"username1"}
+	expectedError := &mojang.TooManyRequestsError{}
+	var nilProfilesResponse []*mojang.ProfileInfo
+
+	strategy := &resultAwareManualStrategy{suite.Strategy, make(chan error, 1)}
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	suite.Provider = NewBatchUuidsProvider(ctx, strategy, suite.Emitter)
+
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0, map[Priority]int{PriorityHigh: 0}).Once()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, nilProfilesResponse, expectedError).Once()
+
+	suite.MojangApi.On("UsernamesToUuids", expectedUsernames).Once().Return(nil, expectedError)
+
+	resultChan := suite.GetUuidAsync("username1")
+
+	suite.Strategy.Iterate(1, 0)
+
+	<-resultChan
+	suite.Assert().Equal(expectedError, <-strategy.results)
+}
+
+func (suite *batchUuidsProviderTestSuite) TestGetUuidWithCustomFetcher() {
+	expectedUsernames := []string{"username"}
+	expectedResult := &mojang.ProfileInfo{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	expectedResponse := []*mojang.ProfileInfo{expectedResult}
+
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0, map[Priority]int{PriorityHigh: 0}).Once()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, expectedResponse, nil).Once()
+
+	fetcher := &mojangUsernamesToUuidsRequestMock{}
+	fetcher.On("UsernamesToUuids", expectedUsernames).Once().Return(expectedResponse, nil)
+	suite.Provider.UsernamesToUuids = fetcher.UsernamesToUuids
+
+	resultChan := suite.GetUuidAsync("username")
+
+	suite.Strategy.Iterate(1, 0)
+
+	result := <-resultChan
+	suite.Assert().Equal(expectedResult, result.Result)
+	suite.Assert().Nil(result.Error)
+	fetcher.AssertExpectations(suite.T())
+}
+
+func (suite *batchUuidsProviderTestSuite) TestGetUuidForDuplicatedUsernames() {
+	expectedUsernames := []string{"Username", "other"}
+	expectedResult1 := &mojang.ProfileInfo{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	expectedResult2 := &mojang.ProfileInfo{Id: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", Name: "other"}
+	expectedResponse := []*mojang.ProfileInfo{expectedResult1, expectedResult2}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	suite.Provider = NewBatchUuidsProvider(ctx, NewPeriodicStrategy(50*time.Millisecond, 10), suite.Emitter)
+
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", mock.Anything, mock.Anything, mock.Anything)
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, expectedResponse, nil).Once()
+
+	// Each username must be requested only once, no matter how many callers are waiting for it
+	suite.MojangApi.On("UsernamesToUuids", expectedUsernames).Once().Return(expectedResponse, nil)
+
+	resultChan1 := suite.GetUuidAsync("Username")
+	resultChan2 := suite.GetUuidAsync("username")
+	resultChan3 := suite.GetUuidAsync("other")
+	resultChan4 := suite.GetUuidAsync("USERNAME")
+
+	for _, resultChan := range []<-chan *batchUuidsProviderGetUuidResult{resultChan1, resultChan2, resultChan4} {
+		result := <-resultChan
+		suite.Assert().Equal(expectedResult1, result.Result)
+		suite.Assert().Nil(result.Error)
+	}
+
+	result := <-resultChan3
+	suite.Assert().Equal(expectedResult2, result.Result)
+	suite.Assert().Nil(result.Error)
+}
+
+func (suite *batchUuidsProviderTestSuite) TestGetUuidWithOpenCircuitBreaker() {
+	expectedUsernames := []string{"username"}
+	expectedError := &mojang.ServerError{Status: 503}
+	var nilProfilesResponse []*mojang.ProfileInfo
+
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0, map[Priority]int{PriorityHigh: 0}).Once()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, nilProfilesResponse, expectedError).Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:circuit_breaker:state_changed",
+		"usernames",
+		CircuitBreakerClosed,
+		CircuitBreakerOpen,
+		expectedError,
+	).Once()
+
+	suite.MojangApi.On("UsernamesToUuids", expectedUsernames).Once().Return(nil, expectedError)
+	suite.Provider.CircuitBreaker = &CircuitBreaker{
+		Emitter:          suite.Emitter,
+		Name:             "usernames",
+		FailureThreshold: 1,
+		OpenDuration:     time.Minute,
+	}
+
+	resultChan := suite.GetUuidAsync("username")
+
+	suite.Strategy.Iterate(1, 0)
+
+	result := <-resultChan
+	suite.Assert().Equal(expectedError, result.Error)
+
+	// The username shouldn't be queued when the breaker is open
+	profile, err := suite.Provider.GetUuid(context.Background(), "username")
+	suite.Assert().Nil(profile)
+	suite.Assert().ErrorIs(err, ErrCircuitBreakerOpen)
+}
+
+func (suite *batchUuidsProviderTestSuite) TestShutdown() {
+	expectedUsernames := []string{"username"}
+	expectedResult := &mojang.ProfileInfo{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	expectedResponse := []*mojang.ProfileInfo{expectedResult}
+
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0, map[Priority]int{PriorityHigh: 0}).Once()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, expectedResponse, nil).Once()
+
+	suite.MojangApi.On("UsernamesToUuids", expectedUsernames).Once().Return(expectedResponse, nil)
+
+	resultChan := suite.GetUuidAsync("username")
+
+	shutdownResult := make(chan error)
+	go func() {
+		shutdownResult <- suite.Provider.Shutdown(context.Background())
+	}()
+
+	// Wait until the provider stops accepting new jobs
+	suite.Eventually(func() bool {
+		_, err := suite.Provider.GetUuid(context.Background(), "username2")
+		return errors.Is(err, ErrBatchUuidsProviderShutdown)
+	}, time.Second, time.Millisecond)
+
+	suite.Strategy.Iterate(1, 0)
+
+	result := <-resultChan
+	suite.Assert().Equal(expectedResult, result.Result)
+	suite.Assert().Nil(result.Error)
+	suite.Assert().NoError(<-shutdownResult)
+}
+
+func (suite *batchUuidsProviderTestSuite) TestShutdownWithDeadlineExceeded() {
+	suite.GetUuidAsync("username")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	suite.Assert().ErrorIs(suite.Provider.Shutdown(ctx), context.DeadlineExceeded)
+}
+
 func TestPeriodicStrategy(t *testing.T) {
 	t.Run("should return first job only after duration", func(t *testing.T) {
 		d := 20 * time.Millisecond
@@ -333,7 +669,7 @@ func TestFullBusStrategy(t *testing.T) {
 	t.Run("should provide iteration immediately when the batch size exceeded", func(t *testing.T) {
 		jobs := make([]*job, 10)
 		for i := 0; i < 10; i++ {
-			jobs[i] = &job{}
+			jobs[i] = &job{Username: strconv.Itoa(i)}
 		}
 
 		d := 20 * time.Millisecond
@@ -365,7 +701,7 @@ func TestFullBusStrategy(t *testing.T) {
 	t.Run("should provide iteration after duration if batch size isn't exceeded", func(t *testing.T) {
 		jobs := make([]*job, 9)
 		for i := 0; i < 9; i++ {
-			jobs[i] = &job{}
+			jobs[i] = &job{Username: strconv.Itoa(i)}
 		}
 
 		d := 20 * time.Millisecond
@@ -395,6 +731,35 @@ func TestFullBusStrategy(t *testing.T) {
 		cancel()
 	})
 
+	t.Run("shouldn't provide iteration when the job is merged into the full bus", func(t *testing.T) {
+		d := 20 * time.Millisecond
+		strategy := NewFullBusStrategy(d, 2)
+		// The bus is full, but its iteration hasn't been requested yet
+		strategy.queue.Enqueue(&job{Username: "username1"})
+		strategy.queue.Enqueue(&job{Username: "username2"})
+
+		queued := make(chan struct{})
+		go func() {
+			strategy.Queue(&job{Username: "USERNAME1"})
+			close(queued)
+		}()
+
+		select {
+		case <-queued:
+		case <-time.After(d):
+			require.Fail(t, "the merged job shouldn't report the full bus once again")
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		startedAt := time.Now()
+		iteration := <-strategy.GetJobs(ctx)
+		require.True(t, time.Now().Sub(startedAt) >= d)
+		require.Len(t, iteration.Jobs, 2)
+		require.Len(t, iteration.Jobs[0].duplicates, 1)
+	})
+
 	t.Run("should provide iteration as soon as the bus is full, without waiting for the previous iteration to finish", func(t *testing.T) {
 		d := 20 * time.Millisecond
 		strategy := NewFullBusStrategy(d, 10)
@@ -431,7 +796,7 @@ func TestFullBusStrategy(t *testing.T) {
 		}()
 
 		for i := 0; i < 31; i++ {
-			strategy.Queue(&job{})
+			strategy.Queue(&job{Username: strconv.Itoa(i)})
 		}
 
 		<-done
@@ -439,3 +804,127 @@ func TestFullBusStrategy(t *testing.T) {
 		cancel()
 	})
 }
+
+func TestAdaptiveStrategy(t *testing.T) {
+	t.Run("should return the job according to the max rate", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:batch_uuids_provider:rate", float64(50)).Once()
+
+		d := 20 * time.Millisecond
+		strategy := NewAdaptiveStrategy(emitter, 10, 50, 1, 1)
+		j := &job{}
+		strategy.Queue(j)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		startedAt := time.Now()
+		ch := strategy.GetJobs(ctx)
+		iteration := <-ch
+		durationBeforeResult := time.Now().Sub(startedAt)
+		require.True(t, durationBeforeResult >= d)
+		require.True(t, durationBeforeResult < d*2)
+
+		require.Equal(t, []*job{j}, iteration.Jobs)
+		require.Equal(t, 0, iteration.Queue)
+
+		cancel()
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should wait for the token after the job has been sent", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:batch_uuids_provider:rate", mock.Anything)
+
+		strategy := NewAdaptiveStrategy(emitter, 1, 50, 1, 1)
+		strategy.Queue(&job{Username: "username1"})
+		strategy.Queue(&job{Username: "username2"})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := strategy.GetJobs(ctx)
+		iteration := <-ch
+		iteration.Done()
+
+		startedAt := time.Now()
+		iteration = <-ch
+		require.Len(t, iteration.Jobs, 1)
+		require.True(t, time.Now().Sub(startedAt) >= 15*time.Millisecond)
+
+		cancel()
+	})
+
+	t.Run("should back off on too many requests error and ramp up on success", func(t *testing.T) {
+		now := time.Now()
+		adaptiveStrategyNow = func() time.Time {
+			return now
+		}
+		defer func() {
+			adaptiveStrategyNow = time.Now
+		}()
+
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:batch_uuids_provider:rate", float64(2)).Once()
+		emitter.On("Emit", "mojang_textures:batch_uuids_provider:rate", float64(1)).Once()
+		emitter.On("Emit", "mojang_textures:batch_uuids_provider:rate", float64(1.5)).Once()
+
+		strategy := NewAdaptiveStrategy(emitter, 10, 4, 1, 0.5)
+
+		strategy.ReportResult(&mojang.TooManyRequestsError{})
+		require.Equal(t, float64(2), strategy.Rate())
+		require.Equal(t, 500*time.Millisecond, strategy.delay())
+
+		strategy.ReportResult(&mojang.TooManyRequestsError{})
+		strategy.ReportResult(&mojang.TooManyRequestsError{})
+		require.Equal(t, float64(1), strategy.Rate(), "rate must not go below the min rate")
+
+		strategy.ReportResult(errors.New("some other error"))
+		require.Equal(t, float64(1), strategy.Rate(), "rate must not change on other errors")
+
+		strategy.ReportResult(nil)
+		require.Equal(t, 1.5, strategy.Rate())
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should not exceed the max rate", func(t *testing.T) {
+		emitter := &mockEmitter{}
+
+		strategy := NewAdaptiveStrategy(emitter, 10, 4, 1, 0.5)
+		strategy.ReportResult(nil)
+		require.Equal(t, float64(4), strategy.Rate())
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should honor the retry after delay", func(t *testing.T) {
+		now := time.Now()
+		adaptiveStrategyNow = func() time.Time {
+			return now
+		}
+		defer func() {
+			adaptiveStrategyNow = time.Now
+		}()
+
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:batch_uuids_provider:rate", float64(2)).Once()
+
+		strategy := NewAdaptiveStrategy(emitter, 10, 4, 1, 0.5)
+		strategy.ReportResult(&mojang.TooManyRequestsError{RetryAfter: time.Minute})
+		require.Equal(t, time.Minute, strategy.delay())
+		require.False(t, strategy.hasToken())
+
+		now = now.Add(time.Minute)
+		require.True(t, strategy.hasToken())
+
+		emitter.AssertExpectations(t)
+	})
+}
+
+func (suite *batchUuidsProviderTestSuite) TestGetUuidShouldReturnWhenContextIsCanceled() {
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:queued", "username").Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := suite.Provider.GetUuid(ctx, "username")
+	suite.Assert().Nil(result)
+	suite.Assert().ErrorIs(err, context.Canceled)
+}
@@ -0,0 +1,206 @@
+package mojangtextures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elyby/chrly/api/mojang"
+)
+
+var circuitBreakerNow = time.Now
+
+var ErrCircuitBreakerOpen = errors.New("the circuit breaker is open")
+
+type CircuitBreakerState string
+
+const (
+	CircuitBreakerClosed   CircuitBreakerState = "closed"
+	CircuitBreakerOpen     CircuitBreakerState = "open"
+	CircuitBreakerHalfOpen CircuitBreakerState = "half-open"
+)
+
+// CircuitBreaker rejects the requests to the Mojang's API right away after several consecutive failures,
+// so the callers don't wait for the requests, which will most likely fail with the timeout anyway.
+// After the OpenDuration a single probe request is allowed and its result decides whether to close the breaker
+type CircuitBreaker struct {
+	Emitter
+	// Name is used to distinguish the breakers in the emitted events
+	Name string
+	// The number of consecutive failures after which the breaker opens
+	FailureThreshold int
+	OpenDuration     time.Duration
+
+	lock          sync.Mutex
+	state         CircuitBreakerState
+	failures      int
+	openedAt      time.Time
+	probeInFlight bool
+	lastError     error
+}
+
+// Allow returns ErrCircuitBreakerOpen when the request must not be performed.
+// Each allowed request must be followed by the Report call with its result
+func (b *CircuitBreaker) Allow() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if !b.isAllowed() {
+		return ErrCircuitBreakerOpen
+	}
+
+	if b.state == CircuitBreakerOpen {
+		b.setState(CircuitBreakerHalfOpen)
+	}
+
+	if b.state == CircuitBreakerHalfOpen {
+		b.probeInFlight = true
+	}
+
+	return nil
+}
+
+// Check is the same as Allow, but it doesn't reserve the probe request, so Report must not be called after it
+func (b *CircuitBreaker) Check() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if !b.isAllowed() {
+		return ErrCircuitBreakerOpen
+	}
+
+	return nil
+}
+
+// Report registers the result of the allowed request
+func (b *CircuitBreaker) Report(err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	isProbe := b.state == CircuitBreakerHalfOpen && b.probeInFlight
+	if isProbe {
+		b.probeInFlight = false
+	}
+
+	if !isMojangApiResult(err) {
+		return
+	}
+
+	if !isCircuitBreakerFailure(err) {
+		b.failures = 0
+		if isProbe {
+			b.lastError = nil
+			b.setState(CircuitBreakerClosed)
+		}
+
+		return
+	}
+
+	b.lastError = err
+	switch b.state {
+	case CircuitBreakerHalfOpen:
+		if isProbe {
+			b.open()
+		}
+	case CircuitBreakerOpen:
+		// The result of the request, which has been started before the breaker was opened
+	default:
+		b.failures++
+		if b.failures >= b.FailureThreshold {
+			b.open()
+		}
+	}
+}
+
+// Observe registers the result of the request, which has been performed by another replica.
+// It isn't preceded by the Allow call, so the success closes the breaker right away
+func (b *CircuitBreaker) Observe(err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if !isMojangApiResult(err) || errors.Is(err, ErrCircuitBreakerOpen) {
+		return
+	}
+
+	if !isCircuitBreakerFailure(err) {
+		b.failures = 0
+		if b.state == CircuitBreakerOpen || b.state == CircuitBreakerHalfOpen {
+			b.lastError = nil
+			b.probeInFlight = false
+			b.setState(CircuitBreakerClosed)
+		}
+
+		return
+	}
+
+	b.lastError = err
+	if b.state == CircuitBreakerOpen || b.state == CircuitBreakerHalfOpen {
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.FailureThreshold {
+		b.open()
+	}
+}
+
+// Status returns nil when the breaker is closed or the error, which has opened it
+func (b *CircuitBreaker) Status() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case CircuitBreakerOpen:
+		return fmt.Errorf("open until %s: %w", b.openedAt.Add(b.OpenDuration).Format(time.RFC3339), b.lastError)
+	case CircuitBreakerHalfOpen:
+		return fmt.Errorf("half-open: %w", b.lastError)
+	default:
+		return nil
+	}
+}
+
+func (b *CircuitBreaker) isAllowed() bool {
+	switch b.state {
+	case CircuitBreakerOpen:
+		return !circuitBreakerNow().Before(b.openedAt.Add(b.OpenDuration))
+	case CircuitBreakerHalfOpen:
+		return !b.probeInFlight
+	default:
+		return true
+	}
+}
+
+func (b *CircuitBreaker) open() {
+	b.failures = 0
+	b.openedAt = circuitBreakerNow()
+	b.setState(CircuitBreakerOpen)
+}
+
+func (b *CircuitBreaker) setState(state CircuitBreakerState) {
+	prevState := b.state
+	if prevState == "" {
+		prevState = CircuitBreakerClosed
+	}
+
+	b.state = state
+	if prevState != state {
+		b.Emit("mojang_textures:circuit_breaker:state_changed", b.Name, prevState, state, b.lastError)
+	}
+}
+
+// The requests, which have failed with these errors, haven't reached the Mojang's API,
+// so they say nothing about its state
+func isMojangApiResult(err error) bool {
+	return !errors.Is(err, context.Canceled) &&
+		!errors.Is(err, ErrRateLimitExceeded) &&
+		!errors.Is(err, ErrNoAvailableRemoteApiWorkers) &&
+		!errors.Is(err, mojang.ErrNoAvailableProxies)
+}
+
+// Only the server errors and the network failures are counted. Other errors mean that the Mojang's API
+// is alive and responds, even if it has rejected the request
+func isCircuitBreakerFailure(err error) bool {
+	return mojang.IsTransientError(err)
+}
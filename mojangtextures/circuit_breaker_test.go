@@ -0,0 +1,200 @@
+package mojangtextures
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elyby/chrly/api/mojang"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	circuitBreakerNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		circuitBreakerNow = time.Now
+	}()
+
+	serverErr := &mojang.ServerError{Status: 502}
+
+	createBreaker := func(emitter *mockEmitter) *CircuitBreaker {
+		return &CircuitBreaker{
+			Emitter:          emitter,
+			Name:             "textures",
+			FailureThreshold: 2,
+			OpenDuration:     time.Minute,
+		}
+	}
+
+	openBreaker := func(b *CircuitBreaker) {
+		for i := 0; i < b.FailureThreshold; i++ {
+			require.NoError(t, b.Allow())
+			b.Report(serverErr)
+		}
+	}
+
+	t.Run("should open after the consecutive failures", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerClosed, CircuitBreakerOpen, serverErr).Once()
+		b := createBreaker(emitter)
+
+		require.NoError(t, b.Allow())
+		b.Report(serverErr)
+		require.NoError(t, b.Allow())
+		b.Report(nil)
+		require.NoError(t, b.Allow())
+		b.Report(serverErr)
+		require.NoError(t, b.Status(), "the success should reset the failures counter")
+
+		require.NoError(t, b.Allow())
+		b.Report(serverErr)
+
+		require.ErrorIs(t, b.Allow(), ErrCircuitBreakerOpen)
+		require.ErrorIs(t, b.Check(), ErrCircuitBreakerOpen)
+		require.ErrorIs(t, b.Status(), serverErr)
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should not count not related to the Mojang's availability errors", func(t *testing.T) {
+		b := createBreaker(&mockEmitter{})
+
+		for _, err := range []error{
+			&mojang.TooManyRequestsError{},
+			&mojang.EmptyResponse{},
+			ErrRateLimitExceeded,
+			ErrNoAvailableRemoteApiWorkers,
+			&url.Error{Op: "Get", URL: "https://sessionserver.mojang.com", Err: mojang.ErrNoAvailableProxies},
+			context.Canceled,
+		} {
+			require.NoError(t, b.Allow())
+			b.Report(err)
+		}
+
+		require.NoError(t, b.Status())
+	})
+
+	t.Run("should count the timeouts", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerClosed, CircuitBreakerOpen, mock.Anything).Once()
+		b := createBreaker(emitter)
+
+		require.NoError(t, b.Allow())
+		b.Report(context.DeadlineExceeded)
+		require.NoError(t, b.Allow())
+		b.Report(&timeoutError{})
+
+		require.ErrorIs(t, b.Allow(), ErrCircuitBreakerOpen)
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should allow a single probe after the open duration and close on its success", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerClosed, CircuitBreakerOpen, serverErr).Once()
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerOpen, CircuitBreakerHalfOpen, serverErr).Once()
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerHalfOpen, CircuitBreakerClosed, nil).Once()
+		b := createBreaker(emitter)
+		openBreaker(b)
+
+		now = now.Add(time.Minute)
+		require.NoError(t, b.Check(), "check shouldn't take the probe")
+		require.NoError(t, b.Allow())
+		require.ErrorIs(t, b.Allow(), ErrCircuitBreakerOpen, "only one probe is allowed")
+		require.ErrorIs(t, b.Check(), ErrCircuitBreakerOpen)
+		require.Error(t, b.Status())
+
+		b.Report(nil)
+
+		require.NoError(t, b.Allow())
+		require.NoError(t, b.Status())
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should open again on the probe failure", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerClosed, CircuitBreakerOpen, serverErr).Once()
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerOpen, CircuitBreakerHalfOpen, serverErr).Once()
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerHalfOpen, CircuitBreakerOpen, serverErr).Once()
+		b := createBreaker(emitter)
+		openBreaker(b)
+
+		now = now.Add(time.Minute)
+		require.NoError(t, b.Allow())
+		b.Report(serverErr)
+
+		require.ErrorIs(t, b.Allow(), ErrCircuitBreakerOpen)
+		now = now.Add(30 * time.Second)
+		require.ErrorIs(t, b.Allow(), ErrCircuitBreakerOpen, "the open duration should be counted from the probe failure")
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should release the probe, which hasn't reached the Mojang's API", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", mock.Anything, mock.Anything, mock.Anything)
+		b := createBreaker(emitter)
+		openBreaker(b)
+
+		now = now.Add(time.Minute)
+		require.NoError(t, b.Allow())
+		b.Report(ErrRateLimitExceeded)
+
+		require.NoError(t, b.Allow(), "the next probe should be allowed")
+		require.Error(t, b.Status(), "the breaker should be still half-open")
+	})
+
+	t.Run("should ignore the results of the requests, which have been started before the breaker was opened", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerClosed, CircuitBreakerOpen, serverErr).Once()
+		b := createBreaker(emitter)
+		require.NoError(t, b.Allow())
+		openBreaker(b)
+
+		b.Report(nil)
+
+		require.ErrorIs(t, b.Allow(), ErrCircuitBreakerOpen)
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should open after the consecutive failures observed on another replica", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerClosed, CircuitBreakerOpen, serverErr).Once()
+		b := createBreaker(emitter)
+
+		b.Observe(serverErr)
+		b.Observe(ErrCircuitBreakerOpen)
+		b.Observe(context.Canceled)
+		require.NoError(t, b.Check(), "the errors, which haven't reached the Mojang's API, shouldn't be counted")
+
+		b.Observe(serverErr)
+		require.ErrorIs(t, b.Check(), ErrCircuitBreakerOpen)
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should close on the success observed on another replica", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerClosed, CircuitBreakerOpen, serverErr).Once()
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "textures", CircuitBreakerOpen, CircuitBreakerClosed, nil).Once()
+		b := createBreaker(emitter)
+		openBreaker(b)
+
+		b.Observe(serverErr)
+		require.ErrorIs(t, b.Check(), ErrCircuitBreakerOpen)
+
+		b.Observe(nil)
+		require.NoError(t, b.Allow())
+		require.NoError(t, b.Status())
+		emitter.AssertExpectations(t)
+	})
+}
+
+type timeoutError struct{}
+
+func (*timeoutError) Error() string   { return "timeout error" }
+func (*timeoutError) Timeout() bool   { return true }
+func (*timeoutError) Temporary() bool { return false }
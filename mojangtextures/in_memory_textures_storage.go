@@ -15,31 +15,56 @@ type inMemoryItem struct {
 
 type InMemoryTexturesStorage struct {
 	GCPeriod time.Duration
+	// Duration during which the stored textures are considered fresh
 	Duration time.Duration
-
-	once sync.Once
-	lock sync.RWMutex
-	data map[string]*inMemoryItem
+	// Duration during which the stored absence of the textures is considered fresh
+	NilDuration time.Duration
+	// Duration after the expiration of the textures during which they are still kept and returned as stale values
+	StaleDuration time.Duration
+
+	once     sync.Once
+	stopOnce sync.Once
+	lock     sync.RWMutex
+	data     map[string]*inMemoryItem
+	// done is assigned only within the once, so it's safe to read after the once is done
 	done chan struct{}
 }
 
 func NewInMemoryTexturesStorage() *InMemoryTexturesStorage {
 	storage := &InMemoryTexturesStorage{
-		GCPeriod: 10 * time.Second,
-		Duration: time.Minute + 10*time.Second,
-		data:     make(map[string]*inMemoryItem),
+		GCPeriod:    10 * time.Second,
+		Duration:    time.Minute + 10*time.Second,
+		NilDuration: time.Minute + 10*time.Second,
+		data:        make(map[string]*inMemoryItem),
 	}
 
 	return storage
 }
 
 func (s *InMemoryTexturesStorage) GetTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
+	textures, _, err := s.LookupTextures(uuid)
+
+	return textures, err
+}
+
+func (s *InMemoryTexturesStorage) LookupTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	item, exists := s.data[uuid]
+	if !exists || s.isExpired(item, 0) {
+		return nil, false, nil
+	}
+
+	return item.textures, true, nil
+}
+
+func (s *InMemoryTexturesStorage) GetStaleTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
 	item, exists := s.data[uuid]
-	validRange := s.getMinimalNotExpiredTimestamp()
-	if !exists || validRange > item.timestamp {
+	if !exists || !s.isExpired(item, 0) || s.isExpired(item, s.StaleDuration) {
 		return nil, nil
 	}
 
@@ -58,6 +83,13 @@ func (s *InMemoryTexturesStorage) StoreTextures(uuid string, textures *mojang.Si
 	}
 }
 
+func (s *InMemoryTexturesStorage) RemoveTextures(uuid string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.data, uuid)
+}
+
 func (s *InMemoryTexturesStorage) start() {
 	s.done = make(chan struct{})
 	ticker := time.NewTicker(s.GCPeriod)
@@ -65,6 +97,7 @@ func (s *InMemoryTexturesStorage) start() {
 		for {
 			select {
 			case <-s.done:
+				ticker.Stop()
 				return
 			case <-ticker.C:
 				s.gc()
@@ -73,22 +106,34 @@ func (s *InMemoryTexturesStorage) start() {
 	}()
 }
 
+// Stop terminates the GC goroutine. The storage can still be used after that, but expired items won't be removed
 func (s *InMemoryTexturesStorage) Stop() {
-	close(s.done)
+	// Prevent the GC from starting if it hasn't been started yet
+	s.once.Do(func() {})
+	s.stopOnce.Do(func() {
+		if s.done != nil {
+			close(s.done)
+		}
+	})
 }
 
 func (s *InMemoryTexturesStorage) gc() {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	maxTime := s.getMinimalNotExpiredTimestamp()
 	for uuid, value := range s.data {
-		if maxTime > value.timestamp {
+		if s.isExpired(value, s.StaleDuration) {
 			delete(s.data, uuid)
 		}
 	}
 }
 
-func (s *InMemoryTexturesStorage) getMinimalNotExpiredTimestamp() int64 {
-	return utils.UnixMillisecond(time.Now().Add(s.Duration * time.Duration(-1)))
+// The extra duration allows to check the expiration of the stale window
+func (s *InMemoryTexturesStorage) isExpired(item *inMemoryItem, extra time.Duration) bool {
+	duration := s.Duration
+	if item.textures == nil {
+		duration = s.NilDuration
+	}
+
+	return utils.UnixMillisecond(time.Now().Add(-(duration + extra))) > item.timestamp
 }
@@ -1,6 +1,7 @@
 package mojangtextures
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -75,6 +76,76 @@ func TestInMemoryTexturesStorage_GetTextures(t *testing.T) {
 		assert.Nil(t, result)
 		assert.Nil(t, err)
 	})
+
+	t.Run("should expire the absence of the textures by its own duration", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage()
+		storage.NilDuration = 10 * time.Millisecond
+		storage.GCPeriod = time.Minute
+		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", nil)
+		storage.StoreTextures("b5d58475007d4f9e9ddd1403e2497579", texturesWithSkin)
+
+		time.Sleep(storage.NilDuration * 2)
+
+		storage.lock.RLock()
+		assert.True(t, storage.isExpired(storage.data["dead24f9a4fa4877b7b04c8c6c72bb46"], 0))
+		assert.False(t, storage.isExpired(storage.data["b5d58475007d4f9e9ddd1403e2497579"], 0))
+		storage.lock.RUnlock()
+
+		result, err := storage.GetTextures("b5d58475007d4f9e9ddd1403e2497579")
+		assert.Equal(t, texturesWithSkin, result)
+		assert.Nil(t, err)
+	})
+}
+
+func TestInMemoryTexturesStorage_GetStaleTextures(t *testing.T) {
+	t.Run("should return nil, nil when textures are unavailable", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage()
+		storage.StaleDuration = time.Minute
+		result, err := storage.GetStaleTextures("b5d58475007d4f9e9ddd1403e2497579")
+
+		assert.Nil(t, result)
+		assert.Nil(t, err)
+	})
+
+	t.Run("should return nil, nil when textures are still fresh", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage()
+		storage.StaleDuration = time.Minute
+		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+		result, err := storage.GetStaleTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+
+		assert.Nil(t, result)
+		assert.Nil(t, err)
+	})
+
+	t.Run("get textures object, when its cache duration is expired, but it's within the stale window", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage()
+		storage.Duration = 10 * time.Millisecond
+		storage.StaleDuration = time.Minute
+		storage.GCPeriod = time.Minute
+		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+
+		time.Sleep(storage.Duration * 2)
+
+		result, err := storage.GetStaleTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+
+		assert.Equal(t, texturesWithSkin, result)
+		assert.Nil(t, err)
+	})
+
+	t.Run("should return nil, nil when the stale window is expired", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage()
+		storage.Duration = 10 * time.Millisecond
+		storage.StaleDuration = 10 * time.Millisecond
+		storage.GCPeriod = time.Minute
+		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+
+		time.Sleep((storage.Duration + storage.StaleDuration) * 2)
+
+		result, err := storage.GetStaleTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+
+		assert.Nil(t, result)
+		assert.Nil(t, err)
+	})
 }
 
 func TestInMemoryTexturesStorage_StoreTextures(t *testing.T) {
@@ -123,11 +194,22 @@ func TestInMemoryTexturesStorage_StoreTextures(t *testing.T) {
 	})
 }
 
+func TestInMemoryTexturesStorage_RemoveTextures(t *testing.T) {
+	storage := NewInMemoryTexturesStorage()
+	storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+	storage.RemoveTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+	result, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+
+	assert.Nil(t, result)
+	assert.Nil(t, err)
+}
+
 func TestInMemoryTexturesStorage_GarbageCollection(t *testing.T) {
 	storage := NewInMemoryTexturesStorage()
 	defer storage.Stop()
 	storage.GCPeriod = 10 * time.Millisecond
 	storage.Duration = 9 * time.Millisecond
+	storage.NilDuration = 9 * time.Millisecond
 
 	textures1 := &mojang.SignedTexturesResponse{
 		Id:    "dead24f9a4fa4877b7b04c8c6c72bb46",
@@ -162,3 +244,37 @@ func TestInMemoryTexturesStorage_GarbageCollection(t *testing.T) {
 	assert.Len(t, storage.data, 0)
 	storage.lock.RUnlock()
 }
+
+func TestInMemoryTexturesStorage_Stop(t *testing.T) {
+	t.Run("stop not started storage", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage()
+		assert.NotPanics(t, storage.Stop)
+
+		// The storage is still usable, but the GC won't be started
+		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", nil)
+		assert.Nil(t, storage.done)
+	})
+
+	t.Run("stop started storage twice", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage()
+		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", nil)
+
+		assert.NotPanics(t, storage.Stop)
+		assert.NotPanics(t, storage.Stop)
+	})
+
+	t.Run("stop concurrently", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage()
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", nil)
+				storage.Stop()
+			}()
+		}
+
+		wg.Wait()
+	})
+}
@@ -1,6 +1,9 @@
 package mojangtextures
 
 import (
+	"context"
+	"time"
+
 	"github.com/elyby/chrly/api/mojang"
 )
 
@@ -8,12 +11,57 @@ var uuidToTextures = mojang.UuidToTextures
 
 type MojangApiTexturesProvider struct {
 	Emitter
+	// UuidToTextures performs the request for the textures. Mojang's API is used when it's not set
+	UuidToTextures func(c context.Context, uuid string, signed bool) (*mojang.SignedTexturesResponse, error)
+	// RateLimiter is optional. When it's set, each request waits for its slot no longer than MaxWait
+	RateLimiter RateLimiter
+	MaxWait     time.Duration
+	// CircuitBreaker is optional. When it's open, the requests fail right away with ErrCircuitBreakerOpen
+	CircuitBreaker *CircuitBreaker
+}
+
+func (ctx *MojangApiTexturesProvider) GetTextures(c context.Context, uuid string) (*mojang.SignedTexturesResponse, error) {
+	if ctx.CircuitBreaker == nil {
+		return ctx.requestTextures(c, uuid)
+	}
+
+	if err := ctx.CircuitBreaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	result, err := ctx.requestTextures(c, uuid)
+	ctx.CircuitBreaker.Report(err)
+
+	return result, err
 }
 
-func (ctx *MojangApiTexturesProvider) GetTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
+func (ctx *MojangApiTexturesProvider) requestTextures(c context.Context, uuid string) (*mojang.SignedTexturesResponse, error) {
+	if ctx.RateLimiter != nil {
+		err := ctx.waitForRateLimit(c, uuid)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	ctx.Emit("mojang_textures:mojang_api_textures_provider:before_request", uuid)
-	result, err := uuidToTextures(uuid, true)
+	fetch := ctx.UuidToTextures
+	if fetch == nil {
+		fetch = uuidToTextures
+	}
+
+	result, err := fetch(c, uuid, true)
 	ctx.Emit("mojang_textures:mojang_api_textures_provider:after_request", uuid, result, err)
 
 	return result, err
 }
+
+func (ctx *MojangApiTexturesProvider) waitForRateLimit(c context.Context, uuid string) error {
+	c, cancel := context.WithTimeout(c, ctx.MaxWait)
+	defer cancel()
+
+	ctx.Emit("mojang_textures:mojang_api_textures_provider:before_wait", uuid)
+	err := waitForRateLimit(c, ctx.RateLimiter)
+	ctx.Emit("mojang_textures:mojang_api_textures_provider:after_wait", uuid, err)
+
+	return err
+}
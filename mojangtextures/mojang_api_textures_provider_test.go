@@ -1,7 +1,9 @@
 package mojangtextures
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -13,7 +15,7 @@ type mojangUuidToTexturesRequestMock struct {
 	mock.Mock
 }
 
-func (o *mojangUuidToTexturesRequestMock) UuidToTextures(uuid string, signed bool) (*mojang.SignedTexturesResponse, error) {
+func (o *mojangUuidToTexturesRequestMock) UuidToTextures(_ context.Context, uuid string, signed bool) (*mojang.SignedTexturesResponse, error) {
 	args := o.Called(uuid, signed)
 	var result *mojang.SignedTexturesResponse
 	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
@@ -69,12 +71,31 @@ func (suite *mojangApiTexturesProviderTestSuite) TestGetTextures() {
 		nil,
 	).Once()
 
-	result, err := suite.Provider.GetTextures("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	result, err := suite.Provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
 
 	suite.Assert().Equal(expectedResult, result)
 	suite.Assert().Nil(err)
 }
 
+func (suite *mojangApiTexturesProviderTestSuite) TestGetTexturesWithCustomFetcher() {
+	expectedResult := &mojang.SignedTexturesResponse{
+		Id:   "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		Name: "username",
+	}
+	fetcher := &mojangUuidToTexturesRequestMock{}
+	fetcher.On("UuidToTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true).Once().Return(expectedResult, nil)
+	suite.Provider.UuidToTextures = fetcher.UuidToTextures
+
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:before_request", mock.Anything).Once()
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:after_request", mock.Anything, mock.Anything, mock.Anything).Once()
+
+	result, err := suite.Provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+
+	suite.Assert().Equal(expectedResult, result)
+	suite.Assert().Nil(err)
+	fetcher.AssertExpectations(suite.T())
+}
+
 func (suite *mojangApiTexturesProviderTestSuite) TestGetTexturesWithError() {
 	var expectedResponse *mojang.SignedTexturesResponse
 	expectedError := &mojang.TooManyRequestsError{}
@@ -91,8 +112,86 @@ func (suite *mojangApiTexturesProviderTestSuite) TestGetTexturesWithError() {
 		expectedError,
 	).Once()
 
-	result, err := suite.Provider.GetTextures("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	result, err := suite.Provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
 
 	suite.Assert().Nil(result)
 	suite.Assert().Equal(expectedError, err)
 }
+
+func (suite *mojangApiTexturesProviderTestSuite) TestGetTexturesWithRateLimiter() {
+	expectedResult := &mojang.SignedTexturesResponse{
+		Id:   "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		Name: "username",
+	}
+	suite.MojangApi.On("UuidToTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true).Twice().Return(expectedResult, nil)
+
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:before_wait", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Twice()
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:after_wait", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil).Twice()
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:before_request", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Twice()
+	suite.Emitter.On("Emit",
+		"mojang_textures:mojang_api_textures_provider:after_request",
+		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		expectedResult,
+		nil,
+	).Twice()
+
+	suite.Provider.RateLimiter = &LocalRateLimiter{Interval: 20 * time.Millisecond}
+	suite.Provider.MaxWait = time.Second
+
+	startedAt := time.Now()
+	_, err := suite.Provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	suite.Require().Nil(err)
+	_, err = suite.Provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	suite.Require().Nil(err)
+
+	suite.Assert().True(time.Since(startedAt) >= 20*time.Millisecond, "second request must wait for the next slot")
+}
+
+func (suite *mojangApiTexturesProviderTestSuite) TestGetTexturesWhenRateLimitExceeded() {
+	suite.MojangApi.On("UuidToTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true).Once().Return(nil, nil)
+
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:before_wait", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Twice()
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:after_wait", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:after_wait", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", ErrRateLimitExceeded).Once()
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:before_request", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:after_request", mock.Anything, mock.Anything, mock.Anything).Once()
+
+	suite.Provider.RateLimiter = &LocalRateLimiter{Interval: time.Minute}
+	suite.Provider.MaxWait = time.Second
+
+	_, err := suite.Provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	suite.Require().Nil(err)
+
+	result, err := suite.Provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	suite.Assert().Nil(result)
+	suite.Assert().Equal(ErrRateLimitExceeded, err)
+}
+
+func (suite *mojangApiTexturesProviderTestSuite) TestGetTexturesWithOpenCircuitBreaker() {
+	expectedError := &mojang.ServerError{Status: 502}
+	suite.MojangApi.On("UuidToTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true).Once().Return(nil, expectedError)
+
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:before_request", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:mojang_api_textures_provider:after_request", mock.Anything, mock.Anything, mock.Anything).Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:circuit_breaker:state_changed",
+		"textures",
+		CircuitBreakerClosed,
+		CircuitBreakerOpen,
+		expectedError,
+	).Once()
+
+	suite.Provider.CircuitBreaker = &CircuitBreaker{
+		Emitter:          suite.Emitter,
+		Name:             "textures",
+		FailureThreshold: 1,
+		OpenDuration:     time.Minute,
+	}
+
+	_, err := suite.Provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	suite.Require().Equal(expectedError, err)
+
+	result, err := suite.Provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	suite.Assert().Nil(result)
+	suite.Assert().ErrorIs(err, ErrCircuitBreakerOpen)
+}
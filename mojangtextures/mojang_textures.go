@@ -1,6 +1,7 @@
 package mojangtextures
 
 import (
+	"context"
 	"regexp"
 	"strings"
 	"sync"
@@ -14,34 +15,49 @@ type broadcastResult struct {
 	error    error
 }
 
+type broadcastListeners struct {
+	channels []chan *broadcastResult
+	context  context.Context
+	cancel   context.CancelFunc
+}
+
 type broadcaster struct {
 	lock      sync.Mutex
-	listeners map[string][]chan *broadcastResult
+	listeners map[string]*broadcastListeners
 }
 
 func createBroadcaster() *broadcaster {
 	return &broadcaster{
-		listeners: make(map[string][]chan *broadcastResult),
+		listeners: make(map[string]*broadcastListeners),
 	}
 }
 
-// Returns a boolean value, which will be true if the passed username didn't exist before
-func (c *broadcaster) AddListener(username string, resultChan chan *broadcastResult) bool {
+// Returns the context for the shared request and a boolean value, which will be true if the passed username
+// didn't exist before. The shared context keeps the values of the first listener's context, but it's canceled
+// only when all the listeners have gone
+func (c *broadcaster) AddListener(ctx context.Context, username string, resultChan chan *broadcastResult) (context.Context, bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	val, alreadyHasSource := c.listeners[username]
 	if alreadyHasSource {
-		c.listeners[username] = append(val, resultChan)
-		return false
+		val.channels = append(val.channels, resultChan)
+		return val.context, false
 	}
 
-	c.listeners[username] = []chan *broadcastResult{resultChan}
+	sharedCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c.listeners[username] = &broadcastListeners{
+		channels: []chan *broadcastResult{resultChan},
+		context:  sharedCtx,
+		cancel:   cancel,
+	}
 
-	return true
+	return sharedCtx, true
 }
 
-func (c *broadcaster) BroadcastAndRemove(username string, result *broadcastResult) {
+// RemoveListener should be called when the listener doesn't wait for the result anymore.
+// When there are no listeners left, the shared request is canceled
+func (c *broadcaster) RemoveListener(username string, resultChan chan *broadcastResult) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
@@ -50,13 +66,38 @@ func (c *broadcaster) BroadcastAndRemove(username string, result *broadcastResul
 		return
 	}
 
-	for _, channel := range val {
+	for i, channel := range val.channels {
+		if channel == resultChan {
+			val.channels = append(val.channels[:i], val.channels[i+1:]...)
+			break
+		}
+	}
+
+	if len(val.channels) == 0 {
+		val.cancel()
+		delete(c.listeners, username)
+	}
+}
+
+// BroadcastAndRemove sends the result to the listeners of the shared request, identified by its context.
+// The listeners of the newer request for the same username (if the previous one was canceled) are kept
+func (c *broadcaster) BroadcastAndRemove(ctx context.Context, username string, result *broadcastResult) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	val, ok := c.listeners[username]
+	if !ok || val.context != ctx {
+		return
+	}
+
+	for _, channel := range val.channels {
 		go func(channel chan *broadcastResult) {
 			channel <- result
 			close(channel)
 		}(channel)
 	}
 
+	val.cancel()
 	delete(c.listeners, username)
 }
 
@@ -64,11 +105,11 @@ func (c *broadcaster) BroadcastAndRemove(username string, result *broadcastResul
 var allowedUsernamesRegex = regexp.MustCompile(`(?i)^[0-9a-z_]{3,16}$`)
 
 type UUIDsProvider interface {
-	GetUuid(username string) (*mojang.ProfileInfo, error)
+	GetUuid(c context.Context, username string) (*mojang.ProfileInfo, error)
 }
 
 type TexturesProvider interface {
-	GetTextures(uuid string) (*mojang.SignedTexturesResponse, error)
+	GetTextures(c context.Context, uuid string) (*mojang.SignedTexturesResponse, error)
 }
 
 type Emitter interface {
@@ -80,14 +121,20 @@ type Provider struct {
 	UUIDsProvider
 	TexturesProvider
 	Storage
+	// Refreshes the popular usernames before their textures expire. Disabled when nil
+	Prefetcher *Prefetcher
 
 	onFirstCall sync.Once
 	*broadcaster
+	inProgress sync.WaitGroup
 }
 
-func (ctx *Provider) GetForUsername(username string) (*mojang.SignedTexturesResponse, error) {
+func (ctx *Provider) GetForUsername(c context.Context, username string) (*mojang.SignedTexturesResponse, error) {
 	ctx.onFirstCall.Do(func() {
 		ctx.broadcaster = createBroadcaster()
+		if ctx.Prefetcher != nil {
+			ctx.Prefetcher.start(ctx.prefetch)
+		}
 	})
 
 	if !allowedUsernamesRegex.MatchString(username) {
@@ -96,6 +143,9 @@ func (ctx *Provider) GetForUsername(username string) (*mojang.SignedTexturesResp
 
 	username = strings.ToLower(username)
 	ctx.Emit("mojang_textures:call", username)
+	if ctx.Prefetcher != nil {
+		ctx.Prefetcher.Track(username)
+	}
 
 	uuid, found, err := ctx.getUuidFromCache(username)
 	if err != nil {
@@ -107,37 +157,141 @@ func (ctx *Provider) GetForUsername(username string) (*mojang.SignedTexturesResp
 	}
 
 	if uuid != "" {
-		textures, err := ctx.getTexturesFromCache(uuid)
-		if err == nil && textures != nil {
+		// The found nil value means that the absence of the textures is cached
+		textures, found, err := ctx.getTexturesFromCache(uuid)
+		if err == nil && found {
 			return textures, nil
 		}
+
+		// Serve the expired textures right away and refresh them in the background
+		staleTextures := ctx.getStaleTexturesFromCache(uuid)
+		if staleTextures != nil {
+			if ctx.refreshInBackground(c, username, uuid) {
+				ctx.Emit("mojang_textures:textures:stale_refresh", username, uuid)
+			}
+
+			return staleTextures, nil
+		}
 	}
 
-	resultChan := make(chan *broadcastResult)
-	isFirstListener := ctx.broadcaster.AddListener(username, resultChan)
+	// The result may be sent after the caller has gone, so the chan is buffered to not block the sender
+	resultChan := make(chan *broadcastResult, 1)
+	// The result is shared between all listeners, so it shouldn't be canceled together with the first caller.
+	// But the values of its context (e.g. request id) are kept to be able to correlate the outgoing requests
+	sharedCtx, isFirstListener := ctx.broadcaster.AddListener(c, username, resultChan)
 	if isFirstListener {
-		go ctx.getResultAndBroadcast(username, uuid)
+		ctx.inProgress.Add(1)
+		go ctx.getResultAndBroadcast(sharedCtx, username, uuid)
 	} else {
 		ctx.Emit("mojang_textures:already_processing", username)
 	}
 
-	result := <-resultChan
+	select {
+	case result := <-resultChan:
+		return result.textures, result.error
+	case <-c.Done():
+		ctx.broadcaster.RemoveListener(username, resultChan)
+		return nil, c.Err()
+	}
+}
+
+// Purge removes the username and its textures from all the cache layers,
+// so the next request will get them from Mojang
+func (ctx *Provider) Purge(username string) error {
+	username = strings.ToLower(username)
+	uuid, err := ctx.peekUuid(username)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.Storage.RemoveUuid(username)
+	if err != nil {
+		return err
+	}
+
+	if uuid != "" {
+		ctx.Storage.RemoveTextures(uuid)
+	}
+
+	ctx.Emit("mojang_textures:purge", username, uuid)
+
+	return nil
+}
+
+// The expired uuid still must be known to remove the textures, which may outlive it
+func (ctx *Provider) peekUuid(username string) (string, error) {
+	peekableStorage, ok := ctx.Storage.(PeekableUUIDsStorage)
+	if !ok {
+		uuid, _, err := ctx.Storage.GetUuid(username)
+		return uuid, err
+	}
+
+	return peekableStorage.PeekUuid(username)
+}
+
+// Shutdown waits until all the started textures requests will be completed and their results broadcast
+func (ctx *Provider) Shutdown(c context.Context) error {
+	if ctx.Prefetcher != nil {
+		ctx.Prefetcher.Stop()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ctx.inProgress.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-c.Done():
+		return c.Err()
+	case <-done:
+		return nil
+	}
+}
+
+// refreshInBackground returns false when the request for the username is already in progress
+func (ctx *Provider) refreshInBackground(c context.Context, username string, uuid string) bool {
+	// Nobody waits for the result, so the chan is buffered to not block the sender
+	resultChan := make(chan *broadcastResult, 1)
+	// Nobody waits for the result, so the username must not delay the interactive requests in the queue
+	sharedCtx, isFirstListener := ctx.broadcaster.AddListener(WithPriority(c, PriorityLow), username, resultChan)
+	if !isFirstListener {
+		ctx.Emit("mojang_textures:already_processing", username)
+		return false
+	}
+
+	ctx.inProgress.Add(1)
+	go ctx.getResultAndBroadcast(sharedCtx, username, uuid)
+
+	return true
+}
+
+func (ctx *Provider) prefetch(username string) bool {
+	uuid, found, err := ctx.Storage.GetUuid(username)
+	if err != nil || (found && uuid == "") {
+		return false
+	}
+
+	// The expired uuid is requested through the low priority lane of the queue together with the textures
 
-	return result.textures, result.error
+	// The refresh takes only the free slot of the Mojang's rate limit and is retried by the Prefetcher later
+	return ctx.refreshInBackground(withoutRateLimitWait(context.Background()), username, uuid)
 }
 
-func (ctx *Provider) getResultAndBroadcast(username string, uuid string) {
+func (ctx *Provider) getResultAndBroadcast(c context.Context, username string, uuid string) {
+	defer ctx.inProgress.Done()
+
 	ctx.Emit("mojang_textures:before_result", username, uuid)
-	result := ctx.getResult(username, uuid)
+	result := ctx.getResult(c, username, uuid)
 	ctx.Emit("mojang_textures:after_result", username, result.textures, result.error)
 
-	ctx.broadcaster.BroadcastAndRemove(username, result)
+	ctx.broadcaster.BroadcastAndRemove(c, username, result)
 }
 
-func (ctx *Provider) getResult(username string, cachedUuid string) *broadcastResult {
+func (ctx *Provider) getResult(c context.Context, username string, cachedUuid string) *broadcastResult {
 	uuid := cachedUuid
 	if uuid == "" {
-		profile, err := ctx.getUuid(username)
+		profile, err := ctx.getUuid(c, username)
 		if err != nil {
 			return &broadcastResult{nil, err}
 		}
@@ -154,12 +308,19 @@ func (ctx *Provider) getResult(username string, cachedUuid string) *broadcastRes
 		}
 	}
 
-	textures, err := ctx.getTextures(uuid)
+	textures, err := ctx.getTextures(c, uuid)
 	if err != nil {
 		// Previously cached UUIDs may disappear
 		// In this case we must invalidate UUID cache for given username
 		if _, ok := err.(*mojang.EmptyResponse); ok && cachedUuid != "" {
-			return ctx.getResult(username, "")
+			return ctx.getResult(c, username, "")
+		}
+
+		// It's better to respond with the outdated textures than with nothing
+		staleTextures := ctx.getStaleTexturesFromCache(uuid)
+		if staleTextures != nil {
+			ctx.Emit("mojang_textures:textures:stale_fallback", uuid, err)
+			return &broadcastResult{staleTextures, nil}
 		}
 
 		return &broadcastResult{nil, err}
@@ -168,6 +329,9 @@ func (ctx *Provider) getResult(username string, cachedUuid string) *broadcastRes
 	// Mojang can respond with an error, but it will still count as a hit,
 	// therefore store the result even if textures is nil to prevent 429 error
 	ctx.Storage.StoreTextures(uuid, textures)
+	if ctx.Prefetcher != nil {
+		ctx.Prefetcher.Fetched(username)
+	}
 
 	return &broadcastResult{textures, nil}
 }
@@ -180,26 +344,48 @@ func (ctx *Provider) getUuidFromCache(username string) (string, bool, error) {
 	return uuid, found, err
 }
 
-func (ctx *Provider) getTexturesFromCache(uuid string) (*mojang.SignedTexturesResponse, error) {
+func (ctx *Provider) getTexturesFromCache(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
 	ctx.Emit("mojang_textures:textures:before_cache", uuid)
-	textures, err := ctx.Storage.GetTextures(uuid)
+	var textures *mojang.SignedTexturesResponse
+	var found bool
+	var err error
+	if lookupStorage, ok := ctx.Storage.(LookupTexturesStorage); ok {
+		textures, found, err = lookupStorage.LookupTextures(uuid)
+	} else {
+		textures, err = ctx.Storage.GetTextures(uuid)
+		found = textures != nil
+	}
 	ctx.Emit("mojang_textures:textures:after_cache", uuid, textures, err)
 
-	return textures, err
+	return textures, found, err
+}
+
+func (ctx *Provider) getStaleTexturesFromCache(uuid string) *mojang.SignedTexturesResponse {
+	staleStorage, ok := ctx.Storage.(StaleTexturesStorage)
+	if !ok {
+		return nil
+	}
+
+	textures, err := staleStorage.GetStaleTextures(uuid)
+	if err != nil {
+		return nil
+	}
+
+	return textures
 }
 
-func (ctx *Provider) getUuid(username string) (*mojang.ProfileInfo, error) {
-	ctx.Emit("mojang_textures:usernames:before_call", username)
-	profile, err := ctx.UUIDsProvider.GetUuid(username)
-	ctx.Emit("mojang_textures:usernames:after_call", username, profile, err)
+func (ctx *Provider) getUuid(c context.Context, username string) (*mojang.ProfileInfo, error) {
+	ctx.Emit("mojang_textures:usernames:before_call", c, username)
+	profile, err := ctx.UUIDsProvider.GetUuid(c, username)
+	ctx.Emit("mojang_textures:usernames:after_call", c, username, profile, err)
 
 	return profile, err
 }
 
-func (ctx *Provider) getTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
-	ctx.Emit("mojang_textures:textures:before_call", uuid)
-	textures, err := ctx.TexturesProvider.GetTextures(uuid)
-	ctx.Emit("mojang_textures:textures:after_call", uuid, textures, err)
+func (ctx *Provider) getTextures(c context.Context, uuid string) (*mojang.SignedTexturesResponse, error) {
+	ctx.Emit("mojang_textures:textures:before_call", c, uuid)
+	textures, err := ctx.TexturesProvider.GetTextures(c, uuid)
+	ctx.Emit("mojang_textures:textures:after_call", c, uuid, textures, err)
 
 	return textures, err
 }
@@ -1,6 +1,7 @@
 package mojangtextures
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"testing"
@@ -20,34 +21,78 @@ func TestBroadcaster(t *testing.T) {
 
 			broadcaster := createBroadcaster()
 			channel := make(chan *broadcastResult)
-			isFirstListener := broadcaster.AddListener("mock", channel)
+			sharedCtx, isFirstListener := broadcaster.AddListener(context.Background(), "mock", channel)
 
 			assert.True(isFirstListener)
+			assert.NotNil(sharedCtx)
 			listeners, ok := broadcaster.listeners["mock"]
 			assert.True(ok)
-			assert.Len(listeners, 1)
-			assert.Equal(channel, listeners[0])
+			assert.Len(listeners.channels, 1)
+			assert.Equal(channel, listeners.channels[0])
 		})
 
-		t.Run("subsequent calls should return false", func(t *testing.T) {
+		t.Run("subsequent calls should return false and the same context", func(t *testing.T) {
 			assert := testify.New(t)
 
 			broadcaster := createBroadcaster()
 			channel1 := make(chan *broadcastResult)
-			isFirstListener := broadcaster.AddListener("mock", channel1)
+			sharedCtx1, isFirstListener := broadcaster.AddListener(context.Background(), "mock", channel1)
 
 			assert.True(isFirstListener)
 
 			channel2 := make(chan *broadcastResult)
-			isFirstListener = broadcaster.AddListener("mock", channel2)
+			sharedCtx2, isFirstListener := broadcaster.AddListener(context.Background(), "mock", channel2)
 
 			assert.False(isFirstListener)
+			assert.Equal(sharedCtx1, sharedCtx2)
 
 			channel3 := make(chan *broadcastResult)
-			isFirstListener = broadcaster.AddListener("mock", channel3)
+			_, isFirstListener = broadcaster.AddListener(context.Background(), "mock", channel3)
 
 			assert.False(isFirstListener)
 		})
+
+		t.Run("shared context shouldn't be canceled with the first listener's context", func(t *testing.T) {
+			assert := testify.New(t)
+
+			broadcaster := createBroadcaster()
+			ctx, cancel := context.WithCancel(context.Background())
+			sharedCtx, _ := broadcaster.AddListener(ctx, "mock", make(chan *broadcastResult))
+			cancel()
+
+			assert.NoError(sharedCtx.Err())
+		})
+	})
+
+	t.Run("RemoveListener", func(t *testing.T) {
+		t.Run("should keep the shared context while there are listeners", func(t *testing.T) {
+			assert := testify.New(t)
+
+			broadcaster := createBroadcaster()
+			channel1 := make(chan *broadcastResult)
+			channel2 := make(chan *broadcastResult)
+			sharedCtx, _ := broadcaster.AddListener(context.Background(), "mock", channel1)
+			broadcaster.AddListener(context.Background(), "mock", channel2)
+
+			broadcaster.RemoveListener("mock", channel1)
+
+			assert.NoError(sharedCtx.Err())
+			assert.Len(broadcaster.listeners["mock"].channels, 1)
+		})
+
+		t.Run("should cancel the shared context when the last listener has gone", func(t *testing.T) {
+			assert := testify.New(t)
+
+			broadcaster := createBroadcaster()
+			channel := make(chan *broadcastResult)
+			sharedCtx, _ := broadcaster.AddListener(context.Background(), "mock", channel)
+
+			broadcaster.RemoveListener("mock", channel)
+
+			assert.ErrorIs(sharedCtx.Err(), context.Canceled)
+			_, isFirstListener := broadcaster.AddListener(context.Background(), "mock", make(chan *broadcastResult))
+			assert.True(isFirstListener)
+		})
 	})
 
 	t.Run("BroadcastAndRemove", func(t *testing.T) {
@@ -57,26 +102,44 @@ func TestBroadcaster(t *testing.T) {
 			broadcaster := createBroadcaster()
 			channel1 := make(chan *broadcastResult)
 			channel2 := make(chan *broadcastResult)
-			broadcaster.AddListener("mock", channel1)
-			broadcaster.AddListener("mock", channel2)
+			sharedCtx, _ := broadcaster.AddListener(context.Background(), "mock", channel1)
+			broadcaster.AddListener(context.Background(), "mock", channel2)
 
 			result := &broadcastResult{}
-			broadcaster.BroadcastAndRemove("mock", result)
+			broadcaster.BroadcastAndRemove(sharedCtx, "mock", result)
 
 			assert.Equal(result, <-channel1)
 			assert.Equal(result, <-channel2)
 
 			channel3 := make(chan *broadcastResult)
-			isFirstListener := broadcaster.AddListener("mock", channel3)
+			_, isFirstListener := broadcaster.AddListener(context.Background(), "mock", channel3)
 			assert.True(isFirstListener)
 		})
 
+		t.Run("should keep the listeners of the newer request", func(t *testing.T) {
+			assert := testify.New(t)
+
+			broadcaster := createBroadcaster()
+			channel1 := make(chan *broadcastResult)
+			staleCtx, _ := broadcaster.AddListener(context.Background(), "mock", channel1)
+			broadcaster.RemoveListener("mock", channel1)
+
+			channel2 := make(chan *broadcastResult)
+			broadcaster.AddListener(context.Background(), "mock", channel2)
+
+			broadcaster.BroadcastAndRemove(staleCtx, "mock", &broadcastResult{})
+
+			listeners, ok := broadcaster.listeners["mock"]
+			assert.True(ok)
+			assert.Len(listeners.channels, 1)
+		})
+
 		t.Run("call on not exists username", func(t *testing.T) {
 			assert := testify.New(t)
 
 			assert.NotPanics(func() {
 				broadcaster := createBroadcaster()
-				broadcaster.BroadcastAndRemove("mock", &broadcastResult{})
+				broadcaster.BroadcastAndRemove(context.Background(), "mock", &broadcastResult{})
 			})
 		})
 	})
@@ -94,7 +157,7 @@ type mockUuidsProvider struct {
 	mock.Mock
 }
 
-func (m *mockUuidsProvider) GetUuid(username string) (*mojang.ProfileInfo, error) {
+func (m *mockUuidsProvider) GetUuid(_ context.Context, username string) (*mojang.ProfileInfo, error) {
 	args := m.Called(username)
 	var result *mojang.ProfileInfo
 	if casted, ok := args.Get(0).(*mojang.ProfileInfo); ok {
@@ -108,7 +171,7 @@ type mockTexturesProvider struct {
 	mock.Mock
 }
 
-func (m *mockTexturesProvider) GetTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
+func (m *mockTexturesProvider) GetTextures(_ context.Context, uuid string) (*mojang.SignedTexturesResponse, error) {
 	args := m.Called(uuid)
 	var result *mojang.SignedTexturesResponse
 	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
@@ -132,6 +195,11 @@ func (m *mockStorage) StoreUuid(username string, uuid string) error {
 	return args.Error(0)
 }
 
+func (m *mockStorage) RemoveUuid(username string) error {
+	args := m.Called(username)
+	return args.Error(0)
+}
+
 func (m *mockStorage) GetTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
 	args := m.Called(uuid)
 	var result *mojang.SignedTexturesResponse
@@ -146,6 +214,47 @@ func (m *mockStorage) StoreTextures(uuid string, textures *mojang.SignedTextures
 	m.Called(uuid, textures)
 }
 
+func (m *mockStorage) RemoveTextures(uuid string) {
+	m.Called(uuid)
+}
+
+type mockStaleStorage struct {
+	*mockStorage
+}
+
+func (m *mockStaleStorage) GetStaleTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
+	args := m.Called(uuid)
+	var result *mojang.SignedTexturesResponse
+	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
+		result = casted
+	}
+
+	return result, args.Error(1)
+}
+
+type mockLookupStorage struct {
+	*mockStorage
+}
+
+func (m *mockLookupStorage) LookupTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
+	args := m.Called(uuid)
+	var result *mojang.SignedTexturesResponse
+	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
+		result = casted
+	}
+
+	return result, args.Bool(1), args.Error(2)
+}
+
+type mockPeekableStorage struct {
+	*mockStorage
+}
+
+func (m *mockPeekableStorage) PeekUuid(username string) (string, error) {
+	args := m.Called(username)
+	return args.String(0), args.Error(1)
+}
+
 type providerTestSuite struct {
 	suite.Suite
 	Provider         *Provider
@@ -188,10 +297,10 @@ func (suite *providerTestSuite) TestGetForUsernameWithoutAnyCache() {
 	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
 	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "", false, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", "username").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", "username", expectedProfile, nil).Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", mock.Anything, "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", mock.Anything, "username", expectedProfile, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
 
 	suite.Storage.On("GetUuid", "username").Once().Return("", false, nil)
@@ -201,7 +310,7 @@ func (suite *providerTestSuite) TestGetForUsernameWithoutAnyCache() {
 	suite.UuidsProvider.On("GetUuid", "username").Once().Return(expectedProfile, nil)
 	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Nil(err)
 	suite.Assert().Equal(expectedResult, result)
@@ -217,8 +326,8 @@ func (suite *providerTestSuite) TestGetForUsernameWithCachedUuid() {
 	suite.Emitter.On("Emit", "mojang_textures:textures:before_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
 	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedCachedTextures, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
 
 	suite.Storage.On("GetUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
@@ -227,7 +336,7 @@ func (suite *providerTestSuite) TestGetForUsernameWithCachedUuid() {
 
 	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Return(expectedResult, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Nil(err)
 	suite.Assert().Equal(expectedResult, result)
@@ -245,12 +354,31 @@ func (suite *providerTestSuite) TestGetForUsernameWithFullyCachedResult() {
 	suite.Storage.On("GetUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
 	suite.Storage.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Nil(err)
 	suite.Assert().Equal(expectedResult, result)
 }
 
+func (suite *providerTestSuite) TestGetForUsernameWithCachedAbsenceOfTextures() {
+	var expectedCachedTextures *mojang.SignedTexturesResponse
+	suite.Provider.Storage = &mockLookupStorage{suite.Storage}
+
+	suite.Emitter.On("Emit", "mojang_textures:call", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedCachedTextures, nil).Once()
+
+	suite.Storage.On("GetUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
+	suite.Storage.On("LookupTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, true, nil)
+
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
+
+	suite.Assert().Nil(result)
+	suite.Assert().Nil(err)
+}
+
 func (suite *providerTestSuite) TestGetForUsernameWithCachedUnknownUuid() {
 	suite.Emitter.On("Emit", "mojang_textures:call", "username").Once()
 	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
@@ -258,7 +386,7 @@ func (suite *providerTestSuite) TestGetForUsernameWithCachedUnknownUuid() {
 
 	suite.Storage.On("GetUuid", "username").Once().Return("", true, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Nil(result)
 	suite.Assert().Nil(err)
@@ -272,8 +400,8 @@ func (suite *providerTestSuite) TestGetForUsernameWhichHasNoMojangAccount() {
 	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
 	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "", false, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", "username").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", "username", expectedProfile, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", mock.Anything, "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", mock.Anything, "username", expectedProfile, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
 
 	suite.Storage.On("GetUuid", "username").Once().Return("", false, nil)
@@ -281,7 +409,7 @@ func (suite *providerTestSuite) TestGetForUsernameWhichHasNoMojangAccount() {
 
 	suite.UuidsProvider.On("GetUuid", "username").Once().Return(nil, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Nil(err)
 	suite.Assert().Nil(result)
@@ -295,10 +423,10 @@ func (suite *providerTestSuite) TestGetForUsernameWhichHasMojangAccountButHasNoM
 	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
 	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "", false, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", "username").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", "username", expectedProfile, nil).Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", mock.Anything, "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", mock.Anything, "username", expectedProfile, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
 
 	suite.Storage.On("GetUuid", "username").Once().Return("", false, nil)
@@ -308,7 +436,7 @@ func (suite *providerTestSuite) TestGetForUsernameWhichHasMojangAccountButHasNoM
 	suite.UuidsProvider.On("GetUuid", "username").Once().Return(expectedProfile, nil)
 	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Equal(expectedResult, result)
 	suite.Assert().Nil(err)
@@ -327,12 +455,12 @@ func (suite *providerTestSuite) TestGetForUsernameWithCachedUuidThatHasBeenDisap
 	suite.Emitter.On("Emit", "mojang_textures:textures:before_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
 	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nilTexturesResponse, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nilTexturesResponse, expectedErr).Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", "username").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", "username", expectedProfile, nil).Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb").Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", expectedResult, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nilTexturesResponse, expectedErr).Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", mock.Anything, "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", mock.Anything, "username", expectedProfile, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", mock.Anything, "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", mock.Anything, "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", expectedResult, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
 
 	suite.Storage.On("GetUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
@@ -344,7 +472,7 @@ func (suite *providerTestSuite) TestGetForUsernameWithCachedUuidThatHasBeenDisap
 	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Return(nil, expectedErr)
 	suite.TexturesProvider.On("GetTextures", "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb").Return(expectedResult, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Nil(err)
 	suite.Assert().Equal(expectedResult, result)
@@ -359,10 +487,10 @@ func (suite *providerTestSuite) TestGetForTheSameUsernames() {
 	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "", false, nil).Twice()
 	suite.Emitter.On("Emit", "mojang_textures:already_processing", "username").Once()
 	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", "username").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", "username", expectedProfile, nil).Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", mock.Anything, "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", mock.Anything, "username", expectedProfile, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
 
 	suite.Storage.On("GetUuid", "username").Twice().Return("", false, nil)
@@ -378,7 +506,7 @@ func (suite *providerTestSuite) TestGetForTheSameUsernames() {
 	for i := 0; i < 2; i++ {
 		wg.Add(1)
 		go func(i int) {
-			textures, _ := suite.Provider.GetForUsername("username")
+			textures, _ := suite.Provider.GetForUsername(context.Background(), "username")
 			results[i] = textures
 			wg.Done()
 		}(i)
@@ -390,7 +518,7 @@ func (suite *providerTestSuite) TestGetForTheSameUsernames() {
 }
 
 func (suite *providerTestSuite) TestGetForNotAllowedMojangUsername() {
-	result, err := suite.Provider.GetForUsername("Not allowed")
+	result, err := suite.Provider.GetForUsername(context.Background(), "Not allowed")
 	suite.Assert().Nil(err)
 	suite.Assert().Nil(result)
 }
@@ -404,7 +532,7 @@ func (suite *providerTestSuite) TestGetErrorFromUUIDsStorage() {
 
 	suite.Storage.On("GetUuid", "username").Once().Return("", false, expectedErr)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Nil(result)
 	suite.Assert().Equal(expectedErr, err)
@@ -419,14 +547,14 @@ func (suite *providerTestSuite) TestGetErrorFromUuidsProvider() {
 	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
 	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "", false, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", "username").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", "username", expectedProfile, err).Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", mock.Anything, "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", mock.Anything, "username", expectedProfile, err).Once()
 	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, err).Once()
 
 	suite.Storage.On("GetUuid", "username").Once().Return("", false, nil)
 	suite.UuidsProvider.On("GetUuid", "username").Once().Return(nil, err)
 
-	result, resErr := suite.Provider.GetForUsername("username")
+	result, resErr := suite.Provider.GetForUsername(context.Background(), "username")
 	suite.Assert().Nil(result)
 	suite.Assert().Equal(err, resErr)
 }
@@ -440,10 +568,10 @@ func (suite *providerTestSuite) TestGetErrorFromTexturesProvider() {
 	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
 	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "", false, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", "username").Once()
-	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", "username", expectedProfile, nil).Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, err).Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", mock.Anything, "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", mock.Anything, "username", expectedProfile, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, err).Once()
 	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, err).Once()
 
 	suite.Storage.On("GetUuid", "username").Return("", false, nil)
@@ -451,7 +579,244 @@ func (suite *providerTestSuite) TestGetErrorFromTexturesProvider() {
 	suite.UuidsProvider.On("GetUuid", "username").Once().Return(expectedProfile, nil)
 	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, err)
 
-	result, resErr := suite.Provider.GetForUsername("username")
+	result, resErr := suite.Provider.GetForUsername(context.Background(), "username")
 	suite.Assert().Nil(result)
 	suite.Assert().Equal(err, resErr)
 }
+
+func (suite *providerTestSuite) TestShutdownShouldWaitForStartedRequests() {
+	var expectedProfile *mojang.ProfileInfo
+	var expectedResult *mojang.SignedTexturesResponse
+
+	suite.Emitter.On("Emit", "mojang_textures:call", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "", false, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", mock.Anything, "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", mock.Anything, "username", expectedProfile, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
+
+	suite.Storage.On("GetUuid", "username").Once().Return("", false, nil)
+	suite.Storage.On("StoreUuid", "username", "").Once().Return(nil)
+
+	called := make(chan struct{})
+	release := make(chan struct{})
+	suite.UuidsProvider.On("GetUuid", "username").Once().Run(func(args mock.Arguments) {
+		close(called)
+		<-release
+	}).Return(nil, nil)
+
+	resultChan := make(chan error)
+	go func() {
+		_, err := suite.Provider.GetForUsername(context.Background(), "username")
+		resultChan <- err
+	}()
+
+	<-called
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	suite.Assert().ErrorIs(suite.Provider.Shutdown(ctx), context.DeadlineExceeded)
+
+	close(release)
+	suite.Assert().NoError(suite.Provider.Shutdown(context.Background()))
+	suite.Assert().NoError(<-resultChan)
+}
+
+func (suite *providerTestSuite) TestGetForUsernameShouldReturnWhenContextIsCanceled() {
+	var expectedProfile *mojang.ProfileInfo
+	var expectedResult *mojang.SignedTexturesResponse
+
+	suite.Emitter.On("Emit", "mojang_textures:call", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "", false, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", mock.Anything, "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", mock.Anything, "username", expectedProfile, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
+
+	suite.Storage.On("GetUuid", "username").Once().Return("", false, nil)
+	suite.Storage.On("StoreUuid", "username", "").Once().Return(nil)
+
+	called := make(chan struct{})
+	release := make(chan struct{})
+	suite.UuidsProvider.On("GetUuid", "username").Once().Run(func(args mock.Arguments) {
+		close(called)
+		<-release
+	}).Return(nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resultChan := make(chan error)
+	go func() {
+		_, err := suite.Provider.GetForUsername(ctx, "username")
+		resultChan <- err
+	}()
+
+	<-called
+	cancel()
+	suite.Assert().ErrorIs(<-resultChan, context.Canceled)
+
+	close(release)
+	suite.Assert().NoError(suite.Provider.Shutdown(context.Background()))
+}
+
+func (suite *providerTestSuite) TestGetForUsernameWithStaleTextures() {
+	var expectedCachedTextures *mojang.SignedTexturesResponse
+	staleResult := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "old username"}
+	expectedResult := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	suite.Provider.Storage = &mockStaleStorage{suite.Storage}
+
+	suite.Emitter.On("Emit", "mojang_textures:call", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedCachedTextures, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:stale_refresh", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
+
+	suite.Storage.On("GetUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
+	suite.Storage.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, nil)
+	suite.Storage.On("GetStaleTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(staleResult, nil)
+	suite.Storage.On("StoreTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult).Once()
+
+	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, nil)
+
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
+
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(staleResult, result)
+
+	// Wait for the background refresh
+	suite.Assert().NoError(suite.Provider.Shutdown(context.Background()))
+}
+
+func (suite *providerTestSuite) TestGetForUsernameShouldFallbackToStaleTexturesOnError() {
+	expectedProfile := &mojang.ProfileInfo{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	var nilTextures *mojang.SignedTexturesResponse
+	staleResult := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	err := errors.New("mock error")
+	suite.Provider.Storage = &mockStaleStorage{suite.Storage}
+
+	suite.Emitter.On("Emit", "mojang_textures:call", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "", false, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", mock.Anything, "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", mock.Anything, "username", expectedProfile, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nilTextures, err).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:stale_fallback", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", err).Once()
+	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", staleResult, nil).Once()
+
+	suite.Storage.On("GetUuid", "username").Once().Return("", false, nil)
+	suite.Storage.On("StoreUuid", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil)
+	suite.Storage.On("GetStaleTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(staleResult, nil)
+
+	suite.UuidsProvider.On("GetUuid", "username").Once().Return(expectedProfile, nil)
+	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, err)
+
+	result, resErr := suite.Provider.GetForUsername(context.Background(), "username")
+
+	suite.Assert().Nil(resErr)
+	suite.Assert().Equal(staleResult, result)
+}
+
+func (suite *providerTestSuite) TestPurge() {
+	suite.Emitter.On("Emit", "mojang_textures:purge", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+
+	suite.Storage.On("GetUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
+	suite.Storage.On("RemoveUuid", "username").Once().Return(nil)
+	suite.Storage.On("RemoveTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+
+	suite.Assert().NoError(suite.Provider.Purge("UserName"))
+}
+
+func (suite *providerTestSuite) TestPurgeExpiredUuid() {
+	suite.Provider.Storage = &mockPeekableStorage{suite.Storage}
+	suite.Emitter.On("Emit", "mojang_textures:purge", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+
+	suite.Storage.On("PeekUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil)
+	suite.Storage.On("RemoveUuid", "username").Once().Return(nil)
+	suite.Storage.On("RemoveTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+
+	suite.Assert().NoError(suite.Provider.Purge("username"))
+}
+
+func (suite *providerTestSuite) TestPurgeUnknownUsername() {
+	suite.Emitter.On("Emit", "mojang_textures:purge", "username", "").Once()
+
+	suite.Storage.On("GetUuid", "username").Once().Return("", true, nil)
+	suite.Storage.On("RemoveUuid", "username").Once().Return(nil)
+
+	suite.Assert().NoError(suite.Provider.Purge("username"))
+}
+
+func (suite *providerTestSuite) TestPurgeWithStorageError() {
+	err := errors.New("mock error")
+	suite.Storage.On("GetUuid", "username").Once().Return("", false, err)
+
+	suite.Assert().Equal(err, suite.Provider.Purge("username"))
+}
+
+func (suite *providerTestSuite) TestPrefetch() {
+	expectedResult := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	suite.Provider.Prefetcher = &Prefetcher{Emitter: suite.Emitter}
+	suite.Provider.broadcaster = createBroadcaster()
+
+	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", mock.MatchedBy(func(c context.Context) bool {
+		return c.Value(noRateLimitWaitContextKey{}) != nil
+	}), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
+
+	suite.Storage.On("GetUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
+	suite.Storage.On("StoreTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult).Once()
+
+	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, nil)
+
+	suite.Assert().True(suite.Provider.prefetch("username"))
+	suite.Assert().NoError(suite.Provider.Shutdown(context.Background()))
+
+	suite.Provider.Prefetcher.lock.Lock()
+	suite.Assert().False(suite.Provider.Prefetcher.entries["username"].fetchedAt.IsZero())
+	suite.Provider.Prefetcher.lock.Unlock()
+}
+
+func (suite *providerTestSuite) TestPrefetchWithExpiredUuid() {
+	expectedProfile := &mojang.ProfileInfo{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	expectedResult := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	suite.Provider.Prefetcher = &Prefetcher{Emitter: suite.Emitter}
+	suite.Provider.broadcaster = createBroadcaster()
+
+	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", mock.MatchedBy(func(c context.Context) bool {
+		return priorityFromContext(c) == PriorityLow
+	}), "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", mock.Anything, "username", expectedProfile, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", mock.Anything, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
+
+	suite.Storage.On("GetUuid", "username").Once().Return("", false, nil)
+	suite.Storage.On("StoreUuid", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil)
+	suite.Storage.On("StoreTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult).Once()
+
+	suite.UuidsProvider.On("GetUuid", "username").Once().Return(expectedProfile, nil)
+	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, nil)
+
+	suite.Assert().True(suite.Provider.prefetch("username"))
+	suite.Assert().NoError(suite.Provider.Shutdown(context.Background()))
+}
+
+func (suite *providerTestSuite) TestPrefetchUnknownUsername() {
+	suite.Provider.Prefetcher = &Prefetcher{Emitter: suite.Emitter}
+	suite.Provider.broadcaster = createBroadcaster()
+
+	suite.Storage.On("GetUuid", "username").Once().Return("", true, nil)
+
+	suite.Assert().False(suite.Provider.prefetch("username"))
+}
@@ -1,12 +1,18 @@
 package mojangtextures
 
 import (
+	"context"
+
 	"github.com/elyby/chrly/api/mojang"
 )
 
 type NilProvider struct {
 }
 
-func (p *NilProvider) GetForUsername(username string) (*mojang.SignedTexturesResponse, error) {
+func (p *NilProvider) GetForUsername(_ context.Context, username string) (*mojang.SignedTexturesResponse, error) {
 	return nil, nil
 }
+
+func (p *NilProvider) Purge(username string) error {
+	return nil
+}
@@ -0,0 +1,161 @@
+package mojangtextures
+
+import (
+	"sync"
+	"time"
+)
+
+var prefetcherNow = time.Now
+
+type prefetchEntry struct {
+	windowStart time.Time
+	hits        int
+	prevHits    int
+	fetchedAt   time.Time
+	// Whether the textures were refreshed proactively and weren't requested since then
+	prefetched bool
+}
+
+// Prefetcher tracks how often the usernames are requested and refreshes the textures
+// of the popular ones before they expire from the cache
+type Prefetcher struct {
+	Emitter
+	// The number of requests within the Window after which the username is considered popular
+	Threshold int
+	Window    time.Duration
+	// The age of the textures after which they're refreshed. Should be less than the textures storage duration
+	RefreshAfter time.Duration
+	// Period of the scheduler iterations
+	Interval time.Duration
+	// Limits the rate of the refreshes. The refresh is postponed to the next iteration
+	// when there is no free slot right away. The refreshes also don't wait for the slots
+	// of the textures provider's rate limit, so the regular requests are never delayed
+	RateLimiter RateLimiter
+
+	once    sync.Once
+	lock    sync.Mutex
+	entries map[string]*prefetchEntry
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// Track registers the request for the username
+func (p *Prefetcher) Track(username string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	entry := p.getEntry(username)
+	now := prefetcherNow()
+	if now.Sub(entry.windowStart) >= p.Window {
+		entry.prevHits = entry.hits
+		entry.hits = 0
+		entry.windowStart = now
+	}
+
+	entry.hits++
+
+	if entry.prefetched {
+		entry.prefetched = false
+		p.Emit("mojang_textures:prefetch:hit", username)
+	}
+}
+
+// Fetched registers that the textures of the username were received from Mojang
+func (p *Prefetcher) Fetched(username string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.getEntry(username).fetchedAt = prefetcherNow()
+}
+
+// Stop terminates the scheduler goroutine and waits until its current iteration is finished
+func (p *Prefetcher) Stop() {
+	// Prevent the scheduler from starting if it hasn't been started yet
+	p.once.Do(func() {})
+	if p.done != nil {
+		close(p.done)
+		<-p.stopped
+	}
+}
+
+// start runs the scheduler, which calls the refresh func for the popular usernames.
+// The refresh func should return false when the refresh hasn't been started
+func (p *Prefetcher) start(refresh func(username string) bool) {
+	p.once.Do(func() {
+		p.done = make(chan struct{})
+		p.stopped = make(chan struct{})
+		ticker := time.NewTicker(p.Interval)
+		go func() {
+			defer close(p.stopped)
+			for {
+				select {
+				case <-p.done:
+					ticker.Stop()
+					return
+				case <-ticker.C:
+					p.iterate(refresh)
+				}
+			}
+		}()
+	})
+}
+
+func (p *Prefetcher) iterate(refresh func(username string) bool) {
+	for _, username := range p.collectDueUsernames() {
+		delay, reserved, err := p.RateLimiter.Reserve(0)
+		if err != nil || !reserved || delay > 0 {
+			return
+		}
+
+		if !refresh(username) {
+			continue
+		}
+
+		p.lock.Lock()
+		if entry, ok := p.entries[username]; ok {
+			entry.prefetched = true
+			// Postpone the next refresh until the result will be received
+			entry.fetchedAt = prefetcherNow()
+		}
+		p.lock.Unlock()
+
+		p.Emit("mojang_textures:prefetch:refresh", username)
+	}
+}
+
+// collectDueUsernames returns the popular usernames, whose textures should be refreshed,
+// and removes the entries, which weren't requested for a long time
+func (p *Prefetcher) collectDueUsernames() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := prefetcherNow()
+	var result []string
+	for username, entry := range p.entries {
+		if now.Sub(entry.windowStart) >= 2*p.Window {
+			delete(p.entries, username)
+			continue
+		}
+
+		isPopular := entry.hits >= p.Threshold || entry.prevHits >= p.Threshold
+		if isPopular && !entry.fetchedAt.IsZero() && now.Sub(entry.fetchedAt) >= p.RefreshAfter {
+			result = append(result, username)
+		}
+	}
+
+	return result
+}
+
+func (p *Prefetcher) getEntry(username string) *prefetchEntry {
+	if p.entries == nil {
+		p.entries = make(map[string]*prefetchEntry)
+	}
+
+	entry, ok := p.entries[username]
+	if !ok {
+		entry = &prefetchEntry{windowStart: prefetcherNow()}
+		p.entries[username] = entry
+	}
+
+	return entry
+}
@@ -0,0 +1,154 @@
+package mojangtextures
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefetcher(t *testing.T) {
+	now := time.Now()
+	prefetcherNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		prefetcherNow = time.Now
+	}()
+
+	unlimited := rateLimiterFunc(func(maxWait time.Duration) (time.Duration, bool, error) {
+		return 0, true, nil
+	})
+
+	createPrefetcher := func(emitter *mockEmitter, limiter RateLimiter) *Prefetcher {
+		return &Prefetcher{
+			Emitter:      emitter,
+			Threshold:    2,
+			Window:       time.Minute,
+			RefreshAfter: time.Minute,
+			Interval:     time.Second,
+			RateLimiter:  limiter,
+		}
+	}
+
+	t.Run("should refresh popular usernames, whose textures are about to expire", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:prefetch:refresh", "popular").Once()
+		p := createPrefetcher(emitter, unlimited)
+
+		p.Track("popular")
+		p.Track("popular")
+		p.Fetched("popular")
+		p.Track("rare")
+		p.Fetched("rare")
+
+		now = now.Add(30 * time.Second)
+		var refreshed []string
+		refresh := func(username string) bool {
+			refreshed = append(refreshed, username)
+			return true
+		}
+		p.iterate(refresh)
+		require.Empty(t, refreshed, "textures are still fresh")
+
+		now = now.Add(30 * time.Second)
+		p.iterate(refresh)
+		require.Equal(t, []string{"popular"}, refreshed)
+
+		p.iterate(refresh)
+		require.Equal(t, []string{"popular"}, refreshed, "should not refresh again until the next expiration")
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should emit the prefetch hit on the first request after the refresh", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:prefetch:refresh", "popular").Once()
+		emitter.On("Emit", "mojang_textures:prefetch:hit", "popular").Once()
+		p := createPrefetcher(emitter, unlimited)
+
+		p.Track("popular")
+		p.Track("popular")
+		p.Fetched("popular")
+
+		now = now.Add(time.Minute)
+		p.iterate(func(username string) bool {
+			return true
+		})
+
+		p.Track("popular")
+		p.Track("popular")
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should not mark the username as prefetched when the refresh hasn't been started", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		p := createPrefetcher(emitter, unlimited)
+
+		p.Track("popular")
+		p.Track("popular")
+		p.Fetched("popular")
+
+		now = now.Add(time.Minute)
+		p.iterate(func(username string) bool {
+			return false
+		})
+
+		p.Track("popular")
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should postpone the refresh when there is no free rate limit slot", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		p := createPrefetcher(emitter, rateLimiterFunc(func(maxWait time.Duration) (time.Duration, bool, error) {
+			require.Equal(t, time.Duration(0), maxWait)
+			return 0, false, nil
+		}))
+
+		p.Track("popular")
+		p.Track("popular")
+		p.Fetched("popular")
+
+		now = now.Add(time.Minute)
+		p.iterate(func(username string) bool {
+			t.Fatal("refresh should not be called")
+			return true
+		})
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should keep the popularity from the previous window", func(t *testing.T) {
+		p := createPrefetcher(&mockEmitter{}, unlimited)
+
+		p.Track("popular")
+		p.Track("popular")
+		p.Fetched("popular")
+
+		now = now.Add(time.Minute)
+		p.Track("popular")
+
+		require.Equal(t, []string{"popular"}, p.collectDueUsernames())
+	})
+
+	t.Run("should remove usernames, which weren't requested for a long time", func(t *testing.T) {
+		p := createPrefetcher(&mockEmitter{}, unlimited)
+
+		p.Track("popular")
+		p.Track("popular")
+		p.Fetched("popular")
+
+		now = now.Add(2 * time.Minute)
+
+		require.Empty(t, p.collectDueUsernames())
+		require.Empty(t, p.entries)
+	})
+
+	t.Run("stop not started prefetcher", func(t *testing.T) {
+		p := createPrefetcher(&mockEmitter{}, unlimited)
+		require.NotPanics(t, p.Stop)
+		require.Nil(t, p.done)
+	})
+}
@@ -0,0 +1,96 @@
+package mojangtextures
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var rateLimiterNow = time.Now
+
+var ErrRateLimitExceeded = errors.New("the request can't be performed within the rate limit")
+
+// RateLimiter distributes the requests over time by reserving a slot for each of them
+type RateLimiter interface {
+	// Reserve returns the delay after which the request can be performed.
+	// When the delay exceeds the maxWait, the slot isn't reserved and false is returned
+	Reserve(maxWait time.Duration) (time.Duration, bool, error)
+}
+
+// LocalRateLimiter allows one request per Interval. The slots are reserved in the order of calls,
+// so the waiting requests form a queue
+type LocalRateLimiter struct {
+	Interval time.Duration
+
+	lock sync.Mutex
+	next time.Time
+}
+
+func (l *LocalRateLimiter) Reserve(maxWait time.Duration) (time.Duration, bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := rateLimiterNow()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+
+	delay := slot.Sub(now)
+	if delay > maxWait {
+		return 0, false, nil
+	}
+
+	l.next = slot.Add(l.Interval)
+
+	return delay, true, nil
+}
+
+type noRateLimitWaitContextKey struct{}
+
+// withoutRateLimitWait returns the context, which makes the requests performed with it take only
+// the slot available right away, so they never wait ahead of the interactive requests
+func withoutRateLimitWait(c context.Context) context.Context {
+	return context.WithValue(c, noRateLimitWaitContextKey{}, true)
+}
+
+// waitForRateLimit blocks until the reserved slot comes. The context's deadline limits the time to wait
+func waitForRateLimit(c context.Context, limiter RateLimiter) error {
+	maxWait := time.Duration(1<<63 - 1)
+	if deadline, ok := c.Deadline(); ok {
+		maxWait = deadline.Sub(rateLimiterNow())
+	}
+
+	if c.Value(noRateLimitWaitContextKey{}) != nil {
+		maxWait = 0
+	}
+
+	delay, reserved, err := limiter.Reserve(maxWait)
+	if err != nil {
+		return err
+	}
+
+	if !reserved {
+		return ErrRateLimitExceeded
+	}
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-c.Done():
+		// Don't hide the cancellation of the request behind the rate limit error
+		if errors.Is(c.Err(), context.Canceled) {
+			return c.Err()
+		}
+
+		return ErrRateLimitExceeded
+	case <-timer.C:
+		return nil
+	}
+}
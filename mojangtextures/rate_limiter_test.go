@@ -0,0 +1,128 @@
+package mojangtextures
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rateLimiterFunc func(maxWait time.Duration) (time.Duration, bool, error)
+
+func (f rateLimiterFunc) Reserve(maxWait time.Duration) (time.Duration, bool, error) {
+	return f(maxWait)
+}
+
+func TestLocalRateLimiter(t *testing.T) {
+	now := time.Now()
+	rateLimiterNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		rateLimiterNow = time.Now
+	}()
+
+	t.Run("reserve consecutive slots", func(t *testing.T) {
+		limiter := &LocalRateLimiter{Interval: time.Second}
+
+		delay, reserved, err := limiter.Reserve(time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, reserved)
+		assert.Equal(t, time.Duration(0), delay)
+
+		delay, reserved, _ = limiter.Reserve(time.Minute)
+		assert.True(t, reserved)
+		assert.Equal(t, time.Second, delay)
+
+		delay, reserved, _ = limiter.Reserve(time.Minute)
+		assert.True(t, reserved)
+		assert.Equal(t, 2*time.Second, delay)
+	})
+
+	t.Run("don't reserve the slot beyond the max wait", func(t *testing.T) {
+		limiter := &LocalRateLimiter{Interval: time.Second}
+
+		_, reserved, _ := limiter.Reserve(0)
+		assert.True(t, reserved)
+
+		_, reserved, _ = limiter.Reserve(500 * time.Millisecond)
+		assert.False(t, reserved)
+
+		delay, reserved, _ := limiter.Reserve(time.Second)
+		assert.True(t, reserved)
+		assert.Equal(t, time.Second, delay, "the rejected request must not take the slot")
+	})
+
+	t.Run("don't accumulate slots while idle", func(t *testing.T) {
+		limiter := &LocalRateLimiter{Interval: time.Second}
+		_, _, _ = limiter.Reserve(0)
+
+		now = now.Add(time.Minute)
+		delay, _, _ := limiter.Reserve(0)
+		assert.Equal(t, time.Duration(0), delay)
+
+		_, reserved, _ := limiter.Reserve(0)
+		assert.False(t, reserved)
+	})
+}
+
+func TestWaitForRateLimit(t *testing.T) {
+	t.Run("pass the time left until the deadline", func(t *testing.T) {
+		c, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		err := waitForRateLimit(c, rateLimiterFunc(func(maxWait time.Duration) (time.Duration, bool, error) {
+			assert.True(t, maxWait > 59*time.Second && maxWait <= time.Minute)
+			return 10 * time.Millisecond, true, nil
+		}))
+		assert.Nil(t, err)
+	})
+
+	t.Run("context without wait", func(t *testing.T) {
+		c, cancel := context.WithTimeout(withoutRateLimitWait(context.Background()), time.Minute)
+		defer cancel()
+
+		err := waitForRateLimit(c, rateLimiterFunc(func(maxWait time.Duration) (time.Duration, bool, error) {
+			assert.Equal(t, time.Duration(0), maxWait)
+			return 0, false, nil
+		}))
+		assert.Equal(t, ErrRateLimitExceeded, err)
+	})
+
+	t.Run("slot isn't reserved", func(t *testing.T) {
+		err := waitForRateLimit(context.Background(), rateLimiterFunc(func(maxWait time.Duration) (time.Duration, bool, error) {
+			return 0, false, nil
+		}))
+		assert.Equal(t, ErrRateLimitExceeded, err)
+	})
+
+	t.Run("limiter error", func(t *testing.T) {
+		expectedErr := errors.New("mock error")
+		err := waitForRateLimit(context.Background(), rateLimiterFunc(func(maxWait time.Duration) (time.Duration, bool, error) {
+			return 0, false, expectedErr
+		}))
+		assert.Equal(t, expectedErr, err)
+	})
+
+	t.Run("context deadline is exceeded while waiting", func(t *testing.T) {
+		c, cancel := context.WithTimeout(context.Background(), 0)
+		defer cancel()
+
+		err := waitForRateLimit(c, rateLimiterFunc(func(maxWait time.Duration) (time.Duration, bool, error) {
+			return time.Minute, true, nil
+		}))
+		assert.Equal(t, ErrRateLimitExceeded, err)
+	})
+
+	t.Run("context is canceled while waiting", func(t *testing.T) {
+		c, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := waitForRateLimit(c, rateLimiterFunc(func(maxWait time.Duration) (time.Duration, bool, error) {
+			return time.Minute, true, nil
+		}))
+		assert.Equal(t, context.Canceled, err)
+	})
+}
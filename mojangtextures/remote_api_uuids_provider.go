@@ -1,13 +1,20 @@
 package mojangtextures
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	. "net/url"
 	"path"
+	"sync"
+	"time"
 
 	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/requestinfo"
 	"github.com/elyby/chrly/version"
 )
 
@@ -17,23 +24,181 @@ var HttpClient = &http.Client{
 	},
 }
 
+var remoteApiNow = time.Now
+
+type WorkersBalancing string
+
+const (
+	// LeastInFlightBalancing sends the request to the worker with the fewest number of requests in progress
+	LeastInFlightBalancing WorkersBalancing = "least-in-flight"
+	// RoundRobinBalancing sends requests to the workers in turn
+	RoundRobinBalancing WorkersBalancing = "round-robin"
+)
+
+var ErrNoAvailableRemoteApiWorkers = errors.New("all remote api workers are on cooldown")
+
+type RemoteApiWorker struct {
+	Url URL
+
+	lock          sync.Mutex
+	inFlight      int
+	cooldownUntil time.Time
+	lastError     error
+}
+
+// Status returns nil when the worker is available or the error, which has put the worker on cooldown
+func (w *RemoteApiWorker) Status() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if !w.isOnCooldown() {
+		return nil
+	}
+
+	return fmt.Errorf("on cooldown until %s: %w", w.cooldownUntil.Format(time.RFC3339), w.lastError)
+}
+
+func (w *RemoteApiWorker) isOnCooldown() bool {
+	return remoteApiNow().Before(w.cooldownUntil)
+}
+
 type RemoteApiUuidsProvider struct {
 	Emitter
-	Url URL
+	Workers   []*RemoteApiWorker
+	Balancing WorkersBalancing
+	// Cooldown is the duration, during which the worker won't receive requests
+	// after it has responded with an unexpected response or has been unreachable
+	Cooldown time.Duration
+	// Token is sent in the Authorization header to authenticate on the workers
+	Token string
+	// Client allows to configure the client certificate for the workers, which require mTLS.
+	// HttpClient is used when not set
+	Client *http.Client
+
+	lock sync.Mutex
+	next int
+}
+
+// Status returns an error when there are no workers available to process requests
+func (ctx *RemoteApiUuidsProvider) Status() error {
+	for _, worker := range ctx.Workers {
+		if worker.Status() == nil {
+			return nil
+		}
+	}
+
+	return ErrNoAvailableRemoteApiWorkers
 }
 
-func (ctx *RemoteApiUuidsProvider) GetUuid(username string) (*mojang.ProfileInfo, error) {
-	url := ctx.Url
+func (ctx *RemoteApiUuidsProvider) GetUuid(c context.Context, username string) (*mojang.ProfileInfo, error) {
+	worker := ctx.acquireWorker()
+	if worker == nil {
+		return nil, ErrNoAvailableRemoteApiWorkers
+	}
+
+	profile, err := ctx.requestWorker(c, worker, username)
+	ctx.releaseWorker(worker, err)
+
+	return profile, err
+}
+
+// GetUuids sends all the usernames at once to the batch endpoint of the worker.
+// In this mode the workers' urls must point to the batch endpoint
+func (ctx *RemoteApiUuidsProvider) GetUuids(c context.Context, usernames []string) ([]*mojang.ProfileInfo, error) {
+	worker := ctx.acquireWorker()
+	if worker == nil {
+		return nil, ErrNoAvailableRemoteApiWorkers
+	}
+
+	profiles, err := ctx.requestWorkerBatch(c, worker, usernames)
+	ctx.releaseWorker(worker, err)
+
+	return profiles, err
+}
+
+// acquireWorker selects the next worker according to the balancing strategy and increments its in-flight counter
+func (ctx *RemoteApiUuidsProvider) acquireWorker() *RemoteApiWorker {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	var selected *RemoteApiWorker
+	workersCount := len(ctx.Workers)
+	for i := 0; i < workersCount; i++ {
+		idx := (ctx.next + i) % workersCount
+		worker := ctx.Workers[idx]
+		worker.lock.Lock()
+		if worker.isOnCooldown() {
+			worker.lock.Unlock()
+			continue
+		}
+
+		if ctx.Balancing == RoundRobinBalancing {
+			worker.lock.Unlock()
+			selected = worker
+			ctx.next = idx + 1
+			break
+		}
+
+		if selected == nil || worker.inFlight < selected.inFlight {
+			selected = worker
+		}
+
+		worker.lock.Unlock()
+	}
+
+	if selected == nil {
+		return nil
+	}
+
+	selected.lock.Lock()
+	selected.inFlight++
+	selected.lock.Unlock()
+
+	return selected
+}
+
+func (ctx *RemoteApiUuidsProvider) releaseWorker(worker *RemoteApiWorker, err error) {
+	worker.lock.Lock()
+	defer worker.lock.Unlock()
+
+	worker.inFlight--
+
+	// The request canceled by the caller doesn't say anything about the worker's state
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	// The worker, which is rate limited by Mojang, won't succeed until the delay passes
+	var tooManyRequestsErr *mojang.TooManyRequestsError
+	if errors.As(err, &tooManyRequestsErr) {
+		cooldown := tooManyRequestsErr.RetryAfter
+		if cooldown == 0 {
+			cooldown = ctx.Cooldown
+		}
+
+		worker.lastError = err
+		worker.cooldownUntil = remoteApiNow().Add(cooldown)
+		return
+	}
+
+	var unexpectedResponseErr *UnexpectedRemoteApiResponse
+	var urlErr *Error
+	if errors.As(err, &unexpectedResponseErr) || errors.As(err, &urlErr) {
+		worker.lastError = err
+		worker.cooldownUntil = remoteApiNow().Add(ctx.Cooldown)
+	}
+}
+
+func (ctx *RemoteApiUuidsProvider) requestWorker(c context.Context, worker *RemoteApiWorker, username string) (*mojang.ProfileInfo, error) {
+	url := worker.Url
 	url.Path = path.Join(url.Path, username)
 	urlStr := url.String()
 
-	request, _ := http.NewRequest("GET", urlStr, nil)
-	request.Header.Add("Accept", "application/json")
-	// Change default User-Agent to allow specify "Username -> UUID at time" Mojang's api endpoint
-	request.Header.Add("User-Agent", "Chrly/"+version.Version())
+	request, _ := http.NewRequestWithContext(c, "GET", urlStr, nil)
+	ctx.setRequestHeaders(request)
 
 	ctx.Emit("mojang_textures:remote_api_uuids_provider:before_request", urlStr)
-	response, err := HttpClient.Do(request)
+	response, err := ctx.httpClient().Do(request)
 	ctx.Emit("mojang_textures:remote_api_uuids_provider:after_request", response, err)
 	if err != nil {
 		return nil, err
@@ -44,6 +209,10 @@ func (ctx *RemoteApiUuidsProvider) GetUuid(username string) (*mojang.ProfileInfo
 		return nil, nil
 	}
 
+	if response.StatusCode == 429 {
+		return nil, newWorkerTooManyRequestsError(response)
+	}
+
 	if response.StatusCode != 200 {
 		return nil, &UnexpectedRemoteApiResponse{response}
 	}
@@ -58,6 +227,70 @@ func (ctx *RemoteApiUuidsProvider) GetUuid(username string) (*mojang.ProfileInfo
 	return result, nil
 }
 
+func (ctx *RemoteApiUuidsProvider) requestWorkerBatch(c context.Context, worker *RemoteApiWorker, usernames []string) ([]*mojang.ProfileInfo, error) {
+	urlStr := worker.Url.String()
+	requestBody, _ := json.Marshal(usernames)
+
+	request, _ := http.NewRequestWithContext(c, "POST", urlStr, bytes.NewReader(requestBody))
+	ctx.setRequestHeaders(request)
+	request.Header.Set("Content-Type", "application/json")
+
+	ctx.Emit("mojang_textures:remote_api_uuids_provider:before_request", urlStr)
+	response, err := ctx.httpClient().Do(request)
+	ctx.Emit("mojang_textures:remote_api_uuids_provider:after_request", response, err)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == 429 {
+		return nil, newWorkerTooManyRequestsError(response)
+	}
+
+	if response.StatusCode != 200 {
+		return nil, &UnexpectedRemoteApiResponse{response}
+	}
+
+	var result []*mojang.ProfileInfo
+	body, _ := ioutil.ReadAll(response.Body)
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (ctx *RemoteApiUuidsProvider) setRequestHeaders(request *http.Request) {
+	request.Header.Add("Accept", "application/json")
+	// Change default User-Agent to allow specify "Username -> UUID at time" Mojang's api endpoint
+	request.Header.Add("User-Agent", "Chrly/"+version.Version())
+	if ctx.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+ctx.Token)
+	}
+
+	// Pass the id of the request that has initiated this call to be able to find it in the worker's logs
+	if requestId := requestinfo.RequestId(request.Context()); requestId != "" {
+		request.Header.Set(requestinfo.RequestIdHeader, requestId)
+	}
+}
+
+func (ctx *RemoteApiUuidsProvider) httpClient() *http.Client {
+	if ctx.Client != nil {
+		return ctx.Client
+	}
+
+	return HttpClient
+}
+
+// newWorkerTooManyRequestsError restores the Mojang's error, which is passed through by the worker,
+// so the queue strategy can back off the same way as when Mojang is requested directly
+func newWorkerTooManyRequestsError(response *http.Response) *mojang.TooManyRequestsError {
+	return &mojang.TooManyRequestsError{
+		RetryAfter: mojang.ParseRetryAfter(response.Header.Get("Retry-After")),
+	}
+}
+
 type UnexpectedRemoteApiResponse struct {
 	Response *http.Response
 }
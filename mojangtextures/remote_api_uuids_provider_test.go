@@ -1,15 +1,20 @@
 package mojangtextures
 
 import (
+	"context"
 	"net"
 	"net/http"
 	. "net/url"
 	"testing"
+	"time"
 
 	"github.com/h2non/gock"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+
+	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/requestinfo"
 )
 
 type remoteApiUuidsProviderTestSuite struct {
@@ -58,8 +63,8 @@ func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForValidUsername() {
 			"name": "username",
 		})
 
-	suite.Provider.Url = shouldParseUrl("http://example.com/subpath")
-	result, err := suite.Provider.GetUuid("username")
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com/subpath")}}
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
 
 	assert := suite.Assert()
 	if assert.NoError(err) {
@@ -70,6 +75,51 @@ func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForValidUsername() {
 	}
 }
 
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidShouldForwardRequestId() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/subpath/username").Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_uuids_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+
+	gock.New("http://example.com").
+		Get("/subpath/username").
+		MatchHeader("X-Request-Id", "mock-request-id").
+		Reply(204)
+
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com/subpath")}}
+	result, err := suite.Provider.GetUuid(requestinfo.WithRequestId(context.Background(), "mock-request-id"), "username")
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	assert.Nil(err)
+	assert.True(gock.IsDone())
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidShouldSendToken() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/subpath/username").Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_uuids_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+
+	gock.New("http://example.com").
+		Get("/subpath/username").
+		MatchHeader("Authorization", "Bearer mock-token").
+		Reply(204)
+
+	suite.Provider.Token = "mock-token"
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com/subpath")}}
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	assert.Nil(err)
+	assert.True(gock.IsDone())
+}
+
 func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForNotExistsUsername() {
 	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/subpath/username").Once()
 	suite.Emitter.On("Emit",
@@ -82,8 +132,8 @@ func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForNotExistsUsername()
 		Get("/subpath/username").
 		Reply(204)
 
-	suite.Provider.Url = shouldParseUrl("http://example.com/subpath")
-	result, err := suite.Provider.GetUuid("username")
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com/subpath")}}
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
 
 	assert := suite.Assert()
 	assert.Nil(result)
@@ -103,8 +153,8 @@ func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForNon20xResponse() {
 		Reply(504).
 		BodyString("504 Gateway Timeout")
 
-	suite.Provider.Url = shouldParseUrl("http://example.com/subpath")
-	result, err := suite.Provider.GetUuid("username")
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com/subpath")}}
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
 
 	assert := suite.Assert()
 	assert.Nil(result)
@@ -125,8 +175,8 @@ func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForNotSuccessRequest()
 		Get("/subpath/username").
 		ReplyError(expectedError)
 
-	suite.Provider.Url = shouldParseUrl("http://example.com/subpath")
-	result, err := suite.Provider.GetUuid("username")
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com/subpath")}}
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
 
 	assert := suite.Assert()
 	assert.Nil(result)
@@ -150,14 +200,217 @@ func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForInvalidSuccessRespon
 		Reply(200).
 		BodyString("completely not json")
 
-	suite.Provider.Url = shouldParseUrl("http://example.com/subpath")
-	result, err := suite.Provider.GetUuid("username")
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com/subpath")}}
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
 
 	assert := suite.Assert()
 	assert.Nil(result)
 	assert.Error(err)
 }
 
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidWithRoundRobinBalancing() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", mock.Anything).Times(3)
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:after_request", mock.Anything, nil).Times(3)
+
+	gock.New("http://worker1.com").Get("/username").Times(2).Reply(204)
+	gock.New("http://worker2.com").Get("/username").Reply(204)
+
+	suite.Provider.Balancing = RoundRobinBalancing
+	suite.Provider.Workers = []*RemoteApiWorker{
+		{Url: shouldParseUrl("http://worker1.com")},
+		{Url: shouldParseUrl("http://worker2.com")},
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := suite.Provider.GetUuid(context.Background(), "username")
+		suite.Assert().NoError(err)
+	}
+
+	suite.Assert().True(gock.IsDone())
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidWithLeastInFlightBalancing() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://worker2.com/username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:after_request", mock.Anything, nil).Once()
+
+	gock.New("http://worker2.com").Get("/username").Reply(204)
+
+	suite.Provider.Balancing = LeastInFlightBalancing
+	suite.Provider.Workers = []*RemoteApiWorker{
+		{Url: shouldParseUrl("http://worker1.com"), inFlight: 2},
+		{Url: shouldParseUrl("http://worker2.com"), inFlight: 1},
+		{Url: shouldParseUrl("http://worker3.com"), inFlight: 3},
+	}
+
+	_, err := suite.Provider.GetUuid(context.Background(), "username")
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(1, suite.Provider.Workers[1].inFlight)
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidShouldPutWorkerOnCooldown() {
+	now := time.Date(2021, 2, 25, 1, 50, 23, 0, time.UTC)
+	remoteApiNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		remoteApiNow = time.Now
+	}()
+
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", mock.Anything).Times(3)
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:after_request", mock.Anything, nil).Times(3)
+
+	gock.New("http://worker1.com").Get("/username").Reply(502)
+	gock.New("http://worker2.com").Get("/username").Reply(200).BodyString("invalid json")
+	gock.New("http://worker2.com").Get("/username").Reply(500)
+
+	suite.Provider.Cooldown = time.Minute
+	suite.Provider.Workers = []*RemoteApiWorker{
+		{Url: shouldParseUrl("http://worker1.com")},
+		{Url: shouldParseUrl("http://worker2.com")},
+	}
+
+	assert := suite.Assert()
+
+	_, err := suite.Provider.GetUuid(context.Background(), "username")
+	assert.IsType(&UnexpectedRemoteApiResponse{}, err)
+	assert.EqualError(suite.Provider.Workers[0].Status(), "on cooldown until 2021-02-25T01:51:23Z: Unexpected remote api response")
+	assert.NoError(suite.Provider.Status())
+
+	// Invalid response body isn't a reason to put the worker on cooldown
+	_, err = suite.Provider.GetUuid(context.Background(), "username")
+	assert.Error(err)
+	assert.NoError(suite.Provider.Workers[1].Status())
+
+	_, err = suite.Provider.GetUuid(context.Background(), "username")
+	assert.IsType(&UnexpectedRemoteApiResponse{}, err)
+	assert.Error(suite.Provider.Workers[1].Status())
+
+	_, err = suite.Provider.GetUuid(context.Background(), "username")
+	assert.ErrorIs(err, ErrNoAvailableRemoteApiWorkers)
+	assert.ErrorIs(suite.Provider.Status(), ErrNoAvailableRemoteApiWorkers)
+
+	now = now.Add(time.Minute)
+	assert.NoError(suite.Provider.Workers[0].Status())
+	assert.NoError(suite.Provider.Status())
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForTooManyRequestsResponse() {
+	now := time.Date(2021, 2, 25, 1, 50, 23, 0, time.UTC)
+	remoteApiNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		remoteApiNow = time.Now
+	}()
+
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", mock.Anything).Once()
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:after_request", mock.Anything, nil).Once()
+
+	gock.New("http://example.com").Get("/username").Reply(429).SetHeader("Retry-After", "30")
+
+	suite.Provider.Cooldown = time.Minute
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com")}}
+	_, err := suite.Provider.GetUuid(context.Background(), "username")
+
+	assert := suite.Assert()
+	assert.Equal(&mojang.TooManyRequestsError{RetryAfter: 30 * time.Second}, err)
+	assert.EqualError(suite.Provider.Workers[0].Status(), "on cooldown until 2021-02-25T01:50:53Z: 429: Too Many Requests")
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuids() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/api/worker/mojang-uuids").Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_uuids_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+
+	gock.New("http://example.com").
+		Post("/api/worker/mojang-uuids").
+		JSON([]string{"username1", "username2"}).
+		Reply(200).
+		JSON([]map[string]interface{}{
+			{"id": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "name": "username1"},
+		})
+
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com/api/worker/mojang-uuids")}}
+	result, err := suite.Provider.GetUuids(context.Background(), []string{"username1", "username2"})
+
+	assert := suite.Assert()
+	if assert.NoError(err) && assert.Len(result, 1) {
+		assert.Equal("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", result[0].Id)
+		assert.Equal("username1", result[0].Name)
+	}
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidsShouldForwardRequestId() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/api/worker/mojang-uuids").Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_uuids_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+
+	gock.New("http://example.com").
+		Post("/api/worker/mojang-uuids").
+		MatchHeader("X-Request-Id", "mock-request-id").
+		Reply(200).
+		JSON([]map[string]interface{}{})
+
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com/api/worker/mojang-uuids")}}
+	result, err := suite.Provider.GetUuids(requestinfo.WithRequestId(context.Background(), "mock-request-id"), []string{"username1"})
+
+	assert := suite.Assert()
+	assert.NoError(err)
+	assert.Empty(result)
+	assert.True(gock.IsDone())
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidsForNon200Response() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/api/worker/mojang-uuids").Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_uuids_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+
+	gock.New("http://example.com").
+		Post("/api/worker/mojang-uuids").
+		Reply(503)
+
+	suite.Provider.Cooldown = time.Minute
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com/api/worker/mojang-uuids")}}
+	result, err := suite.Provider.GetUuids(context.Background(), []string{"username1"})
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	assert.IsType(&UnexpectedRemoteApiResponse{}, err)
+	assert.Error(suite.Provider.Workers[0].Status())
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidsForTooManyRequestsResponse() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/api/worker/mojang-uuids").Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_uuids_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+
+	gock.New("http://example.com").
+		Post("/api/worker/mojang-uuids").
+		Reply(429).
+		SetHeader("Retry-After", "10")
+
+	suite.Provider.Workers = []*RemoteApiWorker{{Url: shouldParseUrl("http://example.com/api/worker/mojang-uuids")}}
+	result, err := suite.Provider.GetUuids(context.Background(), []string{"username1"})
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	// The adaptive strategy must back off the same way as when Mojang responds with 429
+	assert.Equal(&mojang.TooManyRequestsError{RetryAfter: 10 * time.Second}, err)
+	assert.Error(suite.Provider.Workers[0].Status())
+}
+
 func shouldParseUrl(rawUrl string) URL {
 	url, err := Parse(rawUrl)
 	if err != nil {
@@ -0,0 +1,480 @@
+package mojangtextures
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elyby/chrly/api/mojang"
+)
+
+var sharedStrategyNow = time.Now
+
+var ErrSharedResultTimeout = errors.New("the result of the shared queue hasn't been received in time")
+
+// SharedQueue is the storage of the usernames queue, which is shared between the replicas
+type SharedQueue interface {
+	Push(lane string, username string) error
+	// Pop moves up to n usernames from the lanes in their order into the processing lists of the lanes
+	// and returns them with the lengths of the lanes left after the pop
+	Pop(lanes []string, n int) ([]string, []int, error)
+	// Ack removes the usernames, which have been processed, from the processing lists of the lanes
+	Ack(lanes []string, usernames []string) error
+	// Requeue moves the usernames left in the processing lists back to the head of their lanes
+	Requeue(lanes []string) error
+	// AcquireLeadership returns true when the leadership is held by the id for the next ttl
+	AcquireLeadership(id string, ttl time.Duration) (bool, error)
+	// Publish delivers the message to all the subscribed replicas
+	Publish(message []byte) error
+	// Subscribe calls the handler for each published message until the ctx is done
+	Subscribe(ctx context.Context, handler func(message []byte)) error
+}
+
+// SharedStrategy queues the usernames into the storage, which is shared between the replicas.
+// Only one of them, the leader, takes the usernames from the storage and performs the requests.
+// The results are published back to all replicas, so each of them responds to its own callers.
+// The usernames taken by the leader stay in the storage until their results are published,
+// so the new leader queues them again when the previous one has failed
+type SharedStrategy struct {
+	Emitter
+	Storage SharedQueue
+	// Id distinguishes the replica in the leader election
+	Id    string
+	Delay time.Duration
+	Batch int
+	// The leader keeps the leadership while it renews it within the LeadershipTtl.
+	// It's renewed each Delay, including the time of the request
+	LeadershipTtl time.Duration
+	// The username, whose result hasn't been received within the ResultTimeout, is pushed once again.
+	// Its jobs fail with ErrSharedResultTimeout when the result of the second push hasn't been received either.
+	// The results are awaited without the limit when it's zero
+	ResultTimeout time.Duration
+	// CircuitBreaker is optional. The results of the leader's requests are reported to it,
+	// so the breakers of all the replicas follow the state of the Mojang's API
+	CircuitBreaker *CircuitBreaker
+
+	lock    sync.Mutex
+	waiting map[string][]*job
+	queued  map[string]*sharedQueuedUsername
+	// isLeader is accessed only by the jobs loop
+	isLeader bool
+	// The error of the leader's request, which is reported during the round
+	requestErr      error
+	requestReported bool
+}
+
+type sharedQueuedUsername struct {
+	username string
+	priority Priority
+	pushedAt time.Time
+	pushes   int
+}
+
+func NewSharedStrategy(emitter Emitter, storage SharedQueue, delay time.Duration, batch int, leadershipTtl time.Duration) *SharedStrategy {
+	return &SharedStrategy{
+		Emitter:       emitter,
+		Storage:       storage,
+		Id:            newReplicaId(),
+		Delay:         delay,
+		Batch:         batch,
+		LeadershipTtl: leadershipTtl,
+		ResultTimeout: time.Minute,
+		waiting:       map[string][]*job{},
+		queued:        map[string]*sharedQueuedUsername{},
+	}
+}
+
+func (ctx *SharedStrategy) Queue(job *job) {
+	username := strings.ToLower(job.Username)
+	priority := job.Priority
+	if priority == "" {
+		priority = PriorityHigh
+	}
+
+	ctx.lock.Lock()
+	queued, isQueued := ctx.queued[username]
+	ctx.waiting[username] = append(ctx.waiting[username], job)
+	// The username already in the queue will bring the result to the job as well, unless it's waiting
+	// in the low priority lane and the job must not be delayed. The retried job has already been popped
+	// from the queue, so it must be pushed again anyway
+	isPushed := isQueued && job.Retries == 0 && (priority == PriorityLow || queued.priority == PriorityHigh)
+	if !isPushed {
+		ctx.queued[username] = &sharedQueuedUsername{
+			username: job.Username,
+			priority: priority,
+			pushedAt: sharedStrategyNow(),
+			pushes:   1,
+		}
+	}
+	ctx.lock.Unlock()
+
+	if isPushed {
+		return
+	}
+
+	err := ctx.Storage.Push(string(priority), job.Username)
+	if err != nil {
+		ctx.Emit("mojang_textures:shared_strategy:error", err)
+		ctx.respond(username, &jobResult{Error: err})
+	}
+}
+
+func (ctx *SharedStrategy) GetJobs(abort context.Context) <-chan *JobsIteration {
+	ch := make(chan *JobsIteration)
+	go ctx.subscribe(abort)
+	go func() {
+		for {
+			select {
+			case <-abort.Done():
+				close(ch)
+				return
+			case <-time.After(ctx.Delay):
+				ctx.dropCanceledJobs()
+				ctx.checkResultTimeouts()
+				if ctx.acquireLeadership() {
+					ctx.performRound(ch)
+				}
+			}
+		}
+	}()
+
+	return ch
+}
+
+// ReportResult receives the result of the leader's request to publish it for the other replicas
+func (ctx *SharedStrategy) ReportResult(err error) {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	ctx.requestErr = err
+	ctx.requestReported = true
+}
+
+func (ctx *SharedStrategy) acquireLeadership() bool {
+	isLeader, err := ctx.Storage.AcquireLeadership(ctx.Id, ctx.LeadershipTtl)
+	if err != nil {
+		ctx.Emit("mojang_textures:shared_strategy:error", err)
+		isLeader = false
+	}
+
+	// The previous leader may have failed before the results of the usernames taken by it were published
+	if isLeader && !ctx.isLeader {
+		err = ctx.Storage.Requeue(ctx.lanes())
+		if err != nil {
+			ctx.Emit("mojang_textures:shared_strategy:error", err)
+			return false
+		}
+	}
+
+	ctx.isLeader = isLeader
+
+	return isLeader
+}
+
+func (ctx *SharedStrategy) performRound(ch chan *JobsIteration) {
+	lanes := ctx.lanes()
+	usernames, lengths, err := ctx.Storage.Pop(lanes, ctx.Batch)
+	if err != nil {
+		ctx.Emit("mojang_textures:shared_strategy:error", err)
+		return
+	}
+
+	lanesLengths := make(map[Priority]int, len(priorities))
+	for i, priority := range priorities {
+		lanesLengths[priority] = lengths[i]
+	}
+
+	// The callers of these jobs are waiting on the other replicas, so the results are published to reach them.
+	// Each replica queues the username independently, so the same username may be popped several times
+	jobs := make([]*job, 0, len(usernames))
+	popped := make(map[string]bool, len(usernames))
+	for _, username := range usernames {
+		key := strings.ToLower(username)
+		if popped[key] {
+			continue
+		}
+
+		popped[key] = true
+		jobs = append(jobs, &job{Username: username, RespondChan: make(chan *jobResult, 1)})
+	}
+
+	ctx.lock.Lock()
+	ctx.requestErr = nil
+	ctx.requestReported = false
+	ctx.lock.Unlock()
+
+	jobDoneChan := make(chan struct{})
+	ch <- newJobsIteration(jobs, lanesLengths, jobDoneChan)
+	// The usernames of the round are requeued by the new leader, which will publish their results instead.
+	// Acking them would remove them from the processing lists of the new leader
+	if !ctx.keepLeadership(jobDoneChan) {
+		ctx.isLeader = false
+		return
+	}
+
+	ctx.lock.Lock()
+	requestErr, requestReported := ctx.requestErr, ctx.requestReported
+	ctx.lock.Unlock()
+	if requestReported {
+		ctx.publishMessage(&sharedJobResult{Leader: ctx.Id, Error: encodeSharedJobError(requestErr)})
+	}
+
+	for _, job := range jobs {
+		select {
+		case result := <-job.RespondChan:
+			ctx.publish(job.Username, result)
+		default:
+			// The job was queued again for the retry, so its result will be published after the next attempt
+		}
+	}
+
+	if len(usernames) == 0 {
+		return
+	}
+
+	// The result of the username, which hasn't reached the other replicas, is requested again after the ResultTimeout
+	err = ctx.Storage.Ack(lanes, usernames)
+	if err != nil {
+		ctx.Emit("mojang_textures:shared_strategy:error", err)
+	}
+}
+
+// The request may last longer than the leadership, so it's renewed until the round is done.
+// Returns false when the leadership has been lost during the round
+func (ctx *SharedStrategy) keepLeadership(done <-chan struct{}) bool {
+	ticker := time.NewTicker(ctx.Delay)
+	defer ticker.Stop()
+
+	isLeader := true
+	for {
+		select {
+		case <-done:
+			return isLeader
+		case <-ticker.C:
+			if !isLeader {
+				continue
+			}
+
+			renewed, err := ctx.Storage.AcquireLeadership(ctx.Id, ctx.LeadershipTtl)
+			if err != nil {
+				ctx.Emit("mojang_textures:shared_strategy:error", err)
+			}
+
+			isLeader = renewed && err == nil
+		}
+	}
+}
+
+func (ctx *SharedStrategy) lanes() []string {
+	lanes := make([]string, len(priorities))
+	for i, priority := range priorities {
+		lanes[i] = string(priority)
+	}
+
+	return lanes
+}
+
+func (ctx *SharedStrategy) subscribe(abort context.Context) {
+	for {
+		err := ctx.Storage.Subscribe(abort, ctx.handleMessage)
+		if err == nil {
+			return
+		}
+
+		ctx.Emit("mojang_textures:shared_strategy:error", err)
+		select {
+		case <-abort.Done():
+			return
+		case <-time.After(ctx.Delay):
+		}
+	}
+}
+
+func (ctx *SharedStrategy) publish(username string, result *jobResult) {
+	ctx.publishMessage(&sharedJobResult{
+		Username: username,
+		Profile:  result.Profile,
+		Error:    encodeSharedJobError(result.Error),
+	})
+}
+
+func (ctx *SharedStrategy) publishMessage(result *sharedJobResult) {
+	message, _ := json.Marshal(result)
+	if err := ctx.Storage.Publish(message); err != nil {
+		ctx.Emit("mojang_textures:shared_strategy:error", err)
+	}
+}
+
+func (ctx *SharedStrategy) handleMessage(message []byte) {
+	var result *sharedJobResult
+	if err := json.Unmarshal(message, &result); err != nil || result == nil {
+		return
+	}
+
+	// The leader has already reported the result of its request to its own circuit breaker
+	if result.Leader != "" {
+		if result.Leader != ctx.Id && ctx.CircuitBreaker != nil {
+			ctx.CircuitBreaker.Observe(decodeSharedJobError(result.Error))
+		}
+
+		return
+	}
+
+	ctx.respond(strings.ToLower(result.Username), &jobResult{
+		Profile: result.Profile,
+		Error:   decodeSharedJobError(result.Error),
+	})
+}
+
+func (ctx *SharedStrategy) respond(username string, result *jobResult) {
+	ctx.lock.Lock()
+	jobs := ctx.waiting[username]
+	delete(ctx.waiting, username)
+	delete(ctx.queued, username)
+	ctx.lock.Unlock()
+
+	for _, job := range jobs {
+		job.respond(result)
+	}
+}
+
+// The result may never come when the leader has failed, so the jobs of the gone callers are dropped
+func (ctx *SharedStrategy) dropCanceledJobs() {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	for username, jobs := range ctx.waiting {
+		active := jobs[:0]
+		for _, job := range jobs {
+			if !job.isCanceled() {
+				active = append(active, job)
+			}
+		}
+
+		if len(active) == 0 {
+			delete(ctx.waiting, username)
+			delete(ctx.queued, username)
+		} else {
+			ctx.waiting[username] = active
+		}
+	}
+}
+
+// The published result may be lost, so the username is pushed once again and then its jobs fail
+func (ctx *SharedStrategy) checkResultTimeouts() {
+	if ctx.ResultTimeout <= 0 {
+		return
+	}
+
+	var expired []string
+	var repushed []*sharedQueuedUsername
+	ctx.lock.Lock()
+	for username, queued := range ctx.queued {
+		if sharedStrategyNow().Before(queued.pushedAt.Add(ctx.ResultTimeout)) {
+			continue
+		}
+
+		if queued.pushes > 1 {
+			expired = append(expired, username)
+			continue
+		}
+
+		queued.pushes++
+		queued.pushedAt = sharedStrategyNow()
+		repushed = append(repushed, queued)
+	}
+	ctx.lock.Unlock()
+
+	for _, username := range expired {
+		ctx.respond(username, &jobResult{Error: ErrSharedResultTimeout})
+	}
+
+	for _, queued := range repushed {
+		err := ctx.Storage.Push(string(queued.priority), queued.username)
+		if err != nil {
+			ctx.Emit("mojang_textures:shared_strategy:error", err)
+			ctx.respond(strings.ToLower(queued.username), &jobResult{Error: err})
+		}
+	}
+}
+
+type sharedJobResult struct {
+	Username string              `json:"username"`
+	Profile  *mojang.ProfileInfo `json:"profile,omitempty"`
+	Error    *sharedJobError     `json:"error,omitempty"`
+	// Leader is set for the result of the leader's request, which has no username
+	Leader string `json:"leader,omitempty"`
+}
+
+type sharedJobError struct {
+	Message string `json:"message"`
+	// Status is set for the Mojang's responses, so the errors of the known types can be restored
+	Status     int           `json:"status,omitempty"`
+	RetryAfter time.Duration `json:"retryAfter,omitempty"`
+}
+
+// The errors, which are checked by their identity, must stay the same after the delivery
+var sharedSentinelErrors = []error{
+	ErrCircuitBreakerOpen,
+	ErrNoAvailableRemoteApiWorkers,
+	ErrBatchUuidsProviderShutdown,
+	mojang.ErrNoAvailableProxies,
+}
+
+func encodeSharedJobError(err error) *sharedJobError {
+	if err == nil {
+		return nil
+	}
+
+	result := &sharedJobError{Message: err.Error()}
+
+	var serverErr *mojang.ServerError
+	var tooManyRequestsErr *mojang.TooManyRequestsError
+	var forbiddenErr *mojang.ForbiddenError
+	switch {
+	case errors.As(err, &serverErr):
+		result.Status = serverErr.Status
+	case errors.As(err, &tooManyRequestsErr):
+		result.Status = 429
+		result.RetryAfter = tooManyRequestsErr.RetryAfter
+	case errors.As(err, &forbiddenErr):
+		result.Status = 403
+	}
+
+	return result
+}
+
+func decodeSharedJobError(err *sharedJobError) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case err.Status >= 500:
+		return &mojang.ServerError{Status: err.Status}
+	case err.Status == 429:
+		return &mojang.TooManyRequestsError{RetryAfter: err.RetryAfter}
+	case err.Status == 403:
+		return &mojang.ForbiddenError{}
+	}
+
+	for _, sentinel := range sharedSentinelErrors {
+		if sentinel.Error() == err.Message {
+			return sentinel
+		}
+	}
+
+	return errors.New(err.Message)
+}
+
+func newReplicaId() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
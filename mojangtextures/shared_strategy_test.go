@@ -0,0 +1,395 @@
+package mojangtextures
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elyby/chrly/api/mojang"
+)
+
+type inMemorySharedQueue struct {
+	lock       sync.Mutex
+	lanes      map[string][]string
+	processing map[string][]string
+	leader     string
+	// acquisitions counts the attempts to acquire the leadership
+	acquisitions int
+	subscribers  []func(message []byte)
+	pushErr      error
+}
+
+func (q *inMemorySharedQueue) Push(lane string, username string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.pushErr != nil {
+		return q.pushErr
+	}
+
+	if q.lanes == nil {
+		q.lanes = map[string][]string{}
+	}
+
+	q.lanes[lane] = append(q.lanes[lane], username)
+
+	return nil
+}
+
+func (q *inMemorySharedQueue) Pop(lanes []string, n int) ([]string, []int, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.processing == nil {
+		q.processing = map[string][]string{}
+	}
+
+	var usernames []string
+	lengths := make([]int, len(lanes))
+	for i, lane := range lanes {
+		for len(usernames) < n && len(q.lanes[lane]) > 0 {
+			usernames = append(usernames, q.lanes[lane][0])
+			q.processing[lane] = append(q.processing[lane], q.lanes[lane][0])
+			q.lanes[lane] = q.lanes[lane][1:]
+		}
+
+		lengths[i] = len(q.lanes[lane])
+	}
+
+	return usernames, lengths, nil
+}
+
+func (q *inMemorySharedQueue) Ack(lanes []string, usernames []string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	acked := map[string]bool{}
+	for _, username := range usernames {
+		acked[username] = true
+	}
+
+	for _, lane := range lanes {
+		var left []string
+		for _, username := range q.processing[lane] {
+			if !acked[username] {
+				left = append(left, username)
+			}
+		}
+
+		q.processing[lane] = left
+	}
+
+	return nil
+}
+
+func (q *inMemorySharedQueue) Requeue(lanes []string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for _, lane := range lanes {
+		if len(q.processing[lane]) == 0 {
+			continue
+		}
+
+		if q.lanes == nil {
+			q.lanes = map[string][]string{}
+		}
+
+		q.lanes[lane] = append(q.processing[lane], q.lanes[lane]...)
+		delete(q.processing, lane)
+	}
+
+	return nil
+}
+
+func (q *inMemorySharedQueue) AcquireLeadership(id string, _ time.Duration) (bool, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.acquisitions++
+	if q.leader == "" {
+		q.leader = id
+	}
+
+	return q.leader == id, nil
+}
+
+func (q *inMemorySharedQueue) Publish(message []byte) error {
+	q.lock.Lock()
+	subscribers := append([]func(message []byte){}, q.subscribers...)
+	q.lock.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(message)
+	}
+
+	return nil
+}
+
+func (q *inMemorySharedQueue) Subscribe(ctx context.Context, handler func(message []byte)) error {
+	q.lock.Lock()
+	q.subscribers = append(q.subscribers, handler)
+	q.lock.Unlock()
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (q *inMemorySharedQueue) acquisitionsCount() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.acquisitions
+}
+
+func (q *inMemorySharedQueue) subscribersCount() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return len(q.subscribers)
+}
+
+func TestSharedStrategy(t *testing.T) {
+	t.Run("should deliver the result of the leader to the other replica", func(t *testing.T) {
+		storage := &inMemorySharedQueue{}
+		leader := NewSharedStrategy(&mockEmitter{}, storage, 10*time.Millisecond, 10, time.Second)
+		replica := NewSharedStrategy(&mockEmitter{}, storage, time.Hour, 10, time.Second)
+		_, _ = storage.AcquireLeadership(leader.Id, time.Second)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		_ = replica.GetJobs(ctx)
+		require.Eventually(t, func() bool {
+			return storage.subscribersCount() == 1
+		}, time.Second, time.Millisecond)
+
+		resultChan := make(chan *jobResult, 1)
+		replica.Queue(&job{Context: ctx, Username: "Username", RespondChan: resultChan, Priority: PriorityLow})
+		replica.Queue(&job{Context: ctx, Username: "interactive", RespondChan: make(chan *jobResult, 1)})
+
+		iterations := leader.GetJobs(ctx)
+		require.Eventually(t, func() bool {
+			return storage.subscribersCount() == 2
+		}, time.Second, time.Millisecond)
+
+		iteration := <-iterations
+		require.Len(t, iteration.Jobs, 2)
+		require.Equal(t, "interactive", iteration.Jobs[0].Username, "the high priority lane should be taken first")
+		require.Equal(t, "Username", iteration.Jobs[1].Username)
+		require.Equal(t, map[Priority]int{PriorityHigh: 0, PriorityLow: 0}, iteration.Lanes)
+
+		profile := &mojang.ProfileInfo{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+		iteration.Jobs[0].RespondChan <- &jobResult{Error: &mojang.ServerError{Status: 502}}
+		iteration.Jobs[1].RespondChan <- &jobResult{Profile: profile}
+		iteration.Done()
+
+		select {
+		case result := <-resultChan:
+			require.Equal(t, profile, result.Profile)
+			require.Nil(t, result.Error)
+		case <-time.After(time.Second):
+			t.Fatal("the result wasn't delivered")
+		}
+	})
+
+	t.Run("should requeue the usernames taken by the previous leader", func(t *testing.T) {
+		storage := &inMemorySharedQueue{}
+		_ = storage.Push(string(PriorityLow), "waiting")
+		_ = storage.Push(string(PriorityHigh), "taken")
+		// The previous leader has taken the username and failed
+		_, _, _ = storage.Pop([]string{string(PriorityHigh), string(PriorityLow)}, 1)
+
+		leader := NewSharedStrategy(&mockEmitter{}, storage, 10*time.Millisecond, 10, time.Second)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		iteration := <-leader.GetJobs(ctx)
+		require.Len(t, iteration.Jobs, 2)
+		require.Equal(t, "taken", iteration.Jobs[0].Username)
+		require.Equal(t, "waiting", iteration.Jobs[1].Username)
+		require.Equal(t, map[string][]string{
+			string(PriorityHigh): {"taken"},
+			string(PriorityLow):  {"waiting"},
+		}, storage.processing, "the usernames should stay in the processing lists until their results are published")
+
+		for _, job := range iteration.Jobs {
+			job.RespondChan <- &jobResult{}
+		}
+		iteration.Done()
+
+		require.Eventually(t, func() bool {
+			storage.lock.Lock()
+			defer storage.lock.Unlock()
+
+			return len(storage.processing[string(PriorityHigh)]) == 0 && len(storage.processing[string(PriorityLow)]) == 0
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("should leave the usernames to the new leader when the leadership has been lost during the round", func(t *testing.T) {
+		storage := &inMemorySharedQueue{}
+		leader := NewSharedStrategy(&mockEmitter{}, storage, 10*time.Millisecond, 10, time.Second)
+		_, _ = storage.AcquireLeadership(leader.Id, time.Second)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		resultChan := make(chan *jobResult, 1)
+		leader.Queue(&job{Context: ctx, Username: "username", RespondChan: resultChan})
+
+		iteration := <-leader.GetJobs(ctx)
+		require.Len(t, iteration.Jobs, 1)
+
+		// The leadership has expired while the request was in progress and another replica has taken it
+		storage.lock.Lock()
+		storage.leader = "another"
+		acquisitions := storage.acquisitions
+		storage.lock.Unlock()
+		require.Eventually(t, func() bool {
+			return storage.acquisitionsCount() > acquisitions
+		}, time.Second, time.Millisecond, "the leadership should be renewed during the round")
+
+		iteration.Jobs[0].RespondChan <- &jobResult{Profile: &mojang.ProfileInfo{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}}
+		iteration.Done()
+
+		require.Never(t, func() bool {
+			return len(resultChan) != 0
+		}, 50*time.Millisecond, time.Millisecond, "the result should be published by the new leader")
+
+		storage.lock.Lock()
+		defer storage.lock.Unlock()
+		require.Equal(t, []string{"username"}, storage.processing[string(PriorityHigh)], "the username should be left for the new leader")
+	})
+
+	t.Run("should report the result of the leader's request to the circuit breaker of the replica", func(t *testing.T) {
+		serverErr := &mojang.ServerError{Status: 502}
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "usernames", CircuitBreakerClosed, CircuitBreakerOpen, serverErr).Once()
+		breaker := &CircuitBreaker{Emitter: emitter, Name: "usernames", FailureThreshold: 1, OpenDuration: time.Minute}
+
+		storage := &inMemorySharedQueue{}
+		leader := NewSharedStrategy(&mockEmitter{}, storage, 10*time.Millisecond, 10, time.Second)
+		replica := NewSharedStrategy(&mockEmitter{}, storage, time.Hour, 10, time.Second)
+		replica.CircuitBreaker = breaker
+		_, _ = storage.AcquireLeadership(leader.Id, time.Second)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		_ = replica.GetJobs(ctx)
+		require.Eventually(t, func() bool {
+			return storage.subscribersCount() == 1
+		}, time.Second, time.Millisecond)
+
+		replica.Queue(&job{Context: ctx, Username: "username", RespondChan: make(chan *jobResult, 1)})
+
+		iteration := <-leader.GetJobs(ctx)
+		leader.ReportResult(serverErr)
+		iteration.Jobs[0].RespondChan <- &jobResult{Error: serverErr}
+		iteration.Done()
+
+		require.Eventually(t, func() bool {
+			return breaker.Check() != nil
+		}, time.Second, time.Millisecond)
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should respond with the error when the username can't be queued", func(t *testing.T) {
+		err := errors.New("mock error")
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:shared_strategy:error", err).Once()
+		strategy := NewSharedStrategy(emitter, &inMemorySharedQueue{pushErr: err}, time.Hour, 10, time.Second)
+
+		resultChan := make(chan *jobResult, 1)
+		strategy.Queue(&job{Username: "username", RespondChan: resultChan})
+
+		result := <-resultChan
+		require.Same(t, err, result.Error)
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should push the username once while it's waiting for the result", func(t *testing.T) {
+		storage := &inMemorySharedQueue{}
+		strategy := NewSharedStrategy(&mockEmitter{}, storage, time.Hour, 10, time.Second)
+		strategy.Queue(&job{Username: "username", RespondChan: make(chan *jobResult, 1)})
+		strategy.Queue(&job{Username: "USERNAME", RespondChan: make(chan *jobResult, 1)})
+		strategy.Queue(&job{Username: "username", RespondChan: make(chan *jobResult, 1), Retries: 1})
+
+		require.Equal(t, []string{"username", "username"}, storage.lanes[string(PriorityHigh)], "the retried job is pushed again")
+		require.Len(t, strategy.waiting["username"], 3)
+	})
+
+	t.Run("should push the waiting low priority username into the high priority lane", func(t *testing.T) {
+		storage := &inMemorySharedQueue{}
+		strategy := NewSharedStrategy(&mockEmitter{}, storage, time.Hour, 10, time.Second)
+		strategy.Queue(&job{Username: "username", RespondChan: make(chan *jobResult, 1), Priority: PriorityLow})
+		strategy.Queue(&job{Username: "username", RespondChan: make(chan *jobResult, 1), Priority: PriorityLow})
+		strategy.Queue(&job{Username: "username", RespondChan: make(chan *jobResult, 1)})
+		strategy.Queue(&job{Username: "username", RespondChan: make(chan *jobResult, 1), Priority: PriorityLow})
+
+		require.Equal(t, []string{"username"}, storage.lanes[string(PriorityLow)])
+		require.Equal(t, []string{"username"}, storage.lanes[string(PriorityHigh)])
+		require.Len(t, strategy.waiting["username"], 4)
+	})
+
+	t.Run("should push the username again and then fail when the result hasn't been received in time", func(t *testing.T) {
+		now := time.Now()
+		sharedStrategyNow = func() time.Time {
+			return now
+		}
+		defer func() {
+			sharedStrategyNow = time.Now
+		}()
+
+		storage := &inMemorySharedQueue{}
+		strategy := NewSharedStrategy(&mockEmitter{}, storage, time.Hour, 10, time.Second)
+		resultChan := make(chan *jobResult, 1)
+		strategy.Queue(&job{Username: "username", RespondChan: resultChan})
+
+		strategy.checkResultTimeouts()
+		require.Len(t, storage.lanes[string(PriorityHigh)], 1, "the timeout isn't reached yet")
+
+		now = now.Add(strategy.ResultTimeout)
+		strategy.checkResultTimeouts()
+		require.Len(t, storage.lanes[string(PriorityHigh)], 2)
+		require.Empty(t, resultChan)
+
+		now = now.Add(strategy.ResultTimeout)
+		strategy.checkResultTimeouts()
+		require.Len(t, storage.lanes[string(PriorityHigh)], 2)
+		require.Same(t, ErrSharedResultTimeout, (<-resultChan).Error)
+		require.Empty(t, strategy.waiting)
+		require.Empty(t, strategy.queued)
+	})
+
+	t.Run("should drop the jobs of the gone callers", func(t *testing.T) {
+		strategy := NewSharedStrategy(&mockEmitter{}, &inMemorySharedQueue{}, time.Hour, 10, time.Second)
+		ctx, cancel := context.WithCancel(context.Background())
+		strategy.Queue(&job{Context: ctx, Username: "username", RespondChan: make(chan *jobResult, 1)})
+		cancel()
+
+		strategy.dropCanceledJobs()
+		require.Empty(t, strategy.waiting)
+		require.Empty(t, strategy.queued)
+	})
+}
+
+func TestSharedJobError(t *testing.T) {
+	for _, err := range []error{
+		&mojang.ServerError{Status: 503},
+		&mojang.TooManyRequestsError{RetryAfter: time.Minute},
+		&mojang.ForbiddenError{},
+		ErrCircuitBreakerOpen,
+		mojang.ErrNoAvailableProxies,
+		errors.New("mock error"),
+	} {
+		require.Equal(t, err, decodeSharedJobError(encodeSharedJobError(err)))
+	}
+
+	require.Nil(t, decodeSharedJobError(encodeSharedJobError(nil)))
+}
@@ -13,6 +13,7 @@ type UUIDsStorage interface {
 	GetUuid(username string) (uuid string, found bool, err error)
 	// An empty uuid value can be passed if the corresponding account has not been found
 	StoreUuid(username string, uuid string) error
+	RemoveUuid(username string) error
 }
 
 // TexturesStorage is a Mojang's textures storage, used as a values cache to avoid 429 errors
@@ -22,6 +23,31 @@ type TexturesStorage interface {
 	GetTextures(uuid string) (*mojang.SignedTexturesResponse, error)
 	// The nil value can be passed when there are no textures for the corresponding uuid and we know about it
 	StoreTextures(uuid string, textures *mojang.SignedTexturesResponse)
+	// Removes both the fresh and the stale values
+	RemoveTextures(uuid string)
+}
+
+// StaleTexturesStorage is implemented by the textures storages, which keep the expired values for some time.
+// These values can be served while the fresh ones are being fetched or when Mojang fails to respond
+type StaleTexturesStorage interface {
+	// Returns the expired textures, which are still within the stale window. nil nil is returned
+	// when there is no such value or the stored value is still fresh
+	GetStaleTextures(uuid string) (*mojang.SignedTexturesResponse, error)
+}
+
+// LookupTexturesStorage is implemented by the textures storages, which can distinguish
+// the cached absence of the textures from the missing record
+type LookupTexturesStorage interface {
+	// The second argument indicates whether a not expired record was found in the storage
+	LookupTextures(uuid string) (textures *mojang.SignedTexturesResponse, found bool, err error)
+}
+
+// PeekableUUIDsStorage is implemented by the UUIDs storages, which can return the stored uuid
+// even when its record is already expired
+type PeekableUUIDsStorage interface {
+	// Returns the stored uuid without checking its expiration and without removing the expired record.
+	// An empty value is returned when there is no record or the record has an empty uuid
+	PeekUuid(username string) (uuid string, err error)
 }
 
 type Storage interface {
@@ -44,10 +70,47 @@ func (s *SeparatedStorage) StoreUuid(username string, uuid string) error {
 	return s.UUIDsStorage.StoreUuid(username, uuid)
 }
 
+func (s *SeparatedStorage) PeekUuid(username string) (string, error) {
+	peekableStorage, ok := s.UUIDsStorage.(PeekableUUIDsStorage)
+	if !ok {
+		uuid, _, err := s.UUIDsStorage.GetUuid(username)
+		return uuid, err
+	}
+
+	return peekableStorage.PeekUuid(username)
+}
+
+func (s *SeparatedStorage) RemoveUuid(username string) error {
+	return s.UUIDsStorage.RemoveUuid(username)
+}
+
 func (s *SeparatedStorage) GetTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
 	return s.TexturesStorage.GetTextures(uuid)
 }
 
+func (s *SeparatedStorage) LookupTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
+	lookupStorage, ok := s.TexturesStorage.(LookupTexturesStorage)
+	if !ok {
+		textures, err := s.TexturesStorage.GetTextures(uuid)
+		return textures, textures != nil, err
+	}
+
+	return lookupStorage.LookupTextures(uuid)
+}
+
 func (s *SeparatedStorage) StoreTextures(uuid string, textures *mojang.SignedTexturesResponse) {
 	s.TexturesStorage.StoreTextures(uuid, textures)
 }
+
+func (s *SeparatedStorage) RemoveTextures(uuid string) {
+	s.TexturesStorage.RemoveTextures(uuid)
+}
+
+func (s *SeparatedStorage) GetStaleTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
+	staleStorage, ok := s.TexturesStorage.(StaleTexturesStorage)
+	if !ok {
+		return nil, nil
+	}
+
+	return staleStorage.GetStaleTextures(uuid)
+}
@@ -22,6 +22,20 @@ func (m *uuidsStorageMock) StoreUuid(username string, uuid string) error {
 	return nil
 }
 
+func (m *uuidsStorageMock) RemoveUuid(username string) error {
+	m.Called(username)
+	return nil
+}
+
+type peekableUuidsStorageMock struct {
+	uuidsStorageMock
+}
+
+func (m *peekableUuidsStorageMock) PeekUuid(username string) (string, error) {
+	args := m.Called(username)
+	return args.String(0), args.Error(1)
+}
+
 type texturesStorageMock struct {
 	mock.Mock
 }
@@ -40,6 +54,38 @@ func (m *texturesStorageMock) StoreTextures(uuid string, textures *mojang.Signed
 	m.Called(uuid, textures)
 }
 
+type lookupTexturesStorageMock struct {
+	texturesStorageMock
+}
+
+func (m *lookupTexturesStorageMock) LookupTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
+	args := m.Called(uuid)
+	var result *mojang.SignedTexturesResponse
+	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
+		result = casted
+	}
+
+	return result, args.Bool(1), args.Error(2)
+}
+
+type staleTexturesStorageMock struct {
+	texturesStorageMock
+}
+
+func (m *staleTexturesStorageMock) GetStaleTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
+	args := m.Called(uuid)
+	var result *mojang.SignedTexturesResponse
+	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
+		result = casted
+	}
+
+	return result, args.Error(1)
+}
+
+func (m *texturesStorageMock) RemoveTextures(uuid string) {
+	m.Called(uuid)
+}
+
 func TestSplittedStorage(t *testing.T) {
 	createMockedStorage := func() (*SeparatedStorage, *uuidsStorageMock, *texturesStorageMock) {
 		uuidsStorage := &uuidsStorageMock{}
@@ -58,6 +104,25 @@ func TestSplittedStorage(t *testing.T) {
 		uuidsMock.AssertExpectations(t)
 	})
 
+	t.Run("PeekUuid", func(t *testing.T) {
+		uuidsMock := &peekableUuidsStorageMock{}
+		storage := &SeparatedStorage{uuidsMock, &texturesStorageMock{}}
+		uuidsMock.On("PeekUuid", "username").Once().Return("find me", nil)
+		result, err := storage.PeekUuid("username")
+		assert.Nil(t, err)
+		assert.Equal(t, "find me", result)
+		uuidsMock.AssertExpectations(t)
+	})
+
+	t.Run("PeekUuid when uuids storage doesn't support peeking", func(t *testing.T) {
+		storage, uuidsMock, _ := createMockedStorage()
+		uuidsMock.On("GetUuid", "username").Once().Return("find me", true, nil)
+		result, err := storage.PeekUuid("username")
+		assert.Nil(t, err)
+		assert.Equal(t, "find me", result)
+		uuidsMock.AssertExpectations(t)
+	})
+
 	t.Run("StoreUuid", func(t *testing.T) {
 		storage, uuidsMock, _ := createMockedStorage()
 		uuidsMock.On("StoreUuid", "username", "result").Once()
@@ -65,6 +130,13 @@ func TestSplittedStorage(t *testing.T) {
 		uuidsMock.AssertExpectations(t)
 	})
 
+	t.Run("RemoveUuid", func(t *testing.T) {
+		storage, uuidsMock, _ := createMockedStorage()
+		uuidsMock.On("RemoveUuid", "username").Once()
+		_ = storage.RemoveUuid("username")
+		uuidsMock.AssertExpectations(t)
+	})
+
 	t.Run("GetTextures", func(t *testing.T) {
 		result := &mojang.SignedTexturesResponse{Id: "mock id"}
 		storage, _, texturesMock := createMockedStorage()
@@ -75,6 +147,28 @@ func TestSplittedStorage(t *testing.T) {
 		texturesMock.AssertExpectations(t)
 	})
 
+	t.Run("LookupTextures", func(t *testing.T) {
+		texturesMock := &lookupTexturesStorageMock{}
+		storage := &SeparatedStorage{&uuidsStorageMock{}, texturesMock}
+		texturesMock.On("LookupTextures", "uuid").Once().Return(nil, true, nil)
+		returned, found, err := storage.LookupTextures("uuid")
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Nil(t, returned)
+		texturesMock.AssertExpectations(t)
+	})
+
+	t.Run("LookupTextures when textures storage doesn't support lookups", func(t *testing.T) {
+		result := &mojang.SignedTexturesResponse{Id: "mock id"}
+		storage, _, texturesMock := createMockedStorage()
+		texturesMock.On("GetTextures", "uuid").Once().Return(result, nil)
+		returned, found, err := storage.LookupTextures("uuid")
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, result, returned)
+		texturesMock.AssertExpectations(t)
+	})
+
 	t.Run("StoreTextures", func(t *testing.T) {
 		toStore := &mojang.SignedTexturesResponse{}
 		storage, _, texturesMock := createMockedStorage()
@@ -82,4 +176,29 @@ func TestSplittedStorage(t *testing.T) {
 		storage.StoreTextures("mock id", toStore)
 		texturesMock.AssertExpectations(t)
 	})
+
+	t.Run("RemoveTextures", func(t *testing.T) {
+		storage, _, texturesMock := createMockedStorage()
+		texturesMock.On("RemoveTextures", "mock id").Once()
+		storage.RemoveTextures("mock id")
+		texturesMock.AssertExpectations(t)
+	})
+
+	t.Run("GetStaleTextures", func(t *testing.T) {
+		result := &mojang.SignedTexturesResponse{Id: "mock id"}
+		texturesMock := &staleTexturesStorageMock{}
+		storage := &SeparatedStorage{&uuidsStorageMock{}, texturesMock}
+		texturesMock.On("GetStaleTextures", "uuid").Once().Return(result, nil)
+		returned, err := storage.GetStaleTextures("uuid")
+		assert.Nil(t, err)
+		assert.Equal(t, result, returned)
+		texturesMock.AssertExpectations(t)
+	})
+
+	t.Run("GetStaleTextures when textures storage doesn't support stale values", func(t *testing.T) {
+		storage, _, _ := createMockedStorage()
+		returned, err := storage.GetStaleTextures("uuid")
+		assert.Nil(t, err)
+		assert.Nil(t, returned)
+	})
 }
@@ -0,0 +1,207 @@
+package mojangtextures
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elyby/chrly/version"
+)
+
+// Textures are small images, so the larger response is most likely an error
+const maxMirroredTextureSize = 1 << 20
+
+// The hosts of the Mojang's textures servers, which are always allowed to be mirrored
+var mojangTexturesHosts = []string{"textures.minecraft.net"}
+
+// The textures are mirrored within the request, so the download must not take the whole request time
+var texturesMirrorHttpClient = &http.Client{
+	Timeout:   3 * time.Second,
+	Transport: HttpClient.Transport,
+}
+
+var textureHashRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// FilesystemTexturesMirror downloads the Mojang textures and stores them on the disk
+// under the hash of their content, so the same texture is stored only once
+type FilesystemTexturesMirror struct {
+	BasePath string
+	// The additional hosts the textures are allowed to be downloaded from. The Mojang's textures server
+	// is always allowed, the textures with the urls of any other host aren't mirrored
+	AllowedHosts []string
+	// The max number of the remembered url hashes. The arbitrary url is forgotten when the limit is reached,
+	// so its texture will be requested once again. 10000 is used when not set
+	MaxUrls int
+	// texturesMirrorHttpClient with the 3 seconds timeout is used when not set
+	Client *http.Client
+
+	lock   sync.RWMutex
+	hashes map[string]string
+}
+
+// Mirror returns the hash of the texture content, which is downloaded when it hasn't been mirrored yet
+func (m *FilesystemTexturesMirror) Mirror(c context.Context, url string) (string, error) {
+	m.lock.RLock()
+	hash, ok := m.hashes[url]
+	m.lock.RUnlock()
+	if ok {
+		return hash, nil
+	}
+
+	hash, err := m.download(c, url)
+	if err != nil {
+		return "", err
+	}
+
+	m.lock.Lock()
+	if m.hashes == nil {
+		m.hashes = make(map[string]string)
+	}
+
+	if len(m.hashes) >= m.maxUrls() {
+		// The iteration order of the map is random, so an arbitrary url is evicted
+		for evictedUrl := range m.hashes {
+			delete(m.hashes, evictedUrl)
+			break
+		}
+	}
+
+	m.hashes[url] = hash
+	m.lock.Unlock()
+
+	return hash, nil
+}
+
+// Open returns the mirrored texture content. nil nil is returned when there is no texture with such hash
+func (m *FilesystemTexturesMirror) Open(hash string) (io.ReadCloser, error) {
+	if !textureHashRegex.MatchString(hash) {
+		return nil, nil
+	}
+
+	file, err := os.Open(m.texturePath(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (m *FilesystemTexturesMirror) download(c context.Context, url string) (string, error) {
+	if !m.isAllowedUrl(url) {
+		return "", fmt.Errorf("the texture %s isn't hosted on the allowed host", url)
+	}
+
+	request, err := http.NewRequestWithContext(c, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	request.Header.Add("User-Agent", "Chrly/"+version.Version())
+
+	response, err := m.httpClient().Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response status %d for the texture %s", response.StatusCode, url)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(response.Body, maxMirroredTextureSize+1))
+	if err != nil {
+		return "", err
+	}
+
+	if len(content) > maxMirroredTextureSize {
+		return "", fmt.Errorf("the texture %s exceeds the max size", url)
+	}
+
+	checksum := sha256.Sum256(content)
+	hash := hex.EncodeToString(checksum[:])
+	texturePath := m.texturePath(hash)
+	if _, err := os.Stat(texturePath); err == nil {
+		return hash, nil
+	}
+
+	err = os.MkdirAll(path.Dir(texturePath), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	// Write into a temporary file first to not serve partially written textures
+	tmpFile, err := os.CreateTemp(path.Dir(texturePath), hash+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tmpFile.Write(content)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return "", err
+	}
+
+	err = os.Rename(tmpFile.Name(), texturePath)
+	if err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return "", err
+	}
+
+	return hash, nil
+}
+
+func (m *FilesystemTexturesMirror) texturePath(hash string) string {
+	return path.Join(m.BasePath, hash[0:2], hash+".png")
+}
+
+func (m *FilesystemTexturesMirror) isAllowedUrl(textureUrl string) bool {
+	parsedUrl, err := url.Parse(textureUrl)
+	if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") {
+		return false
+	}
+
+	host := parsedUrl.Hostname()
+	for _, hosts := range [][]string{mojangTexturesHosts, m.AllowedHosts} {
+		for _, allowedHost := range hosts {
+			if strings.EqualFold(host, allowedHost) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (m *FilesystemTexturesMirror) maxUrls() int {
+	if m.MaxUrls > 0 {
+		return m.MaxUrls
+	}
+
+	return 10000
+}
+
+func (m *FilesystemTexturesMirror) httpClient() *http.Client {
+	if m.Client != nil {
+		return m.Client
+	}
+
+	return texturesMirrorHttpClient
+}
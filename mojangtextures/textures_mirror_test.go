@@ -0,0 +1,146 @@
+package mojangtextures
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystemTexturesMirror(t *testing.T) {
+	texture := []byte("mock png content")
+	checksum := sha256.Sum256(texture)
+	expectedHash := hex.EncodeToString(checksum[:])
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/texture/1", "/texture/2":
+			_, _ = w.Write(texture)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	serverUrl, _ := url.Parse(server.URL)
+	createMirror := func(t *testing.T) *FilesystemTexturesMirror {
+		requests = 0
+		return &FilesystemTexturesMirror{
+			BasePath:     t.TempDir(),
+			AllowedHosts: []string{serverUrl.Hostname()},
+			Client:       server.Client(),
+		}
+	}
+
+	t.Run("mirror the texture", func(t *testing.T) {
+		mirror := createMirror(t)
+
+		hash, err := mirror.Mirror(context.Background(), server.URL+"/texture/1")
+		require.NoError(t, err)
+		require.Equal(t, expectedHash, hash)
+
+		content, err := os.ReadFile(path.Join(mirror.BasePath, expectedHash[0:2], expectedHash+".png"))
+		require.NoError(t, err)
+		require.Equal(t, texture, content)
+	})
+
+	t.Run("should not download the already mirrored texture again", func(t *testing.T) {
+		mirror := createMirror(t)
+
+		_, _ = mirror.Mirror(context.Background(), server.URL+"/texture/1")
+		hash, err := mirror.Mirror(context.Background(), server.URL+"/texture/1")
+		require.NoError(t, err)
+		require.Equal(t, expectedHash, hash)
+		require.Equal(t, 1, requests)
+	})
+
+	t.Run("should store the same content only once", func(t *testing.T) {
+		mirror := createMirror(t)
+
+		hash1, _ := mirror.Mirror(context.Background(), server.URL+"/texture/1")
+		hash2, _ := mirror.Mirror(context.Background(), server.URL+"/texture/2")
+		require.Equal(t, hash1, hash2)
+
+		files, _ := os.ReadDir(path.Join(mirror.BasePath, expectedHash[0:2]))
+		require.Len(t, files, 1)
+	})
+
+	t.Run("unexpected response", func(t *testing.T) {
+		mirror := createMirror(t)
+
+		hash, err := mirror.Mirror(context.Background(), server.URL+"/unknown")
+		require.Empty(t, hash)
+		require.Error(t, err)
+	})
+
+	t.Run("should not download the texture from the not allowed host", func(t *testing.T) {
+		mirror := createMirror(t)
+		mirror.AllowedHosts = nil
+
+		hash, err := mirror.Mirror(context.Background(), server.URL+"/texture/1")
+		require.Empty(t, hash)
+		require.Error(t, err)
+		require.Equal(t, 0, requests)
+	})
+
+	t.Run("should not download the texture with the not http url", func(t *testing.T) {
+		mirror := createMirror(t)
+
+		hash, err := mirror.Mirror(context.Background(), "file://"+serverUrl.Hostname()+"/etc/passwd")
+		require.Empty(t, hash)
+		require.Error(t, err)
+	})
+
+	t.Run("should forget the arbitrary url when the limit is reached", func(t *testing.T) {
+		mirror := createMirror(t)
+		mirror.MaxUrls = 1
+
+		_, _ = mirror.Mirror(context.Background(), server.URL+"/texture/1")
+		_, _ = mirror.Mirror(context.Background(), server.URL+"/texture/2")
+		require.Len(t, mirror.hashes, 1)
+		require.Contains(t, mirror.hashes, server.URL+"/texture/2")
+
+		hash, err := mirror.Mirror(context.Background(), server.URL+"/texture/1")
+		require.NoError(t, err)
+		require.Equal(t, expectedHash, hash)
+		require.Equal(t, 3, requests)
+	})
+
+	t.Run("open the mirrored texture", func(t *testing.T) {
+		mirror := createMirror(t)
+
+		hash, _ := mirror.Mirror(context.Background(), server.URL+"/texture/1")
+		file, err := mirror.Open(hash)
+		require.NoError(t, err)
+		defer file.Close()
+
+		content, _ := io.ReadAll(file)
+		require.Equal(t, texture, content)
+	})
+
+	t.Run("open not mirrored texture", func(t *testing.T) {
+		mirror := createMirror(t)
+
+		file, err := mirror.Open(expectedHash)
+		require.NoError(t, err)
+		require.Nil(t, file)
+	})
+
+	t.Run("open with invalid hash", func(t *testing.T) {
+		mirror := createMirror(t)
+
+		file, err := mirror.Open("../../etc/passwd")
+		require.NoError(t, err)
+		require.Nil(t, file)
+	})
+}
@@ -0,0 +1,212 @@
+package mojangtextures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/version"
+)
+
+type UsernameTexturesProvider interface {
+	GetForUsername(c context.Context, username string) (*mojang.SignedTexturesResponse, error)
+}
+
+type TexturesPurger interface {
+	Purge(username string) error
+}
+
+// ChainProvider requests the providers one by one until any of them returns the textures.
+// When none of them has found the textures, the first occurred error is returned
+type ChainProvider struct {
+	Providers []UsernameTexturesProvider
+}
+
+func (ctx *ChainProvider) GetForUsername(c context.Context, username string) (*mojang.SignedTexturesResponse, error) {
+	var firstErr error
+	for _, provider := range ctx.Providers {
+		textures, err := provider.GetForUsername(c, username)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			continue
+		}
+
+		if textures != nil {
+			return textures, nil
+		}
+	}
+
+	return nil, firstErr
+}
+
+// Purge removes the username from the caches of all the providers, which support it
+func (ctx *ChainProvider) Purge(username string) error {
+	for _, provider := range ctx.Providers {
+		purger, ok := provider.(TexturesPurger)
+		if !ok {
+			continue
+		}
+
+		if err := purger.Purge(username); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Upstream is a source of the signed textures, which isn't Mojang
+type Upstream interface {
+	GetTextures(c context.Context, username string) (*mojang.SignedTexturesResponse, error)
+}
+
+// UpstreamProvider caches the textures found by the upstream
+type UpstreamProvider struct {
+	Emitter
+	Upstream
+	// Name is used to distinguish the upstreams in the emitted events
+	Name string
+	// The found textures are stored by the lowercased username. When the storage implements
+	// the LookupTexturesStorage, the absence of the textures is cached as well
+	Storage TexturesStorage
+}
+
+func (ctx *UpstreamProvider) GetForUsername(c context.Context, username string) (*mojang.SignedTexturesResponse, error) {
+	if username == "" {
+		return nil, nil
+	}
+
+	username = strings.ToLower(username)
+	textures, found, err := ctx.getTexturesFromCache(username)
+	if err == nil && found {
+		ctx.Emit("upstream:cache_hit", ctx.Name, username)
+		return textures, nil
+	}
+
+	textures, err = ctx.Upstream.GetTextures(c, username)
+	ctx.Emit("upstream:after_call", c, ctx.Name, username, textures, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// The storage is expected to keep the absence of the textures for a short time,
+	// since the user may appear in the upstream at any moment
+	ctx.Storage.StoreTextures(username, textures)
+
+	return textures, nil
+}
+
+func (ctx *UpstreamProvider) getTexturesFromCache(username string) (*mojang.SignedTexturesResponse, bool, error) {
+	lookupStorage, ok := ctx.Storage.(LookupTexturesStorage)
+	if !ok {
+		textures, err := ctx.Storage.GetTextures(username)
+		return textures, textures != nil, err
+	}
+
+	return lookupStorage.LookupTextures(username)
+}
+
+func (ctx *UpstreamProvider) Purge(username string) error {
+	ctx.Storage.RemoveTextures(strings.ToLower(username))
+
+	return nil
+}
+
+// YggdrasilUpstream requests the server, which implements the same API as the Mojang's one
+type YggdrasilUpstream struct {
+	Client *mojang.Client
+}
+
+func (u *YggdrasilUpstream) GetTextures(c context.Context, username string) (*mojang.SignedTexturesResponse, error) {
+	profiles, err := u.Client.UsernamesToUuids(c, []string{username})
+	if err != nil {
+		return nil, err
+	}
+
+	var uuid string
+	for _, profile := range profiles {
+		if strings.EqualFold(profile.Name, username) {
+			uuid = profile.Id
+			break
+		}
+	}
+
+	if uuid == "" {
+		return nil, nil
+	}
+
+	textures, err := u.Client.UuidToTextures(c, uuid, true)
+	if err != nil {
+		var emptyResponse *mojang.EmptyResponse
+		if errors.As(err, &emptyResponse) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return textures, nil
+}
+
+// ChrlyUpstream requests the signed textures endpoint of another Chrly instance
+type ChrlyUpstream struct {
+	Url url.URL
+	// HttpClient is used when not set
+	Client *http.Client
+}
+
+func (u *ChrlyUpstream) GetTextures(c context.Context, username string) (*mojang.SignedTexturesResponse, error) {
+	requestUrl := u.Url
+	requestUrl.Path = strings.TrimSuffix(requestUrl.Path, "/") + "/textures/signed/" + url.PathEscape(username)
+
+	request, err := http.NewRequestWithContext(c, "GET", requestUrl.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("User-Agent", "Chrly/"+version.Version())
+
+	response, err := u.httpClient().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent, http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected response status %d from the upstream %s", response.StatusCode, u.Url.Redacted())
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *mojang.SignedTexturesResponse
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (u *ChrlyUpstream) httpClient() *http.Client {
+	if u.Client != nil {
+		return u.Client
+	}
+
+	return HttpClient
+}
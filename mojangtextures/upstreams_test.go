@@ -0,0 +1,269 @@
+package mojangtextures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elyby/chrly/api/mojang"
+)
+
+type mockUsernameTexturesProvider struct {
+	mock.Mock
+}
+
+func (m *mockUsernameTexturesProvider) GetForUsername(_ context.Context, username string) (*mojang.SignedTexturesResponse, error) {
+	args := m.Called(username)
+	var result *mojang.SignedTexturesResponse
+	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
+		result = casted
+	}
+
+	return result, args.Error(1)
+}
+
+type mockUpstream struct {
+	mock.Mock
+}
+
+func (m *mockUpstream) GetTextures(_ context.Context, username string) (*mojang.SignedTexturesResponse, error) {
+	args := m.Called(username)
+	var result *mojang.SignedTexturesResponse
+	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
+		result = casted
+	}
+
+	return result, args.Error(1)
+}
+
+func TestChainProvider(t *testing.T) {
+	textures := &mojang.SignedTexturesResponse{Id: "mock-id", Name: "mock"}
+
+	t.Run("should return the textures from the first provider, which has found them", func(t *testing.T) {
+		first, second, third := &mockUsernameTexturesProvider{}, &mockUsernameTexturesProvider{}, &mockUsernameTexturesProvider{}
+		first.On("GetForUsername", "mock").Once().Return(nil, nil)
+		second.On("GetForUsername", "mock").Once().Return(textures, nil)
+
+		chain := &ChainProvider{Providers: []UsernameTexturesProvider{first, second, third}}
+		result, err := chain.GetForUsername(context.Background(), "mock")
+
+		require.NoError(t, err)
+		require.Same(t, textures, result)
+		first.AssertExpectations(t)
+		second.AssertExpectations(t)
+		third.AssertExpectations(t)
+	})
+
+	t.Run("should fall through the failed provider", func(t *testing.T) {
+		first, second := &mockUsernameTexturesProvider{}, &mockUsernameTexturesProvider{}
+		first.On("GetForUsername", "mock").Once().Return(nil, errors.New("mock error"))
+		second.On("GetForUsername", "mock").Once().Return(textures, nil)
+
+		chain := &ChainProvider{Providers: []UsernameTexturesProvider{first, second}}
+		result, err := chain.GetForUsername(context.Background(), "mock")
+
+		require.NoError(t, err)
+		require.Same(t, textures, result)
+	})
+
+	t.Run("should return the first error when no provider has found the textures", func(t *testing.T) {
+		first, second, third := &mockUsernameTexturesProvider{}, &mockUsernameTexturesProvider{}, &mockUsernameTexturesProvider{}
+		first.On("GetForUsername", "mock").Once().Return(nil, nil)
+		second.On("GetForUsername", "mock").Once().Return(nil, errors.New("first error"))
+		third.On("GetForUsername", "mock").Once().Return(nil, errors.New("second error"))
+
+		chain := &ChainProvider{Providers: []UsernameTexturesProvider{first, second, third}}
+		result, err := chain.GetForUsername(context.Background(), "mock")
+
+		require.Nil(t, result)
+		require.EqualError(t, err, "first error")
+	})
+
+	t.Run("should purge the providers, which support it", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage()
+		storage.StoreTextures("mock", textures)
+		upstreamProvider := &UpstreamProvider{Storage: storage}
+
+		chain := &ChainProvider{Providers: []UsernameTexturesProvider{&mockUsernameTexturesProvider{}, upstreamProvider}}
+		require.NoError(t, chain.Purge("Mock"))
+
+		result, _ := storage.GetTextures("mock")
+		require.Nil(t, result)
+	})
+}
+
+func TestUpstreamProvider(t *testing.T) {
+	textures := &mojang.SignedTexturesResponse{Id: "mock-id", Name: "mock"}
+
+	t.Run("should cache the found textures", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "upstream:after_call", mock.Anything, "ely", "mock", textures, nil).Once()
+		emitter.On("Emit", "upstream:cache_hit", "ely", "mock").Once()
+		upstream := &mockUpstream{}
+		upstream.On("GetTextures", "mock").Once().Return(textures, nil)
+
+		provider := &UpstreamProvider{
+			Emitter:  emitter,
+			Upstream: upstream,
+			Name:     "ely",
+			Storage:  NewInMemoryTexturesStorage(),
+		}
+
+		result, err := provider.GetForUsername(context.Background(), "Mock")
+		require.NoError(t, err)
+		require.Same(t, textures, result)
+
+		result, err = provider.GetForUsername(context.Background(), "mock")
+		require.NoError(t, err)
+		require.Same(t, textures, result)
+
+		emitter.AssertExpectations(t)
+		upstream.AssertExpectations(t)
+	})
+
+	t.Run("should cache the absence of the textures for its own duration", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "upstream:after_call", mock.Anything, "ely", "mock", mock.Anything, nil).Twice()
+		emitter.On("Emit", "upstream:cache_hit", "ely", "mock").Once()
+		upstream := &mockUpstream{}
+		upstream.On("GetTextures", "mock").Twice().Return(nil, nil)
+
+		storage := NewInMemoryTexturesStorage()
+		storage.NilDuration = 10 * time.Millisecond
+		provider := &UpstreamProvider{
+			Emitter:  emitter,
+			Upstream: upstream,
+			Name:     "ely",
+			Storage:  storage,
+		}
+
+		result, err := provider.GetForUsername(context.Background(), "mock")
+		require.NoError(t, err)
+		require.Nil(t, result)
+
+		result, err = provider.GetForUsername(context.Background(), "mock")
+		require.NoError(t, err)
+		require.Nil(t, result)
+
+		time.Sleep(storage.NilDuration * 2)
+
+		result, err = provider.GetForUsername(context.Background(), "mock")
+		require.NoError(t, err)
+		require.Nil(t, result)
+
+		emitter.AssertExpectations(t)
+		upstream.AssertExpectations(t)
+	})
+
+	t.Run("should not cache the errors", func(t *testing.T) {
+		err := errors.New("mock error")
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "upstream:after_call", mock.Anything, "ely", "mock", mock.Anything, err).Twice()
+		upstream := &mockUpstream{}
+		upstream.On("GetTextures", "mock").Twice().Return(nil, err)
+
+		provider := &UpstreamProvider{
+			Emitter:  emitter,
+			Upstream: upstream,
+			Name:     "ely",
+			Storage:  NewInMemoryTexturesStorage(),
+		}
+
+		result, resultErr := provider.GetForUsername(context.Background(), "mock")
+		require.Same(t, err, resultErr)
+		require.Nil(t, result)
+
+		result, resultErr = provider.GetForUsername(context.Background(), "mock")
+		require.Same(t, err, resultErr)
+		require.Nil(t, result)
+
+		emitter.AssertExpectations(t)
+		upstream.AssertExpectations(t)
+	})
+}
+
+func TestYggdrasilUpstream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/profiles/minecraft":
+			var usernames []string
+			_ = json.NewDecoder(r.Body).Decode(&usernames)
+			if usernames[0] == "unknown" {
+				_, _ = w.Write([]byte("[]"))
+				return
+			}
+
+			_, _ = w.Write([]byte(`[{"id":"4566e69fc90748ee8d71d7ba5aa00d20","name":"` + usernames[0] + `"}]`))
+		case "/sessionserver/session/minecraft/profile/4566e69fc90748ee8d71d7ba5aa00d20":
+			require.Equal(t, "false", r.URL.Query().Get("unsigned"))
+			_, _ = w.Write([]byte(`{"id":"4566e69fc90748ee8d71d7ba5aa00d20","name":"mock"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	upstream := &YggdrasilUpstream{
+		Client: &mojang.Client{
+			ApiBaseUrl:           server.URL + "/api",
+			SessionServerBaseUrl: server.URL + "/sessionserver",
+			HttpClient:           server.Client(),
+		},
+	}
+
+	t.Run("should exchange the username and request the textures", func(t *testing.T) {
+		result, err := upstream.GetTextures(context.Background(), "mock")
+		require.NoError(t, err)
+		require.Equal(t, "4566e69fc90748ee8d71d7ba5aa00d20", result.Id)
+	})
+
+	t.Run("should return nil for the unknown username", func(t *testing.T) {
+		result, err := upstream.GetTextures(context.Background(), "unknown")
+		require.NoError(t, err)
+		require.Nil(t, result)
+	})
+}
+
+func TestChrlyUpstream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/skinsystem/textures/signed/mock":
+			_, _ = w.Write([]byte(`{"id":"4566e69fc90748ee8d71d7ba5aa00d20","name":"mock","properties":[{"name":"textures","signature":"sig","value":"val"}]}`))
+		case "/skinsystem/textures/signed/unknown":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/skinsystem/")
+	upstream := &ChrlyUpstream{Url: *u, Client: server.Client()}
+
+	t.Run("should return the signed textures", func(t *testing.T) {
+		result, err := upstream.GetTextures(context.Background(), "mock")
+		require.NoError(t, err)
+		require.Equal(t, "mock", result.Name)
+		require.Equal(t, "sig", result.Props[0].Signature)
+	})
+
+	t.Run("should return nil for the unknown username", func(t *testing.T) {
+		result, err := upstream.GetTextures(context.Background(), "unknown")
+		require.NoError(t, err)
+		require.Nil(t, result)
+	})
+
+	t.Run("should return an error for the unexpected response", func(t *testing.T) {
+		result, err := upstream.GetTextures(context.Background(), "error")
+		require.Error(t, err)
+		require.Nil(t, result)
+	})
+}
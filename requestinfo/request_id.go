@@ -0,0 +1,38 @@
+package requestinfo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+// Incoming ids are limited to a safe set of chars to prevent logs injections
+var allowedRequestIdRegex = regexp.MustCompile(`^[0-9A-Za-z._:-]{1,128}$`)
+
+type requestIdContextKey struct{}
+
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdContextKey{}, requestId)
+}
+
+// RequestId returns an empty string when there is no request id in the passed context
+func RequestId(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdContextKey{}).(string)
+
+	return requestId
+}
+
+// IsValidRequestId reports whether an id, received from the outside, can be used as is
+func IsValidRequestId(requestId string) bool {
+	return allowedRequestIdRegex.MatchString(requestId)
+}
+
+func GenerateRequestId() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,62 @@
+// Package requestinfo holds the information about an incoming HTTP request, which is collected
+// while the request passes through the middlewares and handlers, and should be available to the loggers
+package requestinfo
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type contextKey struct{}
+
+type Info struct {
+	lock          sync.Mutex
+	path          string
+	routeTemplate string
+}
+
+// WithInfo attaches a new Info to the request. If the request already has one, it'll be kept
+func WithInfo(req *http.Request) (*http.Request, *Info) {
+	if info := FromContext(req.Context()); info != nil {
+		return req, info
+	}
+
+	info := &Info{path: req.URL.Path}
+
+	return req.WithContext(context.WithValue(req.Context(), contextKey{}, info)), info
+}
+
+// FromContext returns nil when there is no Info in the passed context
+func FromContext(ctx context.Context) *Info {
+	info, _ := ctx.Value(contextKey{}).(*Info)
+
+	return info
+}
+
+// SetRouteTemplate stores the template of the matched route. The routers mounted using http.StripPrefix
+// receive the path without the mount prefix, so it's restored by comparing the original and the passed paths
+func (i *Info) SetRouteTemplate(req *http.Request, template string) {
+	if i == nil || template == "" {
+		return
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.routeTemplate = strings.TrimSuffix(i.path, req.URL.Path) + template
+}
+
+// RouteTemplate returns the template of the route that has handled the request, e.g. "/skins/{username}".
+// An empty string will be returned when no route has been matched
+func (i *Info) RouteTemplate() string {
+	if i == nil {
+		return ""
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	return i.routeTemplate
+}
@@ -0,0 +1,54 @@
+package requestinfo
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithInfo(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://localhost/api/skins/mock", nil)
+	assert.Nil(t, FromContext(req.Context()))
+
+	req, info := WithInfo(req)
+	assert.Same(t, info, FromContext(req.Context()))
+
+	sameReq, sameInfo := WithInfo(req)
+	assert.Same(t, req, sameReq)
+	assert.Same(t, info, sameInfo)
+}
+
+func TestInfo_RouteTemplate(t *testing.T) {
+	t.Run("should restore the mount prefix", func(t *testing.T) {
+		req, info := WithInfo(httptest.NewRequest("GET", "http://localhost/api/skins/mock", nil))
+		info.SetRouteTemplate(req, "/api")
+		assert.Equal(t, "/api", info.RouteTemplate())
+
+		mountedReq := httptest.NewRequest("GET", "http://localhost/skins/mock", nil)
+		info.SetRouteTemplate(mountedReq, "/skins/{username}")
+		assert.Equal(t, "/api/skins/{username}", info.RouteTemplate())
+	})
+
+	t.Run("should be safe to call on nil", func(t *testing.T) {
+		info := FromContext(context.Background())
+		info.SetRouteTemplate(httptest.NewRequest("GET", "http://localhost", nil), "/mock")
+		assert.Equal(t, "", info.RouteTemplate())
+	})
+}
+
+func TestRequestId(t *testing.T) {
+	assert.Equal(t, "", RequestId(context.Background()))
+	assert.Equal(t, "mock-id", RequestId(WithRequestId(context.Background(), "mock-id")))
+}
+
+func TestIsValidRequestId(t *testing.T) {
+	assert.True(t, IsValidRequestId("f3b4a1c2-0d9e-4c1b-8a7e-2f6d5c4b3a21"))
+	assert.True(t, IsValidRequestId(GenerateRequestId()))
+	assert.False(t, IsValidRequestId(""))
+	assert.False(t, IsValidRequestId("with spaces"))
+	assert.False(t, IsValidRequestId("new\nline"))
+	assert.False(t, IsValidRequestId(strings.Repeat("a", 129)))
+}
@@ -0,0 +1,120 @@
+package requestinfo
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxies is a list of networks whose forwarding headers (X-Forwarded-For and so on) can be trusted
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies accepts both CIDR notations and plain IP addresses
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	result := make(TrustedProxies, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address \"%s\"", value)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			result = append(result, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy network \"%s\": %w", value, err)
+		}
+
+		result = append(result, network)
+	}
+
+	return result, nil
+}
+
+// IsTrusted reports whether the request came directly from a trusted proxy
+func (p TrustedProxies) IsTrusted(req *http.Request) bool {
+	return p.contains(trimPort(req.RemoteAddr))
+}
+
+// ClientIp returns the address of the client that has performed the request.
+// The X-Forwarded-For header is taken into account only when the request came through the trusted proxies.
+// The header is walked from right to left and the first untrusted address is returned
+func (p TrustedProxies) ClientIp(req *http.Request) string {
+	remoteIp := trimPort(req.RemoteAddr)
+	if !p.contains(remoteIp) {
+		return remoteIp
+	}
+
+	forwardedFor := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwardedFor[i])
+		if ip == "" {
+			continue
+		}
+
+		remoteIp = ip
+		if !p.contains(ip) {
+			break
+		}
+	}
+
+	return remoteIp
+}
+
+// Scheme returns the scheme the client has used to perform the request. The X-Forwarded-Proto header
+// is taken into account only when the request came from a trusted proxy
+func (p TrustedProxies) Scheme(req *http.Request) string {
+	if p.IsTrusted(req) {
+		// The header may contain multiple values when the request passed through several proxies.
+		// The first one is set by the proxy that the client has connected to
+		proto := strings.ToLower(strings.TrimSpace(strings.Split(req.Header.Get("X-Forwarded-Proto"), ",")[0]))
+		if proto == "http" || proto == "https" {
+			return proto
+		}
+	}
+
+	if req.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+func (p TrustedProxies) contains(rawIp string) bool {
+	ip := net.ParseIP(rawIp)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func trimPort(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
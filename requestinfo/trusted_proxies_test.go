@@ -0,0 +1,99 @@
+package requestinfo
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	t.Run("should parse networks and single addresses", func(t *testing.T) {
+		proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 192.0.2.1 ", "", "::1"})
+		require.NoError(t, err)
+		require.Len(t, proxies, 3)
+		assert.Equal(t, "10.0.0.0/8", proxies[0].String())
+		assert.Equal(t, "192.0.2.1/32", proxies[1].String())
+		assert.Equal(t, "::1/128", proxies[2].String())
+	})
+
+	t.Run("should return an error for invalid values", func(t *testing.T) {
+		_, err := ParseTrustedProxies([]string{"not an ip"})
+		assert.Error(t, err)
+
+		_, err = ParseTrustedProxies([]string{"10.0.0.0/99"})
+		assert.Error(t, err)
+	})
+}
+
+func TestTrustedProxies_ClientIp(t *testing.T) {
+	proxies, _ := ParseTrustedProxies([]string{"192.0.2.0/24", "10.0.0.0/8"})
+
+	t.Run("should ignore the forwarded header when the request came not from the trusted proxy", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://localhost", nil)
+		req.RemoteAddr = "203.0.113.5:1234"
+		req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+		assert.Equal(t, "203.0.113.5", proxies.ClientIp(req))
+	})
+
+	t.Run("should return the first untrusted address from the right", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://localhost", nil)
+		req.Header.Set("X-Forwarded-For", "5.6.7.8, 1.2.3.4, 10.1.1.1")
+
+		assert.Equal(t, "1.2.3.4", proxies.ClientIp(req))
+	})
+
+	t.Run("should return the leftmost address when all of them are trusted", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://localhost", nil)
+		req.Header.Set("X-Forwarded-For", "10.1.1.2, 10.1.1.1")
+
+		assert.Equal(t, "10.1.1.2", proxies.ClientIp(req))
+	})
+
+	t.Run("should return the remote address when there are no trusted proxies", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://localhost", nil)
+		req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+		assert.Equal(t, "192.0.2.1", TrustedProxies(nil).ClientIp(req))
+	})
+}
+
+func TestTrustedProxies_Scheme(t *testing.T) {
+	proxies, _ := ParseTrustedProxies([]string{"192.0.2.0/24"})
+
+	t.Run("plain http request", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://localhost", nil)
+
+		assert.Equal(t, "http", proxies.Scheme(req))
+	})
+
+	t.Run("tls request", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "https://localhost", nil)
+
+		assert.Equal(t, "https", proxies.Scheme(req))
+	})
+
+	t.Run("should use the forwarded proto from the trusted proxy", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://localhost", nil)
+		req.Header.Set("X-Forwarded-Proto", "HTTPS, http")
+
+		assert.Equal(t, "https", proxies.Scheme(req))
+	})
+
+	t.Run("should ignore the forwarded proto from the untrusted address", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://localhost", nil)
+		req.RemoteAddr = "203.0.113.5:1234"
+		req.Header.Set("X-Forwarded-Proto", "https")
+
+		assert.Equal(t, "http", proxies.Scheme(req))
+	})
+
+	t.Run("should ignore unknown forwarded proto", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "https://localhost", nil)
+		req.Header.Set("X-Forwarded-Proto", "ws")
+
+		assert.Equal(t, "https", proxies.Scheme(req))
+	})
+}